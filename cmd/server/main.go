@@ -1,19 +1,23 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/config"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/database"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/handler"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/audio"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -29,6 +33,27 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Maintenance commands run instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			migrate(cfg)
+		case "migrate-store":
+			migrateStore(cfg, os.Args[2:])
+		case "gen-store-key":
+			genStoreKey()
+		case "rotate-store-key":
+			rotateStoreKey(cfg)
+		case "export-session":
+			exportSession(cfg, os.Args[2:])
+		case "import-session":
+			importSession(cfg, os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+		return
+	}
+
 	// Connect to database
 	db, err := database.Connect(cfg)
 	if err != nil {
@@ -45,36 +70,136 @@ func main() {
 	chatbotRepo := repository.NewChatbotRepository(db)
 	optionRepo := repository.NewChatbotOptionRepository(db)
 	conversationRepo := repository.NewConversationStateRepository(db)
+	pollRepo := repository.NewPollRepository(db)
+	chatRepo := repository.NewChatRepository(db)
+	messageRepo := repository.NewMessageRepository(db)
+	contactRepo := repository.NewContactRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	clusterRepo := repository.NewClusterRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	usageRepo := repository.NewUsageRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
 
-	// Initialize services (temporary placeholders for WhatsApp manager)
-	chatbotService := service.NewChatbotService(chatbotRepo, optionRepo, conversationRepo, userRepo, nil)
+	// Sessions are tracked in the database, move over any left in the old metadata file
+	sessionService := service.NewSessionService(sessionRepo)
+	ownerID := cfg.WhatsApp.MetadataOwnerID
+	if ownerID != 0 {
+		if _, err := userRepo.FindByID(ownerID); err != nil {
+			log.Printf("Warning: SESSION_METADATA_OWNER_ID %d isn't a user, imported sessions stay unowned", ownerID)
+			ownerID = 0
+		}
+	}
+	if imported, err := sessionService.ImportLegacyMetadata(cfg.WhatsApp.MetadataPath, ownerID); err != nil {
+		log.Printf("Warning: failed to import session metadata: %v", err)
+	} else if imported > 0 {
+		log.Printf("📦 Imported %d sessions from metadata.json", imported)
+	}
+	if ownerID != 0 {
+		if assigned, err := sessionService.AssignUnownedSessions(ownerID); err != nil {
+			log.Printf("Warning: failed to assign unowned sessions: %v", err)
+		} else if assigned > 0 {
+			log.Printf("📦 Assigned %d unowned sessions to user %d", assigned, ownerID)
+		}
+	}
+
+	// Limit how much history phones send to newly paired sessions
+	whatsmeow_client.SetHistorySyncDays(cfg.WhatsApp.HistorySyncDays)
 
-	// Initialize WhatsApp manager with chatbot service as event handler
-	waManager, err := whatsmeow_client.NewManager(cfg.WhatsApp.DBPath, chatbotService)
+	// The chatbot needs the manager to reply, so it is registered as the first
+	// event handler once the manager exists
+	waManager, err := whatsmeow_client.NewManager(cfg.WhatsApp.Store(), sessionService, nil)
 	if err != nil {
 		log.Fatalf("Failed to initialize WhatsApp manager: %v", err)
 	}
+	chatbotService := service.NewChatbotService(chatbotRepo, optionRepo, conversationRepo, userRepo, waManager)
+	waManager.AddEventHandler(chatbotService)
+	quotaService := service.NewQuotaService(usageRepo, userRepo, sessionRepo, cfg.Quota.Plans, cfg.Quota.DefaultPlan)
+	messageService := service.NewMessageService(waManager, audio.NewTranscoder(cfg.WhatsApp.FFmpegPath), quotaService)
+	pollService := service.NewPollService(pollRepo, waManager, quotaService)
+	presenceService := service.NewPresenceService(waManager)
+	chatService := service.NewChatService(chatRepo, waManager)
+	historyService := service.NewHistoryService(messageRepo, waManager, cfg.WhatsApp.HistorySyncDays)
+	contactService := service.NewContactService(contactRepo, waManager)
+	notificationService := service.NewNotificationService(notificationRepo, sessionRepo)
+	transferService := service.NewSessionTransferService(sessionRepo, sessionService, waManager)
 
-	// //Restore saved sessions on starting of server
-	// waManager.RestoreSessions()
+	// Ingest poll votes, keep chats and contacts up to date, store messages and history
+	// and notify owners about logged out or banned sessions
+	waManager.AddEventHandler(pollService)
+	waManager.AddEventHandler(chatService)
+	waManager.AddEventHandler(historyService)
+	waManager.AddEventHandler(contactService)
+	waManager.AddEventHandler(notificationService)
 
-	// Start periodic metadata saving (every 5 minutes)
-	waManager.StartMetadataSaver(5 * time.Minute)
+	// Disconnect idle sessions, they are woken up on schedule and when used
+	waManager.StartHibernation(cfg.WhatsApp.Hibernation())
 
-	// Update chatbot service with the WhatsApp manager
-	chatbotService = service.NewChatbotService(chatbotRepo, optionRepo, conversationRepo, userRepo, waManager)
-	messageService := service.NewMessageService(waManager)
+	// Restore saved sessions in the background so the server starts right away,
+	// /api/ready reports when all of them have been handled. In a cluster each
+	// instance only restores the sessions assigned to it.
+	var clusterService *service.ClusterService
+	if cfg.Cluster.Enabled {
+		clusterService = service.NewClusterService(clusterRepo, sessionRepo, waManager,
+			cfg.Cluster.InstanceID, cfg.Cluster.InstanceURL,
+			time.Duration(cfg.Cluster.LeaseTTLSeconds)*time.Second, cfg.WhatsApp.RestoreConcurrency)
+		clusterService.Start()
+	} else {
+		go waManager.RestoreSessions(cfg.WhatsApp.RestoreConcurrency, nil)
+	}
+
+	// Initialize middleware
+	auditService := service.NewAuditService(auditRepo, cfg.Audit.RetentionDays)
+	clusterMiddleware := middleware.NewClusterMiddleware(clusterService, cfg.Cluster.ForwardRequests, cfg.Cluster.Secret)
+	auditMiddleware := middleware.NewAuditMiddleware(auditService, clusterMiddleware)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, sessionService)
+	authMiddleware, err := middleware.NewAuthMiddleware(cfg, apiKeyService)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+	sessionMiddleware := middleware.NewSessionMiddleware(sessionService)
+	permissionMiddleware := middleware.NewPermissionMiddleware(userRepo)
+	quotaMiddleware := middleware.NewQuotaMiddleware(quotaService, cfg.Quota.AuthRequestsPerMinute)
+
+	// Logins get short-lived access tokens and rotating refresh tokens, expired
+	// tokens, old usage counters and old audit log entries are cleaned up hourly
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, authMiddleware, auditService,
+		authMiddleware.AccessTokenTTL(), time.Duration(cfg.JWT.RefreshTokenDays)*24*time.Hour, cfg.JWT.AllowRegistration)
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			authService.CleanupExpiredTokens()
+			quotaService.CleanupUsage()
+			auditService.Cleanup()
+		}
+	}()
 
 	// Initialize handlers
-	sessionHandler := handler.NewSessionHandler(waManager, chatbotService)
-	messageHandler := handler.NewMessageHandler(messageService)
+	sessionHandler := handler.NewSessionHandler(waManager, chatbotService, sessionService, quotaService, clusterMiddleware)
+	transferHandler := handler.NewSessionTransferHandler(transferService, sessionService, quotaService, clusterMiddleware)
+	messageHandler := handler.NewMessageHandler(messageService, pollService)
+	presenceHandler := handler.NewPresenceHandler(presenceService)
+	chatHandler := handler.NewChatHandler(chatService)
+	contactHandler := handler.NewContactHandler(contactService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
 	chatbotHandler := handler.NewChatbotHandler(chatbotRepo, optionRepo, userRepo, db)
-
-	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg)
+	userHandler := handler.NewUserHandler(userRepo, quotaService)
+	authHandler := handler.NewAuthHandler(authService, userRepo)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, userRepo)
+	auditHandler := handler.NewAuditHandler(auditService)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
+		// Imported session bundles can be larger than the default 4 MB
+		BodyLimit: 32 * 1024 * 1024,
+		// Behind a load balancer the client IP comes from its header, but
+		// only when the request really comes from the load balancer
+		EnableTrustedProxyCheck: len(cfg.Server.TrustedProxies) > 0,
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		ProxyHeader:             proxyHeader(cfg.Server),
+		EnableIPValidation:      true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -90,6 +215,7 @@ func main() {
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New())
+	app.Use(clusterMiddleware.ResolveClientIP)
 
 	// Health check
 	app.Get("/api/health", func(c *fiber.Ctx) error {
@@ -99,21 +225,70 @@ func main() {
 		})
 	})
 
+	// Readiness check, fails until saved sessions have been restored
+	app.Get("/api/ready", func(c *fiber.Ctx) error {
+		progress := waManager.RestoreProgress()
+		status := fiber.StatusOK
+		if !progress.Completed {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"ready":   progress.Completed,
+			"restore": progress,
+		})
+	})
+
+	// Public keys access tokens are signed with, for services that verify them
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(fiber.Map{
+			"keys": authMiddleware.PublicKeys(),
+		})
+	})
+
 	// Root endpoint
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"name":    "WhatsApp Multi-Account API - Go Edition",
 			"version": "1.0.0",
 			"endpoints": []string{
+				"GET /.well-known/jwks.json",
+				"POST /api/auth/register",
+				"POST /api/auth/login",
+				"POST /api/auth/refresh",
+				"POST /api/auth/logout",
+				"GET /api/auth/me",
+				"POST /api/keys",
+				"GET /api/keys",
+				"DELETE /api/keys/:id",
 				"POST /api/session/init",
 				"GET /api/session/qr/:userId",
+				"POST /api/session/qr/:userId/regenerate",
+				"GET /api/session/events/:userId",
 				"GET /api/session/status/:userId",
+				"GET /api/session/status/:userId/history",
 				"POST /api/session/logout",
+				"POST /api/session/export",
+				"POST /api/session/import",
 				"POST /api/message/send",
 				"POST /api/message/send-many",
 				"POST /api/message/send-media",
 				"POST /api/message/send-many-image",
+				"POST /api/message/send-audio",
+				"POST /api/message/send-location",
+				"POST /api/message/send-contact",
+				"POST /api/message/send-poll",
+				"GET /api/message/poll/:pollId/results",
 				"GET /api/sessions",
+				"POST /api/presence",
+				"POST /api/presence/chat",
+				"GET /api/sessions/:userId/chats",
+				"POST /api/chats/:jid/read",
+				"PATCH /api/chats/:jid",
+				"GET /api/contacts",
+				"GET /api/contacts/:jid",
+				"GET /api/notifications",
+				"POST /api/notifications/:id/read",
 				"--- CHATBOT ENDPOINTS ---",
 				"POST /api/chatbot",
 				"GET /api/chatbot",
@@ -121,30 +296,82 @@ func main() {
 				"DELETE /api/chatbot/option/:userId/:optionKey",
 				"PATCH /api/chatbot/:userId/toggle",
 				"DELETE /api/chatbot/:userId",
+				"--- USER ENDPOINTS ---",
+				"PATCH /api/users/:id/role",
+				"PATCH /api/users/:id/plan",
+				"GET /api/usage",
+				"GET /api/audit",
 			},
 		})
 	})
 
+	// Auth routes
+	app.Post("/api/auth/register", auditMiddleware.Record("auth.register"), quotaMiddleware.LimitByIP, authHandler.Register)
+	app.Post("/api/auth/login", auditMiddleware.Record("auth.login"), quotaMiddleware.LimitByIP, authHandler.Login)
+	app.Post("/api/auth/refresh", quotaMiddleware.LimitByIP, authHandler.Refresh)
+	app.Post("/api/auth/logout", auditMiddleware.Record("auth.logout"), authMiddleware.Auth, middleware.RejectAPIKeys, authHandler.Logout)
+	app.Get("/api/auth/me", authMiddleware.Auth, authHandler.Me)
+
+	// API key routes, keys can't manage keys
+	app.Post("/api/keys", auditMiddleware.Record("apikey.create"), authMiddleware.Auth, middleware.RejectAPIKeys, apiKeyHandler.CreateAPIKey)
+	app.Get("/api/keys", authMiddleware.Auth, middleware.RejectAPIKeys, apiKeyHandler.ListAPIKeys)
+	app.Delete("/api/keys/:id", auditMiddleware.Record("apikey.revoke"), authMiddleware.Auth, middleware.RejectAPIKeys, apiKeyHandler.RevokeAPIKey)
+
 	// Session routes
-	app.Post("/api/session/init", authMiddleware.Auth, sessionHandler.InitSession)
-	app.Get("/api/session/qr/:userId", sessionHandler.GetQRCode)
-	app.Get("/api/session/status/:userId", sessionHandler.GetStatus)
-	app.Post("/api/session/logout", sessionHandler.Logout)
-	app.Get("/api/sessions", sessionHandler.GetAllSessions)
+	app.Post("/api/session/init", auditMiddleware.Record("session.init"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, middleware.RejectSessionScopedKeys, sessionHandler.InitSession)
+	app.Get("/api/session/qr/:userId", authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.GetQRCode)
+	app.Post("/api/session/qr/:userId/regenerate", auditMiddleware.Record("session.qr_regenerate"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.RegenerateQR)
+	app.Get("/api/session/events/:userId", authMiddleware.AuthEventStream, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.StreamEvents)
+	app.Get("/api/session/status/:userId", authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.GetStatus)
+	app.Get("/api/session/status/:userId/history", authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.GetStatusHistory)
+	app.Post("/api/session/logout", auditMiddleware.Record("session.logout"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, sessionHandler.Logout)
+	app.Post("/api/session/export", auditMiddleware.Record("session.export"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, transferHandler.ExportSession)
+	app.Post("/api/session/import", auditMiddleware.Record("session.import"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsManage), quotaMiddleware.RateLimit, middleware.RejectSessionScopedKeys, transferHandler.ImportSession)
+	app.Get("/api/sessions", authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsRead), quotaMiddleware.RateLimit, sessionHandler.GetAllSessions)
 
 	// Message routes
-	app.Post("/api/message/send", authMiddleware.Auth, messageHandler.SendTextMessage)
-	app.Post("/api/message/send-many", authMiddleware.Auth, messageHandler.SendBulkTextMessages)
-	app.Post("/api/message/send-media", authMiddleware.Auth, messageHandler.SendMediaMessage)
-	app.Post("/api/message/send-many-image", authMiddleware.Auth, messageHandler.SendBulkMediaMessages)
+	app.Post("/api/message/send", auditMiddleware.Record("message.send"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendTextMessage)
+	app.Post("/api/message/send-many", auditMiddleware.Record("message.send_many"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendBulkTextMessages)
+	app.Post("/api/message/send-media", auditMiddleware.Record("message.send_media"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendMediaMessage)
+	app.Post("/api/message/send-many-image", auditMiddleware.Record("message.send_many_media"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendBulkMediaMessages)
+	app.Post("/api/message/send-audio", auditMiddleware.Record("message.send_audio"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendAudio)
+	app.Post("/api/message/send-location", auditMiddleware.Record("message.send_location"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendLocation)
+	app.Post("/api/message/send-contact", auditMiddleware.Record("message.send_contact"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendContact)
+	app.Post("/api/message/send-poll", auditMiddleware.Record("message.send_poll"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.SendPoll)
+	app.Get("/api/message/poll/:pollId/results", authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, messageHandler.GetPollResults)
+
+	// Presence routes
+	app.Post("/api/presence", auditMiddleware.Record("presence.set"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, presenceHandler.SetAvailability)
+	app.Post("/api/presence/chat", auditMiddleware.Record("presence.chat"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, presenceHandler.SetChatPresence)
+
+	// Chat routes
+	app.Get("/api/sessions/:userId/chats", authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatHandler.ListChats)
+	app.Post("/api/chats/:jid/read", auditMiddleware.Record("chat.mark_read"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatHandler.MarkRead)
+	app.Patch("/api/chats/:jid", auditMiddleware.Record("chat.update"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesSend), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatHandler.UpdateChat)
+
+	// Contact routes
+	app.Get("/api/contacts", authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, contactHandler.ListContacts)
+	app.Get("/api/contacts/:jid", authMiddleware.Auth, permissionMiddleware.Require(domain.PermMessagesRead), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, contactHandler.GetContact)
+
+	// Notification routes
+	app.Get("/api/notifications", authMiddleware.Auth, permissionMiddleware.Require(domain.PermNotificationsRead), quotaMiddleware.RateLimit, notificationHandler.ListNotifications)
+	app.Post("/api/notifications/:id/read", authMiddleware.Auth, permissionMiddleware.Require(domain.PermNotificationsRead), quotaMiddleware.RateLimit, notificationHandler.MarkRead)
 
 	// Chatbot routes
-	app.Post("/api/chatbot", authMiddleware.Auth, chatbotHandler.CreateOrUpdateChatbot)
-	app.Get("/api/chatbot", authMiddleware.Auth, chatbotHandler.GetChatbot)
-	app.Post("/api/chatbot/option", authMiddleware.Auth, chatbotHandler.CreateOrUpdateOption)
-	app.Delete("/api/chatbot/option/:userId/:optionKey", chatbotHandler.DeleteOption)
-	app.Patch("/api/chatbot/:userId/toggle", chatbotHandler.ToggleChatbot)
-	app.Delete("/api/chatbot/:userId", chatbotHandler.DeleteChatbot)
+	app.Post("/api/chatbot", auditMiddleware.Record("chatbot.save"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatbotHandler.CreateOrUpdateChatbot)
+	app.Get("/api/chatbot", authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotRead), quotaMiddleware.RateLimit, chatbotHandler.GetChatbot)
+	app.Post("/api/chatbot/option", auditMiddleware.Record("chatbot.option_save"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotManage), quotaMiddleware.RateLimit, chatbotHandler.CreateOrUpdateOption)
+	app.Delete("/api/chatbot/option/:userId/:optionKey", auditMiddleware.Record("chatbot.option_delete"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatbotHandler.DeleteOption)
+	app.Patch("/api/chatbot/:userId/toggle", auditMiddleware.Record("chatbot.toggle"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatbotHandler.ToggleChatbot)
+	app.Delete("/api/chatbot/:userId", auditMiddleware.Record("chatbot.delete"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermChatbotManage), quotaMiddleware.RateLimit, sessionMiddleware.RequireSession, clusterMiddleware.RouteSession, chatbotHandler.DeleteChatbot)
+
+	// User routes
+	app.Patch("/api/users/:id/role", auditMiddleware.Record("user.set_role"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermUsersManage), quotaMiddleware.RateLimit, userHandler.SetRole)
+	app.Patch("/api/users/:id/plan", auditMiddleware.Record("user.set_plan"), authMiddleware.Auth, permissionMiddleware.Require(domain.PermUsersManage), quotaMiddleware.RateLimit, userHandler.SetPlan)
+	app.Get("/api/usage", authMiddleware.Auth, permissionMiddleware.Require(domain.PermSessionsRead), quotaMiddleware.RateLimit, userHandler.GetUsage)
+
+	// Audit log, mutating routes above record an entry per request
+	app.Get("/api/audit", authMiddleware.Auth, permissionMiddleware.Require(domain.PermAuditRead), quotaMiddleware.RateLimit, middleware.RejectSessionScopedKeys, auditHandler.GetAuditLog)
 
 	// Test authenticated endpoint
 	app.Get("/yeaboi", authMiddleware.Auth, func(c *fiber.Ctx) error {
@@ -161,11 +388,13 @@ func main() {
 		<-c
 		log.Println("\n🛑 Shutting down gracefully...")
 
-		// Save session metadata before shutdown
-		if err := waManager.SaveSessionMetadata(); err != nil {
-			log.Printf("Error saving session metadata on shutdown: %v", err)
+		// Open event streams never go idle, so don't wait on them forever
+		app.ShutdownWithTimeout(10 * time.Second)
+
+		// Hand sessions over right away instead of letting their leases expire
+		if clusterService != nil {
+			clusterService.Stop()
 		}
-		app.Shutdown()
 	}()
 
 	// Start server
@@ -178,3 +407,160 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// migrate creates and updates the application tables. The server doesn't
+// migrate on startup, run this after every upgrade.
+func migrate(cfg *config.Config) {
+	db, err := database.Connect(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := database.AutoMigrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+}
+
+// migrateStore copies all devices and keys from a SQLite device store into a
+// Postgres one, by default from WHATSMEOW_DB_PATH into WHATSMEOW_POSTGRES_DSN
+func migrateStore(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("migrate-store", flag.ExitOnError)
+	fromPath := flags.String("from", cfg.WhatsApp.DBPath, "SQLite device store to copy from")
+	toDSN := flags.String("to", cfg.WhatsApp.PostgresDSN, "Postgres connection string to copy to")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*fromPath); err != nil {
+		log.Fatalf("Can't read %s: %v", *fromPath, err)
+	}
+
+	from := whatsmeow_client.StoreConfig{Driver: whatsmeow_client.StoreDriverSQLite, Path: *fromPath}
+	to := whatsmeow_client.StoreConfig{Driver: whatsmeow_client.StoreDriverPostgres, DSN: *toDSN}
+
+	copied, err := whatsmeow_client.MigrateStore(from, to)
+	if err != nil {
+		log.Fatalf("Device store migration failed: %v", err)
+	}
+
+	var total int64
+	for _, count := range copied {
+		total += count
+	}
+	log.Printf("✅ Copied %d rows from %s to postgres. Set WHATSMEOW_STORE_DRIVER=postgres to use it.", total, *fromPath)
+}
+
+// genStoreKey prints a new key for WHATSMEOW_STORE_KEY
+func genStoreKey() {
+	key, err := whatsmeow_client.GenerateStoreKey()
+	if err != nil {
+		log.Fatalf("Failed to generate store key: %v", err)
+	}
+	fmt.Println(key)
+}
+
+// rotateStoreKey rewraps every device's data key with the current store key.
+// Put the new key in WHATSMEOW_STORE_KEY and the old one in
+// WHATSMEOW_STORE_PREVIOUS_KEYS, run this, then drop the old key.
+func rotateStoreKey(cfg *config.Config) {
+	rotated, err := whatsmeow_client.RotateStoreKey(cfg.WhatsApp.Store())
+	if err != nil {
+		log.Fatalf("Store key rotation failed: %v", err)
+	}
+	log.Printf("✅ Rewrapped %d data keys. Remove the previous keys from WHATSMEOW_STORE_PREVIOUS_KEYS.", rotated)
+}
+
+// exportSession writes a session to an encrypted bundle for import on another
+// server. Meant for a stopped server, use POST /api/session/export otherwise.
+// The passphrase comes from SESSION_BUNDLE_PASSPHRASE or -passphrase-file.
+func exportSession(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("export-session", flag.ExitOnError)
+	sessionID := flags.String("session", "", "ID of the session to export")
+	out := flags.String("out", "", "file to write the bundle to, defaults to <session>.wasession")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the bundle passphrase")
+	force := flags.Bool("force", false, "export even if the session looks connected")
+	flags.Parse(args)
+
+	if *sessionID == "" {
+		log.Fatalf("-session is required")
+	}
+	if *out == "" {
+		*out = *sessionID + ".wasession"
+	}
+	passphrase := bundlePassphrase(*passphraseFile)
+
+	transferService, _ := transferCommandSetup(cfg)
+	bundle, err := transferService.ExportOffline(*sessionID, passphrase, *force)
+	if err != nil {
+		log.Fatalf("Session export failed: %v", err)
+	}
+	if err := os.WriteFile(*out, bundle, 0600); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	log.Printf("✅ Exported session %s to %s. It won't be restored on this server anymore.", *sessionID, *out)
+}
+
+// importSession adds a session bundle to a user's sessions. The session
+// connects the next time the server starts or rebalances.
+func importSession(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("import-session", flag.ExitOnError)
+	in := flags.String("in", "", "bundle to import")
+	ownerID := flags.Uint("owner", 0, "ID of the user the session belongs to")
+	name := flags.String("name", "", "session name, defaults to the exported name")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the bundle passphrase")
+	flags.Parse(args)
+
+	if *in == "" || *ownerID == 0 {
+		log.Fatalf("-in and -owner are required")
+	}
+	bundle, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *in, err)
+	}
+	passphrase := bundlePassphrase(*passphraseFile)
+
+	transferService, userRepo := transferCommandSetup(cfg)
+	if _, err := userRepo.FindByID(*ownerID); err != nil {
+		log.Fatalf("User %d not found: %v", *ownerID, err)
+	}
+	// Operators aren't held to the owner's plan
+	session, err := transferService.Import(*ownerID, bundle, passphrase, *name, false, nil)
+	if err != nil {
+		log.Fatalf("Session import failed: %v", err)
+	}
+	log.Printf("✅ Imported session %s (%s). It connects when the server starts.", session.ID, session.Name)
+}
+
+func transferCommandSetup(cfg *config.Config) (*service.SessionTransferService, repository.UserRepository) {
+	db, err := database.Connect(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	sessionRepo := repository.NewSessionRepository(db)
+	sessionService := service.NewSessionService(sessionRepo)
+
+	waManager, err := whatsmeow_client.NewManager(cfg.WhatsApp.Store(), sessionService, nil)
+	if err != nil {
+		log.Fatalf("Failed to open device store: %v", err)
+	}
+	return service.NewSessionTransferService(sessionRepo, sessionService, waManager), repository.NewUserRepository(db)
+}
+
+func bundlePassphrase(file string) string {
+	if file == "" {
+		if passphrase := os.Getenv("SESSION_BUNDLE_PASSPHRASE"); passphrase != "" {
+			return passphrase
+		}
+		log.Fatalf("Set SESSION_BUNDLE_PASSPHRASE or pass -passphrase-file")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("Failed to read passphrase file: %v", err)
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+// proxyHeader is the header client IPs are read from, none without trusted proxies
+func proxyHeader(cfg config.ServerConfig) string {
+	if len(cfg.TrustedProxies) == 0 {
+		return ""
+	}
+	return cfg.ProxyHeader
+}
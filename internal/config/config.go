@@ -1,10 +1,15 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
 	"github.com/joho/godotenv"
 )
 
@@ -13,11 +18,18 @@ type Config struct {
 	Database DatabaseConfig
 	JWT      JWTConfig
 	WhatsApp WhatsAppConfig
+	Cluster  ClusterConfig
+	Quota    QuotaConfig
+	Audit    AuditConfig
 }
 
 type ServerConfig struct {
 	Port string
 	Env  string
+	// Client IPs are taken from ProxyHeader only on requests coming from one
+	// of the TrustedProxies (addresses or CIDR ranges)
+	TrustedProxies []string
+	ProxyHeader    string
 }
 
 type DatabaseConfig struct {
@@ -28,24 +40,105 @@ type DatabaseConfig struct {
 	Name     string
 }
 
+// Signing algorithms for access tokens
+const (
+	JWTAlgorithmHS256 = "HS256"
+	JWTAlgorithmRS256 = "RS256"
+	JWTAlgorithmEdDSA = "EdDSA"
+)
+
+// defaultJWTSecret is only good for development, production refuses to start with it
+const defaultJWTSecret = "change-this-secret-key"
+
 type JWTConfig struct {
-	Secret string
+	// Algorithm is HS256 (signed with Secret) or RS256/EdDSA (signed with the
+	// PEM key in PrivateKeyFile). Previous secrets and key files are still
+	// accepted, so keys can be rotated without logging everyone out.
+	Algorithm        string
+	Secret           string
+	PreviousSecrets  string
+	PrivateKeyFile   string
+	PreviousKeyFiles string
+	Issuer           string
+	Audience         string
+	// Access tokens are short-lived, refresh tokens rotate on every use
+	AccessTokenMinutes int
+	RefreshTokenDays   int
+	// AllowRegistration opens POST /api/auth/register to anyone, off by default
+	AllowRegistration bool
 }
 
 type WhatsAppConfig struct {
-	DBPath           string
-	MetadataPath     string
-	MaxMediaSizeMB   int
+	// StoreDriver is where whatsmeow keeps devices and keys: "sqlite" (DBPath) or "postgres" (PostgresDSN)
+	StoreDriver string
+	DBPath      string
+	PostgresDSN string
+	// StoreKey or StoreKeyFile encrypt device keys at rest, see StoreConfig
+	StoreKey          string
+	StoreKeyFile      string
+	StorePreviousKeys string
+	MetadataPath      string
+	// MetadataOwnerID owns sessions imported from metadata.json (0 leaves them unowned)
+	MetadataOwnerID uint
+	MaxMediaSizeMB  int
+	FFmpegPath      string
+	// HistorySyncDays is how far back messages are imported after pairing (0 disables the import)
+	HistorySyncDays int
+	// RestoreConcurrency is how many sessions are reconnected at once on startup
+	RestoreConcurrency int
+	// Sessions idle for HibernateAfterMinutes are disconnected (0 keeps them
+	// connected) and woken up every HibernateWakeIntervalMinutes
+	HibernateAfterMinutes        int
+	HibernateWakeIntervalMinutes int
+	HibernateWakeDurationSeconds int
+	HibernateWakeTimeoutSeconds  int
+}
+
+// ClusterConfig spreads sessions over several instances sharing one database
+type ClusterConfig struct {
+	Enabled bool
+	// InstanceID must be unique per instance, InstanceURL is where the others reach it
+	InstanceID      string
+	InstanceURL     string
+	LeaseTTLSeconds int
+	// ForwardRequests proxies requests for sessions of other instances instead of answering 421
+	ForwardRequests bool
+	// Secret signs forwarded requests, so clients can't pass as another instance
+	Secret string
+}
+
+// QuotaConfig holds the plans accounts are limited by. The built-in "default"
+// plan takes its limits from PLAN_* variables, PlansFile can add more plans or
+// override it.
+type QuotaConfig struct {
+	PlansFile   string
+	DefaultPlan string
+	Plans       map[string]domain.Plan
+	// AuthRequestsPerMinute limits login, registration and refresh per client IP
+	AuthRequestsPerMinute int
+}
+
+type AuditConfig struct {
+	// RetentionDays is how long audit log entries are kept (0 keeps them forever)
+	RetentionDays int
 }
 
 func Load() (*Config, error) {
 	// Load .env file if it exists
 	_ = godotenv.Load()
 
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	port := getEnv("PORT", "3456")
+
 	config := &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "3456"),
-			Env:  getEnv("ENV", "development"),
+			Port:           port,
+			Env:            getEnv("ENV", "development"),
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
+			ProxyHeader:    getEnv("PROXY_HEADER", "X-Forwarded-For"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -55,18 +148,204 @@ func Load() (*Config, error) {
 			Name:     getEnv("DB_NAME", "whatsapp_chatbot"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "change-this-secret-key"),
+			Algorithm:          getEnv("JWT_ALGORITHM", JWTAlgorithmHS256),
+			Secret:             getEnv("JWT_SECRET", defaultJWTSecret),
+			PreviousSecrets:    getEnv("JWT_PREVIOUS_SECRETS", ""),
+			PrivateKeyFile:     getEnv("JWT_PRIVATE_KEY_FILE", ""),
+			PreviousKeyFiles:   getEnv("JWT_PREVIOUS_KEY_FILES", ""),
+			Issuer:             getEnv("JWT_ISSUER", "whatsapp-keepconnect"),
+			Audience:           getEnv("JWT_AUDIENCE", "whatsapp-keepconnect-api"),
+			AccessTokenMinutes: getEnvAsInt("JWT_ACCESS_TOKEN_MINUTES", 15),
+			RefreshTokenDays:   getEnvAsInt("JWT_REFRESH_TOKEN_DAYS", 30),
+			AllowRegistration:  getEnvAsBool("ALLOW_REGISTRATION", false),
 		},
 		WhatsApp: WhatsAppConfig{
-			DBPath:         getEnv("WHATSMEOW_DB_PATH", "./sessions/whatsmeow.db"),
-			MetadataPath:   getEnv("SESSION_METADATA_PATH", "./sessions/metadata.json"),
-			MaxMediaSizeMB: getEnvAsInt("MAX_MEDIA_SIZE_MB", 16),
+			StoreDriver:        getEnv("WHATSMEOW_STORE_DRIVER", "sqlite"),
+			DBPath:             getEnv("WHATSMEOW_DB_PATH", "./sessions/whatsmeow.db"),
+			PostgresDSN:        getEnv("WHATSMEOW_POSTGRES_DSN", ""),
+			StoreKey:           getEnv("WHATSMEOW_STORE_KEY", ""),
+			StoreKeyFile:       getEnv("WHATSMEOW_STORE_KEY_FILE", ""),
+			StorePreviousKeys:  getEnv("WHATSMEOW_STORE_PREVIOUS_KEYS", ""),
+			MetadataPath:       getEnv("SESSION_METADATA_PATH", "./sessions/metadata.json"),
+			MetadataOwnerID:    uint(getEnvAsInt("SESSION_METADATA_OWNER_ID", 0)),
+			MaxMediaSizeMB:     getEnvAsInt("MAX_MEDIA_SIZE_MB", 16),
+			FFmpegPath:         getEnv("FFMPEG_PATH", "ffmpeg"),
+			HistorySyncDays:    getEnvAsInt("HISTORY_SYNC_DAYS", 30),
+			RestoreConcurrency: getEnvAsInt("RESTORE_CONCURRENCY", 10),
+
+			HibernateAfterMinutes:        getEnvAsInt("HIBERNATE_AFTER_MINUTES", 0),
+			HibernateWakeIntervalMinutes: getEnvAsInt("HIBERNATE_WAKE_INTERVAL_MINUTES", 60),
+			HibernateWakeDurationSeconds: getEnvAsInt("HIBERNATE_WAKE_DURATION_SECONDS", 60),
+			HibernateWakeTimeoutSeconds:  getEnvAsInt("HIBERNATE_WAKE_TIMEOUT_SECONDS", 20),
+		},
+		Cluster: ClusterConfig{
+			Enabled:         getEnvAsBool("CLUSTER_ENABLED", false),
+			InstanceID:      getEnv("INSTANCE_ID", hostname),
+			InstanceURL:     getEnv("INSTANCE_URL", fmt.Sprintf("http://%s:%s", hostname, port)),
+			LeaseTTLSeconds: getEnvAsInt("CLUSTER_LEASE_TTL_SECONDS", 30),
+			ForwardRequests: getEnvAsBool("CLUSTER_FORWARD_REQUESTS", true),
+			Secret:          getEnv("CLUSTER_SECRET", ""),
 		},
+		Quota: QuotaConfig{
+			PlansFile:   getEnv("PLANS_FILE", ""),
+			DefaultPlan: getEnv("DEFAULT_PLAN", domain.DefaultPlanName),
+			Plans: map[string]domain.Plan{
+				domain.DefaultPlanName: {
+					Name:              domain.DefaultPlanName,
+					MaxSessions:       getEnvAsInt("PLAN_MAX_SESSIONS", 10),
+					MessagesPerDay:    getEnvAsInt("PLAN_MESSAGES_PER_DAY", 10000),
+					BulkRecipients:    getEnvAsInt("PLAN_BULK_RECIPIENTS", 500),
+					RequestsPerMinute: getEnvAsInt("PLAN_REQUESTS_PER_MINUTE", 300),
+				},
+			},
+			AuthRequestsPerMinute: getEnvAsInt("AUTH_REQUESTS_PER_MINUTE", 20),
+		},
+		Audit: AuditConfig{
+			RetentionDays: getEnvAsInt("AUDIT_RETENTION_DAYS", 90),
+		},
+	}
+
+	if err := config.Quota.loadPlans(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	return config, nil
 }
 
+// Validate checks settings that would otherwise only fail once they are used
+func (c *Config) Validate() error {
+	if err := c.WhatsApp.Store().Validate(); err != nil {
+		return fmt.Errorf("invalid device store settings: %w", err)
+	}
+	if err := c.JWT.Validate(c.Server.Env == "production"); err != nil {
+		return err
+	}
+	if c.JWT.AccessTokenMinutes <= 0 || c.JWT.RefreshTokenDays <= 0 {
+		return fmt.Errorf("JWT_ACCESS_TOKEN_MINUTES and JWT_REFRESH_TOKEN_DAYS must be at least 1")
+	}
+	if _, ok := c.Quota.Plans[c.Quota.DefaultPlan]; !ok {
+		return fmt.Errorf("DEFAULT_PLAN %q is not a configured plan", c.Quota.DefaultPlan)
+	}
+	for name, plan := range c.Quota.Plans {
+		if plan.MaxSessions < 0 || plan.MessagesPerDay < 0 || plan.BulkRecipients < 0 || plan.RequestsPerMinute < 0 {
+			return fmt.Errorf("limits of plan %q can't be negative", name)
+		}
+	}
+	if c.Audit.RetentionDays < 0 {
+		return fmt.Errorf("AUDIT_RETENTION_DAYS can't be negative")
+	}
+	if c.WhatsApp.RestoreConcurrency <= 0 {
+		return fmt.Errorf("RESTORE_CONCURRENCY must be at least 1")
+	}
+	if c.WhatsApp.HibernateAfterMinutes < 0 || c.WhatsApp.HibernateWakeIntervalMinutes < 0 {
+		return fmt.Errorf("HIBERNATE_AFTER_MINUTES and HIBERNATE_WAKE_INTERVAL_MINUTES can't be negative")
+	}
+	if c.WhatsApp.HibernateAfterMinutes > 0 && (c.WhatsApp.HibernateWakeDurationSeconds <= 0 || c.WhatsApp.HibernateWakeTimeoutSeconds <= 0) {
+		return fmt.Errorf("HIBERNATE_WAKE_DURATION_SECONDS and HIBERNATE_WAKE_TIMEOUT_SECONDS must be at least 1")
+	}
+	if c.Cluster.Enabled {
+		// Every instance must be able to load every device
+		if c.WhatsApp.StoreDriver != whatsmeow_client.StoreDriverPostgres {
+			return fmt.Errorf("CLUSTER_ENABLED requires WHATSMEOW_STORE_DRIVER=postgres")
+		}
+		if c.Cluster.LeaseTTLSeconds < 3 {
+			return fmt.Errorf("CLUSTER_LEASE_TTL_SECONDS must be at least 3")
+		}
+		if len(c.Cluster.Secret) < 32 {
+			return fmt.Errorf("CLUSTER_SECRET must be at least 32 characters and the same on every instance (openssl rand -base64 48)")
+		}
+	}
+	return nil
+}
+
+// Validate checks the signing settings. Production refuses the default or a
+// short secret, since anyone who knows it can sign tokens for any user.
+func (j *JWTConfig) Validate(production bool) error {
+	if j.Issuer == "" || j.Audience == "" {
+		return fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE can't be empty")
+	}
+	switch j.Algorithm {
+	case JWTAlgorithmHS256:
+		if j.Secret == "" {
+			return fmt.Errorf("JWT_SECRET is required with JWT_ALGORITHM=HS256")
+		}
+		if production && j.Secret == defaultJWTSecret {
+			return fmt.Errorf("JWT_SECRET is still the default, set a random secret (openssl rand -base64 48) before running with ENV=production")
+		}
+		if production && len(j.Secret) < 32 {
+			return fmt.Errorf("JWT_SECRET must be at least 32 characters with ENV=production")
+		}
+	case JWTAlgorithmRS256, JWTAlgorithmEdDSA:
+		if j.PrivateKeyFile == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE is required with JWT_ALGORITHM=%s", j.Algorithm)
+		}
+	default:
+		return fmt.Errorf("JWT_ALGORITHM must be one of HS256, RS256 or EdDSA")
+	}
+
+	// Tokens signed with a previous secret still verify, so they need to be as strong
+	for _, secret := range strings.Split(j.PreviousSecrets, ",") {
+		if secret = strings.TrimSpace(secret); secret == "" || !production {
+			continue
+		}
+		if secret == defaultJWTSecret {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS contains the default secret, remove it before running with ENV=production")
+		}
+		if len(secret) < 32 {
+			return fmt.Errorf("every secret in JWT_PREVIOUS_SECRETS must be at least 32 characters with ENV=production")
+		}
+	}
+	return nil
+}
+
+// loadPlans adds the plans of PlansFile, a JSON object of plan names to limits:
+// {"pro": {"maxSessions": 50, "messagesPerDay": 100000, "bulkRecipients": 1000, "requestsPerMinute": 1200}}
+func (q *QuotaConfig) loadPlans() error {
+	if q.PlansFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(q.PlansFile)
+	if err != nil {
+		return fmt.Errorf("failed to read PLANS_FILE: %w", err)
+	}
+	var plans map[string]domain.Plan
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return fmt.Errorf("invalid PLANS_FILE: %w", err)
+	}
+	for name, plan := range plans {
+		plan.Name = name
+		q.Plans[name] = plan
+	}
+	return nil
+}
+
+// Store returns the whatsmeow device store settings
+func (w WhatsAppConfig) Store() whatsmeow_client.StoreConfig {
+	return whatsmeow_client.StoreConfig{
+		Driver: w.StoreDriver,
+		Path:   w.DBPath,
+		DSN:    w.PostgresDSN,
+
+		Key:          w.StoreKey,
+		KeyFile:      w.StoreKeyFile,
+		PreviousKeys: w.StorePreviousKeys,
+	}
+}
+
+// Hibernation returns the idle session hibernation policy
+func (w WhatsAppConfig) Hibernation() whatsmeow_client.HibernationPolicy {
+	return whatsmeow_client.HibernationPolicy{
+		IdleAfter:   time.Duration(w.HibernateAfterMinutes) * time.Minute,
+		WakeEvery:   time.Duration(w.HibernateWakeIntervalMinutes) * time.Minute,
+		WakeFor:     time.Duration(w.HibernateWakeDurationSeconds) * time.Second,
+		WakeTimeout: time.Duration(w.HibernateWakeTimeoutSeconds) * time.Second,
+	}
+}
+
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		c.Database.User,
@@ -91,4 +370,24 @@ func getEnvAsInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList reads a comma separated list
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
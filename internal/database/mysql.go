@@ -47,9 +47,23 @@ func AutoMigrate(db *gorm.DB) error {
 
 	err := db.AutoMigrate(
 		&domain.User{},
+		&domain.Session{},
+		&domain.SessionStatusChange{},
 		&domain.Chatbot{},
 		&domain.ChatbotOption{},
 		&domain.ConversationState{},
+		&domain.Poll{},
+		&domain.PollVote{},
+		&domain.Chat{},
+		&domain.Message{},
+		&domain.Contact{},
+		&domain.Notification{},
+		&domain.Instance{},
+		&domain.SessionLease{},
+		&domain.RefreshToken{},
+		&domain.APIKey{},
+		&domain.UsageCounter{},
+		&domain.AuditLog{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
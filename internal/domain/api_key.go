@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// APIKeyPrefix starts every API key, so keys can be told apart from JWTs
+const APIKeyPrefix = "wak_"
+
+// APIKey lets a backend call the API without logging in. Only a hash of the
+// key is stored, Prefix identifies it in lists and logs. A key can do at most
+// what its user's role allows, further limited by its scopes and sessions.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(20);not null;uniqueIndex"`
+	KeyHash    string     `json:"-" gorm:"type:char(64);not null"`
+	Scopes     []string   `json:"scopes" gorm:"type:text;serializer:json;not null"`
+	SessionIDs []string   `json:"session_ids" gorm:"type:text;serializer:json"` // Empty allows all of the account's sessions
+	AllowedIPs []string   `json:"allowed_ips" gorm:"type:text;serializer:json"` // IPs or CIDRs, empty allows any
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope reports whether the key may be used for a permission
+func (k *APIKey) HasScope(permission string) bool {
+	for _, scope := range k.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsSession reports whether the key may be used on a session
+func (k *APIKey) AllowsSession(sessionID string) bool {
+	if len(k.SessionIDs) == 0 {
+		return true
+	}
+	for _, id := range k.SessionIDs {
+		if id == sessionID {
+			return true
+		}
+	}
+	return false
+}
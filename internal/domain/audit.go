@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied" // Rejected by authentication, permissions or limits
+	AuditFailure = "failure"
+)
+
+// AuditLog records who did what to which session or chatbot
+type AuditLog struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	TenantID   uint                   `json:"tenant_id" gorm:"index"`
+	ActorID    uint                   `json:"actor_id" gorm:"index"` // 0 when the request wasn't authenticated
+	APIKeyID   *uint                  `json:"api_key_id"`
+	Action     string                 `json:"action" gorm:"type:varchar(100);not null;index"`
+	SessionID  string                 `json:"session_id" gorm:"type:varchar(255);index"`
+	ChatbotID  string                 `json:"chatbot_id" gorm:"type:varchar(255)"`
+	Outcome    string                 `json:"outcome" gorm:"type:varchar(20);not null"`
+	StatusCode int                    `json:"status_code"`
+	Method     string                 `json:"method" gorm:"type:varchar(10)"`
+	Path       string                 `json:"path" gorm:"type:varchar(255)"`
+	IP         string                 `json:"ip" gorm:"type:varchar(45)"`
+	UserAgent  string                 `json:"user_agent" gorm:"type:varchar(255)"`
+	RequestID  string                 `json:"request_id" gorm:"type:varchar(100)"`
+	Details    map[string]interface{} `json:"details,omitempty" gorm:"type:text;serializer:json"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// AuditFilter selects audit log entries. Zero values don't filter.
+type AuditFilter struct {
+	TenantID   uint
+	AllTenants bool
+	ActorID    uint
+	Action     string // Exact action, or a prefix ending in "." like "session."
+	SessionID  string
+	ChatbotID  string
+	Outcome    string
+	From       time.Time
+	To         time.Time
+	BeforeID   uint // Entries older than this ID, for paging
+	Limit      int
+}
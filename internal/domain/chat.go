@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+type Chat struct {
+	ID                string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	UserID            string     `json:"user_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_chat_user_jid;index:idx_chat_user_activity"`
+	JID               string     `json:"jid" gorm:"type:varchar(255);not null;uniqueIndex:idx_chat_user_jid"`
+	Name              string     `json:"name" gorm:"type:varchar(255)"`
+	IsGroup           bool       `json:"is_group" gorm:"default:false"`
+	LastMessage       string     `json:"last_message" gorm:"type:text"`
+	LastMessageID     string     `json:"last_message_id" gorm:"type:varchar(255)"`
+	LastMessageFromMe bool       `json:"last_message_from_me" gorm:"default:false"`
+	LastActivity      *time.Time `json:"last_activity" gorm:"index:idx_chat_user_activity"`
+	UnreadCount       int        `json:"unread_count" gorm:"default:0"`
+	MarkedUnread      bool       `json:"marked_unread" gorm:"default:false"`
+	Archived          bool       `json:"archived" gorm:"default:false"`
+	Pinned            bool       `json:"pinned" gorm:"default:false"`
+	MutedUntil        *time.Time `json:"muted_until"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Chat) TableName() string {
+	return "chats"
+}
@@ -8,6 +8,9 @@ type Chatbot struct {
 	WelcomeMessage string    `json:"welcome_message" gorm:"type:text;not null"`
 	MediaURL       *string   `json:"media_url" gorm:"type:varchar(255)"`
 	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	SimulateTyping bool      `json:"simulate_typing" gorm:"default:false"`
+	TypingSpeed    int       `json:"typing_speed" gorm:"default:0"` // Characters per second, 0 uses the default
+	AutoRead       bool      `json:"auto_read" gorm:"default:false"`
 	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
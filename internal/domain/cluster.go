@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Instance is a running server process taking part in the cluster
+type Instance struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	URL         string    `json:"url" gorm:"type:varchar(255)"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at" gorm:"index"`
+}
+
+func (Instance) TableName() string {
+	return "instances"
+}
+
+// SessionLease records which instance runs a session. Only the holder may
+// connect the session's device, and it has to renew the lease before it expires.
+type SessionLease struct {
+	SessionID  string    `json:"session_id" gorm:"primaryKey;type:varchar(255)"`
+	InstanceID string    `json:"instance_id" gorm:"type:varchar(100);not null;index"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+}
+
+func (SessionLease) TableName() string {
+	return "session_leases"
+}
@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+type Contact struct {
+	ID                string           `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	UserID            string           `json:"user_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_contact_user_jid"`
+	JID               string           `json:"jid" gorm:"type:varchar(255);not null;uniqueIndex:idx_contact_user_jid"`
+	Name              string           `json:"name" gorm:"-"` // Best available name, filled in when returned by the API
+	FullName          string           `json:"full_name" gorm:"type:varchar(255)"`
+	FirstName         string           `json:"first_name" gorm:"type:varchar(255)"`
+	PushName          string           `json:"push_name" gorm:"type:varchar(255)"`
+	BusinessName      string           `json:"business_name" gorm:"type:varchar(255)"`
+	IsBusiness        bool             `json:"is_business" gorm:"default:false"`
+	About             string           `json:"about" gorm:"type:text"`
+	ProfilePictureID  string           `json:"profile_picture_id" gorm:"type:varchar(255)"`
+	ProfilePictureURL string           `json:"profile_picture_url" gorm:"type:text"`
+	BusinessProfile   *BusinessProfile `json:"business_profile,omitempty" gorm:"type:text;serializer:json"`
+	ProfileFetchedAt  *time.Time       `json:"profile_fetched_at"`
+	CreatedAt         time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BusinessProfile holds the public profile of a WhatsApp Business account
+type BusinessProfile struct {
+	Address       string          `json:"address,omitempty"`
+	Email         string          `json:"email,omitempty"`
+	Categories    []string        `json:"categories,omitempty"`
+	TimeZone      string          `json:"time_zone,omitempty"`
+	BusinessHours []BusinessHours `json:"business_hours,omitempty"`
+}
+
+type BusinessHours struct {
+	DayOfWeek string `json:"day_of_week"`
+	Mode      string `json:"mode"`
+	OpenTime  string `json:"open_time,omitempty"`
+	CloseTime string `json:"close_time,omitempty"`
+}
+
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+// DisplayName returns the saved name, falling back to the push name, business name or JID
+func (c *Contact) DisplayName() string {
+	for _, name := range []string{c.FullName, c.FirstName, c.PushName, c.BusinessName} {
+		if name != "" {
+			return name
+		}
+	}
+	return c.JID
+}
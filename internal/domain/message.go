@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type Message struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_message_user_chat_msg;index:idx_message_user_chat_time"`
+	ChatJID   string    `json:"chat_jid" gorm:"type:varchar(255);not null;uniqueIndex:idx_message_user_chat_msg;index:idx_message_user_chat_time"`
+	MessageID string    `json:"message_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_message_user_chat_msg"`
+	SenderJID string    `json:"sender_jid" gorm:"type:varchar(255)"`
+	FromMe    bool      `json:"from_me" gorm:"default:false"`
+	PushName  string    `json:"push_name" gorm:"type:varchar(255)"`
+	Body      string    `json:"body" gorm:"type:text"`
+	Timestamp time.Time `json:"timestamp" gorm:"index:idx_message_user_chat_time"`
+	Source    string    `json:"source" gorm:"type:varchar(20);default:'live'"` // live or history
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (Message) TableName() string {
+	return "messages"
+}
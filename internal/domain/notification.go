@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// Notification tells a user about something that happened to one of their
+// sessions, such as WhatsApp logging it out
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	SessionID string     `json:"session_id" gorm:"type:varchar(255);index"`
+	Type      string     `json:"type" gorm:"type:varchar(50);not null"`
+	Title     string     `json:"title" gorm:"type:varchar(255);not null"`
+	Message   string     `json:"message" gorm:"type:text"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// DefaultPlanName is the built-in plan used when no plans are configured
+const DefaultPlanName = "default"
+
+// Plan limits what an account and its agents can do. Zero means unlimited.
+type Plan struct {
+	Name              string `json:"name"`
+	MaxSessions       int    `json:"maxSessions"`
+	MessagesPerDay    int    `json:"messagesPerDay"`
+	BulkRecipients    int    `json:"bulkRecipients"`
+	RequestsPerMinute int    `json:"requestsPerMinute"`
+}
+
+// Usage metrics counted per account and day
+const (
+	UsageMessages = "messages"
+)
+
+// UsageCounter counts an account's use of a metric over one UTC day
+type UsageCounter struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	Metric    string    `json:"metric" gorm:"primaryKey;type:varchar(30)"`
+	Day       string    `json:"day" gorm:"primaryKey;type:char(10)"` // 2006-01-02
+	Count     int       `json:"count" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (UsageCounter) TableName() string {
+	return "usage_counters"
+}
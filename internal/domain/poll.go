@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+type Poll struct {
+	ID                    string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	UserID                string    `json:"user_id" gorm:"type:varchar(255);not null;index"`
+	ChatJID               string    `json:"chat_jid" gorm:"type:varchar(255);not null"`
+	Question              string    `json:"question" gorm:"type:text;not null"`
+	Options               []string  `json:"options" gorm:"type:text;serializer:json;not null"`
+	SelectableOptionCount int       `json:"selectable_option_count" gorm:"default:1"`
+	CreatedAt             time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (Poll) TableName() string {
+	return "polls"
+}
+
+type PollVote struct {
+	ID              string    `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	PollID          string    `json:"poll_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_poll_voter"`
+	VoterJID        string    `json:"voter_jid" gorm:"type:varchar(255);not null;uniqueIndex:idx_poll_voter"`
+	SelectedOptions []string  `json:"selected_options" gorm:"type:text;serializer:json"`
+	VotedAt         time.Time `json:"voted_at"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (PollVote) TableName() string {
+	return "poll_votes"
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// RefreshToken lets a user get new access tokens without logging in again.
+// Only a hash of the token is stored. Every refresh replaces the token with a
+// new one of the same family; using a replaced token again revokes the family.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	FamilyID  string     `json:"family_id" gorm:"type:varchar(64);not null;index"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
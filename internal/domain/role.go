@@ -0,0 +1,61 @@
+package domain
+
+// User roles. Owners have their own sessions, agents and read-only users work
+// on the sessions of the owner they belong to, admins can access every session.
+const (
+	RoleAdmin    = "admin"
+	RoleOwner    = "owner"
+	RoleAgent    = "agent"
+	RoleReadOnly = "read_only"
+)
+
+// Permissions checked by the API
+const (
+	PermSessionsRead      = "sessions:read"   // Status, history and the session list
+	PermSessionsManage    = "sessions:manage" // Linking (QR and pairing codes), logout, export and import
+	PermMessagesRead      = "messages:read"   // Chats, contacts and poll results
+	PermMessagesSend      = "messages:send"   // Sending, presence and marking chats read
+	PermChatbotRead       = "chatbot:read"
+	PermChatbotManage     = "chatbot:manage"
+	PermNotificationsRead = "notifications:read"
+	PermUsersManage       = "users:manage" // Assigning roles
+	PermAuditRead         = "audit:read"
+)
+
+var rolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermSessionsRead, PermSessionsManage, PermMessagesRead, PermMessagesSend,
+		PermChatbotRead, PermChatbotManage, PermNotificationsRead, PermUsersManage, PermAuditRead,
+	},
+	RoleOwner: {
+		PermSessionsRead, PermSessionsManage, PermMessagesRead, PermMessagesSend,
+		PermChatbotRead, PermChatbotManage, PermNotificationsRead, PermAuditRead,
+	},
+	RoleAgent: {
+		PermSessionsRead, PermMessagesRead, PermMessagesSend, PermChatbotRead, PermNotificationsRead,
+	},
+	RoleReadOnly: {
+		PermSessionsRead, PermMessagesRead, PermChatbotRead, PermNotificationsRead,
+	},
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission reports whether a role grants a permission
+func RoleHasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions returns the permissions a role grants
+func RolePermissions(role string) []string {
+	return append([]string{}, rolePermissions[role]...)
+}
@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// SessionStatusExported marks sessions moved to another server. Their device
+// stays in the store so they can be imported back, but they aren't restored.
+const SessionStatusExported = "exported"
+
+// Session is a WhatsApp account linked by a user. Its ID is the key the
+// WhatsApp manager and per-session data (chatbots, chats, messages) use.
+type Session struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:varchar(255)"`
+	OwnerID         uint       `json:"owner_id" gorm:"not null;uniqueIndex:idx_session_owner_name"`
+	Name            string     `json:"name" gorm:"type:varchar(100);not null;uniqueIndex:idx_session_owner_name"`
+	DeviceJID       string     `json:"device_jid" gorm:"type:varchar(255);index"`
+	Status          string     `json:"status" gorm:"type:varchar(50);default:'not_initialized'"`
+	StatusReason    string     `json:"status_reason" gorm:"type:varchar(255)"`
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+	LastConnectedAt *time.Time `json:"last_connected_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// SessionStatusChange is one entry in a session's status history
+type SessionStatusChange struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SessionID string    `json:"session_id" gorm:"type:varchar(255);not null;index"`
+	Status    string    `json:"status" gorm:"type:varchar(50);not null"`
+	Reason    string    `json:"reason" gorm:"type:varchar(255)"`
+	DeviceJID string    `json:"device_jid" gorm:"type:varchar(255)"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+func (SessionStatusChange) TableName() string {
+	return "session_status_history"
+}
@@ -8,8 +8,11 @@ type User struct {
 	Email           string     `json:"email" gorm:"type:varchar(255);uniqueIndex;"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"type:timestamp"`
 	Password        string     `json:"-" gorm:"type:varchar(255);not null"`
-	RememberToken   *string    `json:"remember_token" gorm:"type:varchar(100)"`
+	RememberToken   *string    `json:"-" gorm:"type:varchar(100)"`
 	ChatbotID       *string    `json:"chatbot_id" gorm:"type:varchar(255)"`
+	Role            string     `json:"role" gorm:"type:varchar(20);not null;default:owner"`
+	OwnerID         *uint      `json:"owner_id" gorm:"index"`        // Account whose sessions agents and read-only users work on
+	Plan            string     `json:"plan" gorm:"type:varchar(50)"` // Empty uses DEFAULT_PLAN
 	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -17,3 +20,11 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+// TenantID returns the account whose sessions the user can access
+func (u *User) TenantID() uint {
+	if (u.Role == RoleAgent || u.Role == RoleReadOnly) && u.OwnerID != nil {
+		return *u.OwnerID
+	}
+	return u.ID
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	userRepo      repository.UserRepository
+}
+
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService, userRepo repository.UserRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		userRepo:      userRepo,
+	}
+}
+
+// CreateAPIKey issues an API key for the authenticated user. The key is only
+// shown in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var req service.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.userRepo.FindByID(middleware.GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load user",
+			"details": err.Error(),
+		})
+	}
+
+	raw, key, err := h.apiKeyService.Create(user, req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Failed to create API key",
+			"details": err.Error(),
+		})
+	}
+
+	middleware.AuditDetail(c, "keyPrefix", key.Prefix)
+	middleware.AuditDetail(c, "scopes", key.Scopes)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"apiKey":  raw,
+		"key":     key,
+		"message": "Store the API key now, it can't be shown again.",
+	})
+}
+
+// ListAPIKeys returns the authenticated user's API keys without the secrets
+func (h *APIKeyHandler) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.apiKeyService.List(middleware.GetUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list API keys",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"keys":  keys,
+		"total": len(keys),
+	})
+}
+
+// RevokeAPIKey revokes one of the authenticated user's API keys
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key id",
+		})
+	}
+
+	middleware.AuditDetail(c, "keyId", id)
+
+	if err := h.apiKeyService.Revoke(middleware.GetUserID(c), uint(id)); err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			status = fiber.StatusNotFound
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
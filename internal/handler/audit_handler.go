@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLog returns the audit log of the authenticated user's account, newest
+// first. Admins can read another account's log with tenantId, or every
+// account's with all=true.
+func (h *AuditHandler) GetAuditLog(c *fiber.Ctx) error {
+	filter := domain.AuditFilter{
+		TenantID:  middleware.GetTenantID(c),
+		ActorID:   uint(max(c.QueryInt("actorId", 0), 0)),
+		Action:    c.Query("action"),
+		SessionID: c.Query("sessionId"),
+		ChatbotID: c.Query("chatbotId"),
+		Outcome:   c.Query("outcome"),
+		BeforeID:  uint(max(c.QueryInt("before", 0), 0)),
+		Limit:     c.QueryInt("limit", 100),
+	}
+
+	requested := c.QueryInt("tenantId", 0)
+	all := c.QueryBool("all", false)
+	if requested > 0 || all {
+		if !middleware.IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Only admins can read other accounts' audit logs",
+			})
+		}
+		filter.TenantID = uint(requested)
+		filter.AllTenants = all
+	}
+
+	switch filter.Outcome {
+	case "", domain.AuditSuccess, domain.AuditDenied, domain.AuditFailure:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "outcome must be one of: success, denied, failure",
+		})
+	}
+
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": param + " must be an RFC 3339 timestamp",
+			})
+		}
+		*target = parsed
+	}
+
+	entries, err := h.auditService.Find(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load audit log",
+			"details": err.Error(),
+		})
+	}
+
+	// Pass nextBefore as before to get the next page, an empty page is the end
+	var nextBefore uint
+	if len(entries) > 0 {
+		nextBefore = entries[len(entries)-1].ID
+	}
+
+	return c.JSON(fiber.Map{
+		"entries":    entries,
+		"total":      len(entries),
+		"nextBefore": nextBefore,
+	})
+}
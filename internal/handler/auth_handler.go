@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type AuthHandler struct {
+	authService *service.AuthService
+	userRepo    repository.UserRepository
+}
+
+func NewAuthHandler(authService *service.AuthService, userRepo repository.UserRepository) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		userRepo:    userRepo,
+	}
+}
+
+// Register creates an owner account. Log in afterwards to get tokens.
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	var req struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	middleware.AuditDetail(c, "email", req.Email)
+
+	user, err := h.authService.Register(req.Name, req.Email, req.Password)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, service.ErrRegistrationClosed):
+			status = fiber.StatusForbidden
+		case errors.Is(err, service.ErrEmailTaken):
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	middleware.SetAuditActor(c, user)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"user":    user,
+	})
+}
+
+// Login exchanges an email and password for an access and a refresh token
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	middleware.AuditDetail(c, "email", req.Email)
+
+	user, tokens, err := h.authService.Login(req.Email, req.Password)
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to log in",
+			"details": err.Error(),
+		})
+	}
+	middleware.SetAuditActor(c, user)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"user":    user,
+		"tokens":  tokens,
+	})
+}
+
+// Refresh exchanges a refresh token for new tokens. The old refresh token
+// can't be used again.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refreshToken is required",
+		})
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to refresh tokens",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"tokens":  tokens,
+	})
+}
+
+// Logout revokes a refresh token, or every refresh token of the user with all
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+		All          bool   `json:"all"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.RefreshToken == "" && !req.All {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refreshToken or all is required",
+		})
+	}
+
+	middleware.AuditDetail(c, "all", req.All)
+
+	err := h.authService.Logout(middleware.GetUserID(c), req.RefreshToken, req.All)
+	if errors.Is(err, service.ErrInvalidRefreshToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to log out",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// Me returns the authenticated user with their role and permissions
+func (h *AuthHandler) Me(c *fiber.Ctx) error {
+	user, err := h.userRepo.FindByID(middleware.GetUserID(c))
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User no longer exists",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load user",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"user":        user,
+		"tenantId":    user.TenantID(),
+		"permissions": domain.RolePermissions(user.Role),
+	})
+}
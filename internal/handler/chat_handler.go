@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ChatHandler struct {
+	chatService *service.ChatService
+}
+
+func NewChatHandler(chatService *service.ChatService) *ChatHandler {
+	return &ChatHandler{
+		chatService: chatService,
+	}
+}
+
+// ListChats returns the chats of a session with their last message and unread counter
+func (h *ChatHandler) ListChats(c *fiber.Ctx) error {
+	filter := repository.ChatFilter{
+		Search:     c.Query("q", c.Query("search")),
+		UnreadOnly: c.QueryBool("unread", false),
+		SortBy:     c.Query("sort", "last_activity"),
+		Desc:       c.Query("order", "desc") != "asc",
+		Limit:      c.QueryInt("limit", 50),
+		Offset:     c.QueryInt("offset", 0),
+	}
+
+	switch filter.SortBy {
+	case "last_activity", "unread_count", "name":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "sort must be one of: last_activity, unread_count, name",
+		})
+	}
+
+	if archived := c.Query("archived"); archived != "" {
+		value, err := strconv.ParseBool(archived)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "archived must be true or false",
+			})
+		}
+		filter.Archived = &value
+	}
+
+	result, err := h.chatService.ListChats(middleware.GetSessionID(c), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list chats",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// MarkRead marks incoming messages of a chat as read (blue ticks)
+func (h *ChatHandler) MarkRead(c *fiber.Ctx) error {
+	var req struct {
+		MessageIDs []string `json:"messageIds"` // Optional, defaults to all unread messages
+		Sender     string   `json:"sender"`     // Required with messageIds in group chats
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+
+	marked, err := h.chatService.MarkRead(userID, c.Params("jid"), req.MessageIDs, req.Sender)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to mark chat as read",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"marked":  marked,
+	})
+}
+
+// UpdateChat applies an app state action (archive, pin, mute, mark_unread and their inverses) to a chat
+func (h *ChatHandler) UpdateChat(c *fiber.Ctx) error {
+	var req struct {
+		Action            string `json:"action"`
+		MuteDurationHours int    `json:"muteDurationHours"` // 0 mutes forever
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	action := service.ChatAction(req.Action)
+	switch action {
+	case service.ChatActionArchive, service.ChatActionUnarchive,
+		service.ChatActionPin, service.ChatActionUnpin,
+		service.ChatActionMute, service.ChatActionUnmute,
+		service.ChatActionUnread:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action must be one of: archive, unarchive, pin, unpin, mute, unmute, mark_unread",
+		})
+	}
+
+	if req.MuteDurationHours < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "muteDurationHours cannot be negative",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+	muteDuration := time.Duration(req.MuteDurationHours) * time.Hour
+
+	if err := h.chatService.ApplyAction(userID, c.Params("jid"), action, muteDuration); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update chat",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"action":  action,
+	})
+}
@@ -36,10 +36,12 @@ func NewChatbotHandler(
 // CreateOrUpdateChatbot creates or updates a chatbot
 func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 	var req struct {
-		UserID         string  `json:"userId"`
 		WelcomeMessage string  `json:"welcomeMessage"`
 		IsActive       *bool   `json:"isActive"`
 		MediaURL       *string `json:"mediaUrl"`
+		SimulateTyping *bool   `json:"simulateTyping"`
+		TypingSpeed    *int    `json:"typingSpeed"` // Characters per second
+		AutoRead       *bool   `json:"autoRead"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -48,12 +50,11 @@ func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 		})
 	}
 
-	chatbotID := middleware.GetChatbotID(c)
 	accountUserID := middleware.GetUserID(c)
+	sessionID := middleware.GetSessionID(c)
 
 	// Validate required fields
 	if err := utils.ValidateRequired(map[string]string{
-		"userId":         req.UserID,
 		"welcomeMessage": req.WelcomeMessage,
 	}); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -61,34 +62,52 @@ func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.TypingSpeed != nil && *req.TypingSpeed < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "typingSpeed cannot be negative",
+		})
+	}
+
 	var chatbot *domain.Chatbot
 	var isUpdate bool
 
-	// Check if chatbot exists
-	if chatbotID != "" {
-		existing, err := h.chatbotRepo.FindByID(chatbotID)
-		if err == nil {
-			// Update existing
-			existing.WelcomeMessage = req.WelcomeMessage
-			existing.UserID = req.UserID
-			existing.MediaURL = req.MediaURL
-			if req.IsActive != nil {
-				existing.IsActive = *req.IsActive
-			} else {
-				existing.IsActive = true
-			}
-			existing.UpdatedAt = time.Now()
-
-			if err := h.chatbotRepo.Update(existing); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error":   "Failed to update chatbot",
-					"details": err.Error(),
-				})
-			}
-
-			chatbot = existing
-			isUpdate = true
+	// Each session has its own chatbot
+	existing, err := h.chatbotRepo.FindByUserID(sessionID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to look up chatbot",
+			"details": err.Error(),
+		})
+	}
+	if err == nil {
+		// Update existing
+		existing.WelcomeMessage = req.WelcomeMessage
+		existing.MediaURL = req.MediaURL
+		if req.IsActive != nil {
+			existing.IsActive = *req.IsActive
+		} else {
+			existing.IsActive = true
+		}
+		if req.SimulateTyping != nil {
+			existing.SimulateTyping = *req.SimulateTyping
 		}
+		if req.TypingSpeed != nil {
+			existing.TypingSpeed = *req.TypingSpeed
+		}
+		if req.AutoRead != nil {
+			existing.AutoRead = *req.AutoRead
+		}
+		existing.UpdatedAt = time.Now()
+
+		if err := h.chatbotRepo.Update(existing); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to update chatbot",
+				"details": err.Error(),
+			})
+		}
+
+		chatbot = existing
+		isUpdate = true
 	}
 
 	// Create new chatbot if not updating
@@ -100,7 +119,7 @@ func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 
 		newChatbot := &domain.Chatbot{
 			ID:             newChatbotID,
-			UserID:         req.UserID,
+			UserID:         sessionID,
 			WelcomeMessage: req.WelcomeMessage,
 			MediaURL:       req.MediaURL,
 			IsActive:       true,
@@ -108,6 +127,15 @@ func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 		if req.IsActive != nil {
 			newChatbot.IsActive = *req.IsActive
 		}
+		if req.SimulateTyping != nil {
+			newChatbot.SimulateTyping = *req.SimulateTyping
+		}
+		if req.TypingSpeed != nil {
+			newChatbot.TypingSpeed = *req.TypingSpeed
+		}
+		if req.AutoRead != nil {
+			newChatbot.AutoRead = *req.AutoRead
+		}
 
 		if err := tx.Create(newChatbot).Error; err != nil {
 			tx.Rollback()
@@ -130,6 +158,9 @@ func (h *ChatbotHandler) CreateOrUpdateChatbot(c *fiber.Ctx) error {
 		chatbot = newChatbot
 	}
 
+	middleware.SetAuditChatbot(c, chatbot.ID)
+	middleware.AuditDetail(c, "created", !isUpdate)
+
 	message := "Chatbot created"
 	if isUpdate {
 		message = "Chatbot updated"
@@ -198,6 +229,7 @@ func (h *ChatbotHandler) CreateOrUpdateOption(c *fiber.Ctx) error {
 	}
 
 	chatbotID := middleware.GetChatbotID(c)
+	middleware.AuditDetail(c, "optionKey", req.OptionKey)
 
 	// Validate required fields
 	if err := utils.ValidateRequired(map[string]string{
@@ -289,12 +321,13 @@ func (h *ChatbotHandler) CreateOrUpdateOption(c *fiber.Ctx) error {
 
 // DeleteOption deletes a chatbot option
 func (h *ChatbotHandler) DeleteOption(c *fiber.Ctx) error {
-	userID := c.Params("userId")
+	userID := middleware.GetSessionID(c)
 	optionKey := c.Params("optionKey")
+	middleware.AuditDetail(c, "optionKey", optionKey)
 
-	if userID == "" || optionKey == "" {
+	if optionKey == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId and optionKey are required",
+			"error": "optionKey is required",
 		})
 	}
 
@@ -304,6 +337,7 @@ func (h *ChatbotHandler) DeleteOption(c *fiber.Ctx) error {
 			"error": "Chatbot not found",
 		})
 	}
+	middleware.SetAuditChatbot(c, chatbot.ID)
 
 	option, err := h.optionRepo.FindByKey(chatbot.ID, optionKey)
 	if err != nil {
@@ -327,7 +361,7 @@ func (h *ChatbotHandler) DeleteOption(c *fiber.Ctx) error {
 
 // ToggleChatbot toggles chatbot active status
 func (h *ChatbotHandler) ToggleChatbot(c *fiber.Ctx) error {
-	userID := c.Params("userId")
+	userID := middleware.GetSessionID(c)
 
 	var req struct {
 		IsActive bool `json:"isActive"`
@@ -345,6 +379,7 @@ func (h *ChatbotHandler) ToggleChatbot(c *fiber.Ctx) error {
 			"error": "Chatbot not found",
 		})
 	}
+	middleware.SetAuditChatbot(c, chatbot.ID)
 
 	chatbot.IsActive = req.IsActive
 	chatbot.UpdatedAt = time.Now()
@@ -370,7 +405,7 @@ func (h *ChatbotHandler) ToggleChatbot(c *fiber.Ctx) error {
 
 // DeleteChatbot deletes a chatbot
 func (h *ChatbotHandler) DeleteChatbot(c *fiber.Ctx) error {
-	userID := c.Params("userId")
+	userID := middleware.GetSessionID(c)
 
 	chatbot, err := h.chatbotRepo.FindByUserID(userID)
 	if err != nil {
@@ -378,6 +413,7 @@ func (h *ChatbotHandler) DeleteChatbot(c *fiber.Ctx) error {
 			"error": "Chatbot not found",
 		})
 	}
+	middleware.SetAuditChatbot(c, chatbot.ID)
 
 	if err := h.chatbotRepo.Delete(chatbot.ID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ContactHandler struct {
+	contactService *service.ContactService
+}
+
+func NewContactHandler(contactService *service.ContactService) *ContactHandler {
+	return &ContactHandler{
+		contactService: contactService,
+	}
+}
+
+// ListContacts returns the contact directory of a session
+func (h *ContactHandler) ListContacts(c *fiber.Ctx) error {
+	userID := middleware.GetSessionID(c)
+
+	result, err := h.contactService.ListContacts(userID, repository.ContactFilter{
+		Search: c.Query("q", c.Query("search")),
+		Limit:  c.QueryInt("limit", 50),
+		Offset: c.QueryInt("offset", 0),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list contacts",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// GetContact returns a contact with profile picture, about text and business profile
+func (h *ContactHandler) GetContact(c *fiber.Ctx) error {
+	userID := middleware.GetSessionID(c)
+
+	contact, err := h.contactService.GetContact(userID, c.Params("jid"), c.QueryBool("refresh", false))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to get contact",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(contact)
+}
@@ -1,28 +1,28 @@
 package handler
 
 import (
-	"fmt"
-
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type MessageHandler struct {
 	messageService *service.MessageService
+	pollService    *service.PollService
 }
 
-func NewMessageHandler(messageService *service.MessageService) *MessageHandler {
+func NewMessageHandler(messageService *service.MessageService, pollService *service.PollService) *MessageHandler {
 	return &MessageHandler{
 		messageService: messageService,
+		pollService:    pollService,
 	}
 }
 
 // SendTextMessage handles sending a text message
 func (h *MessageHandler) SendTextMessage(c *fiber.Ctx) error {
 	var req struct {
-		UserID  string `json:"userId"`
 		Phone   string `json:"phone"`
 		Message string `json:"message"`
 	}
@@ -43,15 +43,14 @@ func (h *MessageHandler) SendTextMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Use userId from request if provided, otherwise from auth token
-	userID := req.UserID
-	if userID == "" {
-		tokenUserID := middleware.GetUserID(c)
-		userID = fmt.Sprintf("%d", tokenUserID)
-	}
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
 
 	resp, err := h.messageService.SendTextMessage(userID, req.Phone, req.Message)
 	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to send message",
 			"details": err.Error(),
@@ -69,7 +68,6 @@ func (h *MessageHandler) SendTextMessage(c *fiber.Ctx) error {
 // SendMediaMessage handles sending a media message (image/video/document)
 func (h *MessageHandler) SendMediaMessage(c *fiber.Ctx) error {
 	var req struct {
-		UserID   string `json:"userId"`
 		Phone    string `json:"phone"`
 		Caption  string `json:"caption"`
 		MediaURL string `json:"mediaUrl"` // Changed from imageUrl to mediaUrl for clarity
@@ -97,15 +95,14 @@ func (h *MessageHandler) SendMediaMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Use userId from request if provided, otherwise from auth token
-	userID := req.UserID
-	if userID == "" {
-		tokenUserID := middleware.GetUserID(c)
-		userID = fmt.Sprintf("%d", tokenUserID)
-	}
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
 
 	resp, err := h.messageService.SendMediaMessage(userID, req.Phone, req.MediaURL, req.Caption)
 	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to send media",
 			"details": err.Error(),
@@ -120,10 +117,60 @@ func (h *MessageHandler) SendMediaMessage(c *fiber.Ctx) error {
 	})
 }
 
+// SendAudio handles sending an audio file or voice note
+func (h *MessageHandler) SendAudio(c *fiber.Ctx) error {
+	var req struct {
+		Phone    string `json:"phone"`
+		AudioURL string `json:"audioUrl"`
+		PTT      *bool  `json:"ptt"` // Send as voice note, defaults to true
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Validate required fields
+	if err := utils.ValidateRequired(map[string]string{
+		"phone":    req.Phone,
+		"audioUrl": req.AudioURL,
+	}); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	ptt := true
+	if req.PTT != nil {
+		ptt = *req.PTT
+	}
+
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
+
+	resp, err := h.messageService.SendAudioMessage(userID, req.Phone, req.AudioURL, ptt)
+	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send audio",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Audio sent successfully",
+		"message_id": resp.MessageID,
+		"timestamp":  resp.Timestamp,
+	})
+}
+
 // SendBulkTextMessages handles sending bulk text messages
 func (h *MessageHandler) SendBulkTextMessages(c *fiber.Ctx) error {
 	var req struct {
-		UserID  string   `json:"userId"`
 		Phones  []string `json:"phones"`
 		Message string   `json:"message"`
 	}
@@ -140,14 +187,20 @@ func (h *MessageHandler) SendBulkTextMessages(c *fiber.Ctx) error {
 		})
 	}
 
-	// Use userId from request if provided, otherwise from auth token
-	userID := req.UserID
-	if userID == "" {
-		tokenUserID := middleware.GetUserID(c)
-		userID = fmt.Sprintf("%d", tokenUserID)
-	}
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipients", len(req.Phones))
 
-	results := h.messageService.SendBulkTextMessages(userID, req.Phones, req.Message)
+	results, err := h.messageService.SendBulkTextMessages(userID, req.Phones, req.Message)
+	if quotaErr, ok := err.(*service.QuotaError); ok {
+		return middleware.QuotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send messages",
+			"details": err.Error(),
+		})
+	}
+	middleware.AuditDetail(c, "failed", countFailed(results))
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -158,7 +211,6 @@ func (h *MessageHandler) SendBulkTextMessages(c *fiber.Ctx) error {
 // SendBulkMediaMessages handles sending bulk media messages (image/video/document)
 func (h *MessageHandler) SendBulkMediaMessages(c *fiber.Ctx) error {
 	var req struct {
-		UserID   string   `json:"userId"`
 		Phones   []string `json:"phones"`
 		Message  string   `json:"message"`
 		MediaURL string   `json:"mediaUrl"` // New field name
@@ -182,17 +234,210 @@ func (h *MessageHandler) SendBulkMediaMessages(c *fiber.Ctx) error {
 		})
 	}
 
-	// Use userId from request if provided, otherwise from auth token
-	userID := req.UserID
-	if userID == "" {
-		tokenUserID := middleware.GetUserID(c)
-		userID = fmt.Sprintf("%d", tokenUserID)
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipients", len(req.Phones))
+
+	results, err := h.messageService.SendBulkMediaMessages(userID, req.Phones, req.MediaURL, req.Message)
+	if quotaErr, ok := err.(*service.QuotaError); ok {
+		return middleware.QuotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send messages",
+			"details": err.Error(),
+		})
+	}
+	middleware.AuditDetail(c, "failed", countFailed(results))
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"results": results,
+	})
+}
+
+// SendLocation handles sending a location pin
+func (h *MessageHandler) SendLocation(c *fiber.Ctx) error {
+	var req struct {
+		Phone     string   `json:"phone"`
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+		Name      string   `json:"name"`
+		Address   string   `json:"address"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Phone == "" || req.Latitude == nil || req.Longitude == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "phone, latitude and longitude are required",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
+
+	resp, err := h.messageService.SendLocationMessage(userID, req.Phone, service.LocationPin{
+		Latitude:  *req.Latitude,
+		Longitude: *req.Longitude,
+		Name:      req.Name,
+		Address:   req.Address,
+	})
+	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send location",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Location sent successfully",
+		"message_id": resp.MessageID,
+		"timestamp":  resp.Timestamp,
+	})
+}
+
+// SendContact handles sending one or more contact cards
+func (h *MessageHandler) SendContact(c *fiber.Ctx) error {
+	var req struct {
+		Phone    string                `json:"phone"`
+		Contact  *service.ContactCard  `json:"contact"`  // Single contact
+		Contacts []service.ContactCard `json:"contacts"` // Multiple contacts
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	contacts := req.Contacts
+	if req.Contact != nil {
+		contacts = append([]service.ContactCard{*req.Contact}, contacts...)
+	}
+
+	if req.Phone == "" || len(contacts) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "phone and contact (or contacts) are required",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
+
+	resp, err := h.messageService.SendContactMessage(userID, req.Phone, contacts)
+	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send contact",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Contact sent successfully",
+		"message_id": resp.MessageID,
+		"timestamp":  resp.Timestamp,
+	})
+}
+
+// SendPoll handles creating a poll in a chat
+func (h *MessageHandler) SendPoll(c *fiber.Ctx) error {
+	var req struct {
+		Phone           string   `json:"phone"`
+		Question        string   `json:"question"`
+		Options         []string `json:"options"`
+		SelectableCount *int     `json:"selectableCount"` // 0 allows any number of choices
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Phone == "" || req.Question == "" || len(req.Options) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "phone, question and options are required",
+		})
+	}
+
+	selectableCount := 1
+	if req.SelectableCount != nil {
+		selectableCount = *req.SelectableCount
 	}
 
-	results := h.messageService.SendBulkMediaMessages(userID, req.Phones, req.MediaURL, req.Message)
+	userID := middleware.GetSessionID(c)
+	middleware.AuditDetail(c, "recipient", req.Phone)
+
+	resp, err := h.pollService.SendPoll(userID, req.Phone, req.Question, req.Options, selectableCount)
+	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to send poll",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"message":    "Poll sent successfully",
+		"poll_id":    resp.MessageID,
+		"message_id": resp.MessageID,
+		"timestamp":  resp.Timestamp,
+	})
+}
+
+// GetPollResults returns the current vote tally of a poll
+func (h *MessageHandler) GetPollResults(c *fiber.Ctx) error {
+	pollID := c.Params("pollId")
+	if pollID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "pollId is required",
+		})
+	}
+
+	results, err := h.pollService.GetResults(pollID)
+	if err == nil && results.Poll.UserID != middleware.GetSessionID(c) {
+		err = gorm.ErrRecordNotFound // Don't reveal polls of other sessions
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Poll not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to fetch poll results",
+			"details": err.Error(),
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
 		"results": results,
 	})
 }
+
+// countFailed counts the recipients a bulk send failed for
+func countFailed(results []service.BulkSendResult) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandler struct {
+	notificationService *service.NotificationService
+}
+
+func NewNotificationHandler(notificationService *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// ListNotifications returns the authenticated user's notifications
+func (h *NotificationHandler) ListNotifications(c *fiber.Ctx) error {
+	result, err := h.notificationService.ListNotifications(middleware.GetTenantID(c), c.QueryBool("unread", false), c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list notifications",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// MarkRead marks a notification as read. The id "all" marks every notification.
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	var id uint64
+	if param := c.Params("id"); param != "all" {
+		var err error
+		id, err = strconv.ParseUint(param, 10, 64)
+		if err != nil || id == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid notification id",
+			})
+		}
+	}
+
+	if err := h.notificationService.MarkRead(middleware.GetTenantID(c), uint(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to mark notification as read",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PresenceHandler struct {
+	presenceService *service.PresenceService
+}
+
+func NewPresenceHandler(presenceService *service.PresenceService) *PresenceHandler {
+	return &PresenceHandler{
+		presenceService: presenceService,
+	}
+}
+
+// SetAvailability marks the session as online ("available") or offline ("unavailable")
+func (h *PresenceHandler) SetAvailability(c *fiber.Ctx) error {
+	var req struct {
+		State string `json:"state"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.State != "available" && req.State != "unavailable" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "state must be one of: available, unavailable",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+
+	if err := h.presenceService.SetAvailability(userID, req.State == "available"); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to set availability",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"state":   req.State,
+	})
+}
+
+// SetChatPresence shows typing or recording in a chat, or clears it with "paused"
+func (h *PresenceHandler) SetChatPresence(c *fiber.Ctx) error {
+	var req struct {
+		Phone string `json:"phone"`
+		State string `json:"state"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	state := whatsmeow_client.ChatState(req.State)
+	switch state {
+	case whatsmeow_client.ChatStateComposing, whatsmeow_client.ChatStateRecording, whatsmeow_client.ChatStatePaused:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "state must be one of: composing, recording, paused",
+		})
+	}
+
+	if req.Phone == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "phone is required",
+		})
+	}
+
+	userID := middleware.GetSessionID(c)
+
+	if err := h.presenceService.SetChatPresence(userID, req.Phone, state); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to set chat presence",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"state":   state,
+	})
+}
@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"time"
 
-	// "github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
-	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
 	"github.com/gofiber/fiber/v2"
 )
@@ -13,26 +16,81 @@ import (
 type SessionHandler struct {
 	waManager      *whatsmeow_client.Manager
 	chatbotService *service.ChatbotService
+	sessionService *service.SessionService
+	quotaService   *service.QuotaService
+	cluster        *middleware.ClusterMiddleware
 }
 
-func NewSessionHandler(waManager *whatsmeow_client.Manager, chatbotService *service.ChatbotService) *SessionHandler {
+func NewSessionHandler(waManager *whatsmeow_client.Manager, chatbotService *service.ChatbotService, sessionService *service.SessionService, quotaService *service.QuotaService, cluster *middleware.ClusterMiddleware) *SessionHandler {
 	return &SessionHandler{
 		waManager:      waManager,
 		chatbotService: chatbotService,
+		sessionService: sessionService,
+		quotaService:   quotaService,
+		cluster:        cluster,
 	}
 }
 
-// InitSession initializes a new WhatsApp session
+// InitSession initializes a WhatsApp session of the authenticated user. Sessions
+// are identified by name, so initializing the same name again resumes it.
 func (h *SessionHandler) InitSession(c *fiber.Ctx) error {
-	// userID := middleware.GetUserID(c)
-	userID := utils.GenerateID("user")
-	// if userID == 0 {
-	// 	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-	// 		"error": "User ID not found in token",
-	// 	})
-	// }
-
-	// userIDStr := fmt.Sprintf("%d", userID) // Convert uint to string
+	var req struct {
+		Name  string `json:"name"`  // Optional, defaults to "default"
+		Phone string `json:"phone"` // Optional, links with a pairing code instead of a QR code
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	session, _, err := h.sessionService.GetOrCreate(middleware.GetTenantID(c), req.Name, h.quotaService.CheckSessionCount)
+	if quotaErr, ok := err.(*service.QuotaError); ok {
+		return middleware.QuotaExceeded(c, quotaErr)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Failed to create session",
+			"details": err.Error(),
+		})
+	}
+	userID := session.ID
+	c.Locals("session_id", userID)
+
+	// The device now belongs to another server, linking it here again would
+	// run the same account twice
+	if session.Status == domain.SessionStatusExported {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Session was exported to another server. Import it back or use another name.",
+		})
+	}
+
+	// The session is known now, let the instance that runs it do the rest
+	if forwarded, err := h.cluster.Forward(c, userID); forwarded || err != nil {
+		return err
+	}
+
+	if req.Phone != "" {
+		clientData, pairCode, err := h.waManager.InitializeClientWithPhone(userID, req.Phone)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to initialize session",
+				"details": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"success":     true,
+			"userId":      userID,
+			"name":        session.Name,
+			"message":     "Session initialized. On the phone open WhatsApp > Linked devices > Link with phone number and enter the pairing code.",
+			"status":      clientData.GetStatus(),
+			"pairingCode": pairCode,
+		})
+	}
+
 	clientData, err := h.waManager.InitializeClient(userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -44,6 +102,7 @@ func (h *SessionHandler) InitSession(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"success": true,
 		"userId":  userID,
+		"name":    session.Name,
 		"message": "Session initialized. Scan QR code to authenticate.",
 		"status":  clientData.GetStatus(),
 	})
@@ -51,13 +110,7 @@ func (h *SessionHandler) InitSession(c *fiber.Ctx) error {
 
 // GetQRCode returns the QR code for authentication
 func (h *SessionHandler) GetQRCode(c *fiber.Ctx) error {
-	userID := c.Params("userId")
-
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId is required",
-		})
-	}
+	userID := middleware.GetSessionID(c)
 
 	clientData, exists := h.waManager.GetClient(userID)
 	if !exists {
@@ -69,13 +122,11 @@ func (h *SessionHandler) GetQRCode(c *fiber.Ctx) error {
 	qrCode := clientData.GetQRCode()
 	status := clientData.GetStatus()
 
-	if status == "auth_failed" {
-		if err := h.waManager.RegenerateQR(userID); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error":   "Failed to regenerate QR code",
-				"details": err.Error(),
-			})
-		}
+	if status == whatsmeow_client.StatusAuthFailed {
+		return c.JSON(fiber.Map{
+			"status":  status,
+			"message": "QR code expired. Regenerate it with POST /api/session/qr/:userId/regenerate",
+		})
 	}
 
 	if qrCode == "" || qrCode == "undefined" {
@@ -93,17 +144,29 @@ func (h *SessionHandler) GetQRCode(c *fiber.Ctx) error {
 
 // GetStatus returns the current session status
 func (h *SessionHandler) GetStatus(c *fiber.Ctx) error {
-	userID := c.Params("userId")
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId is required",
-		})
-	}
+	userID := middleware.GetSessionID(c)
 
 	clientData, exists := h.waManager.GetClient(userID)
-
-	fmt.Sprintf("%v", exists)
 	if !exists {
+		// Sessions logged out by WhatsApp keep their last status and reason
+		session, err := h.sessionService.Get(userID)
+		if err == nil && session.Status == string(whatsmeow_client.StatusLoggedOut) {
+			return c.JSON(fiber.Map{
+				"status":       whatsmeow_client.StatusLoggedOut,
+				"reason":       session.StatusReason,
+				"is_logged_in": false,
+				"message":      "Session logged out. Initialize it again to link a device.",
+			})
+		}
+		if err == nil && session.Status == domain.SessionStatusExported {
+			return c.JSON(fiber.Map{
+				"status":       whatsmeow_client.StatusExported,
+				"reason":       session.StatusReason,
+				"is_logged_in": false,
+				"message":      "Session was exported to another server.",
+			})
+		}
+
 		return c.JSON(fiber.Map{
 			"status":       whatsmeow_client.StatusNotInitialized,
 			"is_logged_in": false,
@@ -113,22 +176,99 @@ func (h *SessionHandler) GetStatus(c *fiber.Ctx) error {
 
 	status := clientData.GetStatus()
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"status":       status,
-		"is_logged_in": status == whatsmeow_client.StatusReady,
+		"is_logged_in": isLoggedIn(status),
+		"history_sync": clientData.HistorySync(),
+		"reconnect":    clientData.ReconnectInfo(),
+	}
+	if h.waManager.HibernationEnabled() {
+		response["hibernation"] = clientData.HibernationInfo()
+	}
+	if reason := clientData.GetStatusReason(); reason != "" {
+		response["reason"] = reason
+	}
+	if pairCode := clientData.GetPairCode(); pairCode != "" {
+		response["pairing_code"] = pairCode
+	}
+
+	return c.JSON(response)
+}
+
+// StreamEvents pushes QR codes, pairing and connection changes of a session as
+// server-sent events, starting with a snapshot of the current state
+func (h *SessionHandler) StreamEvents(c *fiber.Ctx) error {
+	userID := middleware.GetSessionID(c)
+
+	clientData, exists := h.waManager.GetClient(userID)
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found. Initialize session first.",
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	events, unsubscribe := h.waManager.SubscribeSessionEvents(userID)
+	snapshot := clientData.SessionSnapshot(userID)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		keepAlive := time.NewTicker(15 * time.Second)
+		defer keepAlive.Stop()
+
+		if err := writeSessionEvent(w, snapshot); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := writeSessionEvent(w, evt); err != nil {
+					return // Client went away
+				}
+
+			case <-keepAlive.C:
+				if _, err := w.WriteString(": keep-alive\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
 	})
+
+	return nil
+}
+
+// isLoggedIn reports whether a session has a linked device it is connected
+// with, or can connect with as soon as it is used
+func isLoggedIn(status whatsmeow_client.SessionStatus) bool {
+	return status == whatsmeow_client.StatusReady || status == whatsmeow_client.StatusHibernating
 }
 
-// In internal/handler/session_handler.go
+func writeSessionEvent(w *bufio.Writer, evt whatsmeow_client.SessionEvent) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
 
 // RegenerateQR regenerates a new QR code for authentication
 func (h *SessionHandler) RegenerateQR(c *fiber.Ctx) error {
-	userID := c.Params("userId")
-	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId is required",
-		})
-	}
+	userID := middleware.GetSessionID(c)
 
 	if err := h.waManager.RegenerateQR(userID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -139,40 +279,44 @@ func (h *SessionHandler) RegenerateQR(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "QR code regeneration started. Poll /api/session/qr/:userId for new code.",
+		"message": "QR code regeneration started. Poll /api/session/qr/:userId or listen on /api/session/events/:userId for the new code.",
 	})
 }
 
-// Logout logs out and destroys the session
-func (h *SessionHandler) Logout(c *fiber.Ctx) error {
-	var req struct {
-		UserID string `json:"userId"`
-	}
-
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+// GetStatusHistory returns the recent status changes of a session
+func (h *SessionHandler) GetStatusHistory(c *fiber.Ctx) error {
+	history, err := h.sessionService.GetStatusHistory(middleware.GetSessionID(c), c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to fetch status history",
+			"details": err.Error(),
 		})
 	}
 
-	if req.UserID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "userId is required",
-		})
-	}
+	return c.JSON(fiber.Map{
+		"history": history,
+	})
+}
+
+// Logout unlinks the session's device. The session itself is kept with its
+// status history and can be linked again by initializing the same name.
+func (h *SessionHandler) Logout(c *fiber.Ctx) error {
+	userID := middleware.GetSessionID(c)
 
 	// Set chatbot to inactive
-	if err := h.chatbotService.SetChatbotInactive(req.UserID); err != nil {
+	if err := h.chatbotService.SetChatbotInactive(userID); err != nil {
 		// Log but don't fail the logout
 		c.Append("X-Warning", "Failed to deactivate chatbot")
 	}
 
-	// Logout from WhatsApp
-	if err := h.waManager.LogoutClient(req.UserID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to logout",
-			"details": err.Error(),
-		})
+	// Logout from WhatsApp if the session is running
+	if _, exists := h.waManager.GetClient(userID); exists {
+		if err := h.waManager.LogoutClient(userID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to logout",
+				"details": err.Error(),
+			})
+		}
 	}
 
 	return c.JSON(fiber.Map{
@@ -181,20 +325,100 @@ func (h *SessionHandler) Logout(c *fiber.Ctx) error {
 	})
 }
 
-// GetAllSessions returns all active sessions
+// GetAllSessions returns the sessions of the authenticated user's account.
+// Admins can list another account's sessions with ownerId.
 func (h *SessionHandler) GetAllSessions(c *fiber.Ctx) error {
-	sessions := h.waManager.GetAllSessions()
+	ownerID := middleware.GetTenantID(c)
+	if requested := c.QueryInt("ownerId", 0); requested > 0 {
+		if !middleware.IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Only admins can list other accounts' sessions",
+			})
+		}
+		ownerID = uint(requested)
+	}
+
+	owned, err := h.sessionService.ListForOwner(ownerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to list sessions",
+			"details": err.Error(),
+		})
+	}
+	if key := middleware.GetAPIKey(c); key != nil {
+		allowed := owned[:0]
+		for _, session := range owned {
+			if key.AllowsSession(session.ID) {
+				allowed = append(allowed, session)
+			}
+		}
+		owned = allowed
+	}
 
+	ids := make([]string, 0, len(owned))
+	for _, session := range owned {
+		ids = append(ids, session.ID)
+	}
+	holders := h.cluster.Holders(ids)
+
+	sessions := make([]fiber.Map, 0, len(owned))
 	activeSessions := 0
-	for _, session := range sessions {
-		if isLoggedIn, ok := session["is_logged_in"].(bool); ok && isLoggedIn {
+	for _, session := range owned {
+		status := whatsmeow_client.StatusNotInitialized
+		reason := ""
+		if session.Status == string(whatsmeow_client.StatusLoggedOut) || session.Status == domain.SessionStatusExported {
+			status = whatsmeow_client.SessionStatus(session.Status)
+			reason = session.StatusReason
+		}
+		// Sessions running on another instance show their last recorded status
+		instanceID, remote := holders[session.ID]
+		if remote && session.Status != "" {
+			if _, local := h.waManager.GetClient(session.ID); !local {
+				status = whatsmeow_client.SessionStatus(session.Status)
+				reason = session.StatusReason
+			}
+		}
+		var historySync *whatsmeow_client.HistorySyncProgress
+		var reconnect *whatsmeow_client.ReconnectInfo
+		var hibernation *whatsmeow_client.HibernationInfo
+		if clientData, exists := h.waManager.GetClient(session.ID); exists {
+			status = clientData.GetStatus()
+			reason = clientData.GetStatusReason()
+			historySync = clientData.HistorySync()
+			info := clientData.ReconnectInfo()
+			reconnect = &info
+			if h.waManager.HibernationEnabled() {
+				sleep := clientData.HibernationInfo()
+				hibernation = &sleep
+			}
+		}
+
+		loggedIn := isLoggedIn(status)
+		if loggedIn {
 			activeSessions++
 		}
+
+		sessions = append(sessions, fiber.Map{
+			"user_id":           session.ID,
+			"name":              session.Name,
+			"status":            status,
+			"reason":            reason,
+			"is_logged_in":      loggedIn,
+			"hibernation":       hibernation,
+			"history_sync":      historySync,
+			"reconnect":         reconnect,
+			"restore":           h.waManager.SessionRestoreStatus(session.ID),
+			"instance_id":       instanceID,
+			"device_jid":        session.DeviceJID,
+			"last_connected_at": session.LastConnectedAt,
+			"created_at":        session.CreatedAt,
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"sessions":        sessions,
 		"total_sessions":  len(sessions),
 		"active_sessions": activeSessions,
+		"restore":         h.waManager.RestoreProgress(),
 	})
 }
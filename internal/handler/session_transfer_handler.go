@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"regexp"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBundleSize limits uploaded session bundles, which hold one device's keys
+// and signal state
+const maxBundleSize = 32 << 20
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type SessionTransferHandler struct {
+	transferService *service.SessionTransferService
+	sessionService  *service.SessionService
+	quotaService    *service.QuotaService
+	cluster         *middleware.ClusterMiddleware
+}
+
+func NewSessionTransferHandler(transferService *service.SessionTransferService, sessionService *service.SessionService, quotaService *service.QuotaService, cluster *middleware.ClusterMiddleware) *SessionTransferHandler {
+	return &SessionTransferHandler{
+		transferService: transferService,
+		sessionService:  sessionService,
+		quotaService:    quotaService,
+		cluster:         cluster,
+	}
+}
+
+// ExportSession disconnects a session and downloads it as an encrypted bundle
+// that can be imported on another server. The session stays exported here.
+func (h *SessionTransferHandler) ExportSession(c *fiber.Ctx) error {
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	session, err := h.sessionService.Get(middleware.GetSessionID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load session",
+			"details": err.Error(),
+		})
+	}
+
+	bundle, err := h.transferService.Export(session, req.Passphrase)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, service.ErrWeakPassphrase) || errors.Is(err, service.ErrSessionNotLinked) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error":   "Failed to export session",
+			"details": err.Error(),
+		})
+	}
+
+	filename := unsafeFilenameChars.ReplaceAllString(session.Name, "_") + ".wasession"
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(bundle)
+}
+
+// ImportSession adds a session exported from another server to the
+// authenticated user. The bundle is sent as a multipart file named "bundle"
+// with passphrase and name fields, or as JSON.
+func (h *SessionTransferHandler) ImportSession(c *fiber.Ctx) error {
+	var req struct {
+		Bundle     json.RawMessage `json:"bundle"`
+		Passphrase string          `json:"passphrase"`
+		Name       string          `json:"name"` // Optional, defaults to the exported session's name
+	}
+
+	if file, err := c.FormFile("bundle"); err == nil {
+		data, err := readBundle(file)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid bundle",
+				"details": err.Error(),
+			})
+		}
+		req.Bundle = data
+		req.Passphrase = c.FormValue("passphrase")
+		req.Name = c.FormValue("name")
+	} else if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Bundle) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bundle is required",
+		})
+	}
+
+	if err := h.quotaService.CheckSessions(middleware.GetTenantID(c)); err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to check session limit",
+			"details": err.Error(),
+		})
+	}
+
+	// In a cluster the instance the session is assigned to starts it
+	session, err := h.transferService.Import(middleware.GetTenantID(c), req.Bundle, req.Passphrase, req.Name, !h.cluster.Clustered(), h.quotaService.CheckSessionCount)
+	if err != nil {
+		if quotaErr, ok := err.(*service.QuotaError); ok {
+			return middleware.QuotaExceeded(c, quotaErr)
+		}
+		status := fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, service.ErrSessionAlreadyHere), errors.Is(err, service.ErrSessionNameConflict):
+			status = fiber.StatusConflict
+		case errors.Is(err, service.ErrBadBundle):
+			status = fiber.StatusUnprocessableEntity
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error":   "Failed to import session",
+			"details": err.Error(),
+		})
+	}
+
+	c.Locals("session_id", session.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"userId":  session.ID,
+		"name":    session.Name,
+		"message": "Session imported. It reconnects with the linked device, no QR code needed.",
+	})
+}
+
+func readBundle(header *multipart.FileHeader) ([]byte, error) {
+	if header.Size > maxBundleSize {
+		return nil, fmt.Errorf("bundle is larger than %d MB", maxBundleSize>>20)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxBundleSize))
+}
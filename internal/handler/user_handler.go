@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/middleware"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	userRepo     repository.UserRepository
+	quotaService *service.QuotaService
+}
+
+func NewUserHandler(userRepo repository.UserRepository, quotaService *service.QuotaService) *UserHandler {
+	return &UserHandler{
+		userRepo:     userRepo,
+		quotaService: quotaService,
+	}
+}
+
+// SetRole changes a user's role. Agents and read-only users must belong to
+// an owner, whose sessions they then work on.
+func (h *UserHandler) SetRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user id",
+		})
+	}
+
+	var req struct {
+		Role    string `json:"role"`
+		OwnerID *uint  `json:"ownerId"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	middleware.AuditDetail(c, "userId", id)
+	middleware.AuditDetail(c, "role", req.Role)
+
+	if !domain.ValidRole(req.Role) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role must be one of admin, owner, agent or read_only",
+		})
+	}
+
+	user, err := h.userRepo.FindByID(uint(id))
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load user",
+			"details": err.Error(),
+		})
+	}
+
+	user.OwnerID = nil
+	if req.Role == domain.RoleAgent || req.Role == domain.RoleReadOnly {
+		if req.OwnerID == nil || *req.OwnerID == user.ID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ownerId is required for agents and read-only users",
+			})
+		}
+		owner, err := h.userRepo.FindByID(*req.OwnerID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Owner not found",
+			})
+		}
+		if owner.Role != domain.RoleOwner && owner.Role != domain.RoleAdmin {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "ownerId must be an owner or admin account",
+			})
+		}
+		user.OwnerID = &owner.ID
+	}
+	user.Role = req.Role
+
+	if err := h.userRepo.Update(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update user",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"user":    user,
+	})
+}
+
+// SetPlan changes the plan of an account. Agents and read-only users are
+// limited by the plan of the account they belong to.
+func (h *UserHandler) SetPlan(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user id",
+		})
+	}
+
+	var req struct {
+		Plan string `json:"plan"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	middleware.AuditDetail(c, "userId", id)
+	middleware.AuditDetail(c, "plan", req.Plan)
+
+	if !h.quotaService.HasPlan(req.Plan) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown plan",
+		})
+	}
+
+	user, err := h.userRepo.FindByID(uint(id))
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load user",
+			"details": err.Error(),
+		})
+	}
+
+	user.Plan = req.Plan
+	if err := h.userRepo.Update(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update user",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"user":    user,
+	})
+}
+
+// GetUsage returns the plan of the authenticated user's account and what it used
+func (h *UserHandler) GetUsage(c *fiber.Ctx) error {
+	usage, err := h.quotaService.Usage(middleware.GetTenantID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to load usage",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(usage)
+}
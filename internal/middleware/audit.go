@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditMiddleware struct {
+	auditService      *service.AuditService
+	clusterMiddleware *ClusterMiddleware
+}
+
+func NewAuditMiddleware(auditService *service.AuditService, clusterMiddleware *ClusterMiddleware) *AuditMiddleware {
+	return &AuditMiddleware{
+		auditService:      auditService,
+		clusterMiddleware: clusterMiddleware,
+	}
+}
+
+// Record writes an audit log entry for the request once it was handled,
+// including requests that were denied. Handlers add details with
+// AuditDetail. Put it before Auth so denied requests are recorded too.
+func (am *AuditMiddleware) Record(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// The instance that received the request records it
+		if am.clusterMiddleware.IsForwarded(c) {
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if e, ok := err.(*fiber.Error); ok {
+			status = e.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+		outcome := domain.AuditSuccess
+		switch {
+		case status == fiber.StatusUnauthorized || status == fiber.StatusForbidden || status == fiber.StatusTooManyRequests:
+			outcome = domain.AuditDenied
+		case status >= 400:
+			outcome = domain.AuditFailure
+		}
+
+		entry := &domain.AuditLog{
+			TenantID:   GetTenantID(c),
+			ActorID:    GetUserID(c),
+			Action:     action,
+			SessionID:  GetSessionID(c),
+			Outcome:    outcome,
+			StatusCode: status,
+			Method:     c.Method(),
+			Path:       truncate(c.Path(), 255),
+			IP:         GetClientIP(c),
+			UserAgent:  truncate(c.Get(fiber.HeaderUserAgent), 255),
+			RequestID:  truncate(c.Get(fiber.HeaderXRequestID), 100),
+		}
+		if key := GetAPIKey(c); key != nil {
+			entry.APIKeyID = &key.ID
+		}
+		if chatbotID, ok := c.Locals("audit_chatbot_id").(string); ok {
+			entry.ChatbotID = chatbotID
+		} else if strings.HasPrefix(action, "chatbot.") {
+			entry.ChatbotID = GetChatbotID(c)
+		}
+		if details, ok := c.Locals("audit_details").(map[string]interface{}); ok {
+			entry.Details = details
+		}
+		am.auditService.Record(entry)
+
+		return err
+	}
+}
+
+// AuditDetail adds a detail to the request's audit log entry
+func AuditDetail(c *fiber.Ctx, key string, value interface{}) {
+	details, ok := c.Locals("audit_details").(map[string]interface{})
+	if !ok {
+		details = make(map[string]interface{})
+		c.Locals("audit_details", details)
+	}
+	details[key] = value
+}
+
+// SetAuditChatbot sets the chatbot the request's audit log entry is about,
+// when it isn't the one of the caller's token
+func SetAuditChatbot(c *fiber.Ctx, chatbotID string) {
+	c.Locals("audit_chatbot_id", chatbotID)
+}
+
+// SetAuditActor sets the actor of requests that aren't authenticated yet, like login
+func SetAuditActor(c *fiber.Ctx, user *domain.User) {
+	c.Locals("user_id", user.ID)
+	c.Locals("tenant_id", user.TenantID())
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
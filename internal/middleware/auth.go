@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/config"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -16,19 +19,62 @@ type Claims struct {
 }
 
 type AuthMiddleware struct {
-	jwtSecret string
+	keys          *jwtKeySet
+	parser        *jwt.Parser
+	issuer        string
+	audience      string
+	accessTTL     time.Duration
+	apiKeyService *service.APIKeyService
 }
 
-func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtSecret: cfg.JWT.Secret,
+func NewAuthMiddleware(cfg *config.Config, apiKeyService *service.APIKeyService) (*AuthMiddleware, error) {
+	keys, err := loadJWTKeys(cfg.JWT)
+	if err != nil {
+		return nil, err
 	}
+	return &AuthMiddleware{
+		keys: keys,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(keys.methods),
+			jwt.WithIssuer(cfg.JWT.Issuer),
+			jwt.WithAudience(cfg.JWT.Audience),
+			jwt.WithExpirationRequired(),
+		),
+		issuer:        cfg.JWT.Issuer,
+		audience:      cfg.JWT.Audience,
+		accessTTL:     time.Duration(cfg.JWT.AccessTokenMinutes) * time.Minute,
+		apiKeyService: apiKeyService,
+	}, nil
 }
 
-// Auth validates JWT token and adds user info to context
+// Auth validates JWT token and adds user info to context. API keys are
+// accepted instead, in X-API-Key or as the bearer token.
 func (am *AuthMiddleware) Auth(c *fiber.Ctx) error {
-	// Get authorization header
-	authHeader := c.Get("Authorization")
+	if apiKey := c.Get("X-API-Key"); apiKey != "" {
+		return am.authAPIKey(c, apiKey)
+	}
+	return am.authBearer(c, c.Get("Authorization"))
+}
+
+// AuthEventStream is Auth for event streams, which also takes an access token
+// in ?access_token= because browsers can't set headers on EventSource requests.
+// API keys don't expire soon enough to end up in URLs and logs, so they aren't
+// accepted there.
+func (am *AuthMiddleware) AuthEventStream(c *fiber.Ctx) error {
+	token := c.Query("access_token")
+	if token == "" || c.Get("X-API-Key") != "" || c.Get("Authorization") != "" {
+		return am.Auth(c)
+	}
+	if strings.HasPrefix(token, domain.APIKeyPrefix) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "API keys can't be sent in the URL, use the X-API-Key header",
+		})
+	}
+	return am.authBearer(c, "Bearer "+token)
+}
+
+// authBearer authenticates a request with the value of its Authorization header
+func (am *AuthMiddleware) authBearer(c *fiber.Ctx, authHeader string) error {
 	if authHeader == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Authorization header required",
@@ -44,11 +90,12 @@ func (am *AuthMiddleware) Auth(c *fiber.Ctx) error {
 	}
 
 	tokenString := parts[1]
+	if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+		return am.authAPIKey(c, tokenString)
+	}
 
-	// Parse and validate token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(am.jwtSecret), nil
-	})
+	// Parse and validate token, including its algorithm, key, issuer and audience
+	token, err := am.parser.ParseWithClaims(tokenString, &Claims{}, am.keys.keyFunc)
 
 	if err != nil || !token.Valid {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -71,19 +118,57 @@ func (am *AuthMiddleware) Auth(c *fiber.Ctx) error {
 	return c.Next()
 }
 
-// GenerateToken generates a JWT token for a user
+// authAPIKey authenticates a request made with an API key
+func (am *AuthMiddleware) authAPIKey(c *fiber.Ctx, raw string) error {
+	key, user, err := am.apiKeyService.Authenticate(raw, GetClientIP(c))
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch err {
+		case service.ErrInvalidAPIKey:
+			status = fiber.StatusUnauthorized
+		case service.ErrAPIKeyIPNotAllowed:
+			status = fiber.StatusForbidden
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Locals("user_id", user.ID)
+	c.Locals("chatbot_id", utils.SafeString(user.ChatbotID))
+	c.Locals("api_key", key)
+	setUser(c, user)
+
+	return c.Next()
+}
+
+// AccessTokenTTL is how long generated tokens are valid
+func (am *AuthMiddleware) AccessTokenTTL() time.Duration {
+	return am.accessTTL
+}
+
+// GenerateToken generates a short-lived access token for a user
 func (am *AuthMiddleware) GenerateToken(userID uint, chatbotID string) (string, error) {
 	claims := Claims{
 		UserID:    userID,
 		ChatbotID: chatbotID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour * 30)), // 30 days
+			Issuer:    am.issuer,
+			Audience:  jwt.ClaimStrings{am.audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(am.accessTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(am.jwtSecret))
+	key := am.keys.current
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.sign)
+}
+
+// PublicKeys returns the keys access tokens can be verified with, empty for HS256
+func (am *AuthMiddleware) PublicKeys() []JWK {
+	return am.keys.publicKeys()
 }
 
 // GetUserID extracts user ID from context
@@ -95,6 +180,12 @@ func GetUserID(c *fiber.Ctx) uint {
 	return userID
 }
 
+// GetAPIKey returns the API key the request was made with, nil for JWTs
+func GetAPIKey(c *fiber.Ctx) *domain.APIKey {
+	key, _ := c.Locals("api_key").(*domain.APIKey)
+	return key
+}
+
 // GetChatbotID extracts chatbot ID from context
 func GetChatbotID(c *fiber.Ctx) string {
 	chatbotID, ok := c.Locals("chatbot_id").(string)
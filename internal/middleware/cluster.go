@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/proxy"
+)
+
+// Headers of requests another instance forwarded. They are signed with the
+// cluster secret, so a request is never passed around more than once and
+// clients can't pass their requests off as forwarded. The client IP is
+// passed along because the receiving instance only sees the forwarding one.
+const (
+	HeaderForwardedByInstance = "X-Forwarded-By-Instance"
+	HeaderForwardTimestamp    = "X-Forward-Timestamp"
+	HeaderForwardSignature    = "X-Forward-Signature"
+	HeaderForwardedClientIP   = "X-Forwarded-Client-IP"
+)
+
+// forwardMaxAge is how old a forwarded request's signature may be, allowing
+// for some clock drift between instances
+const forwardMaxAge = 2 * time.Minute
+
+type ClusterMiddleware struct {
+	clusterService *service.ClusterService // nil when clustering is disabled
+	forward        bool
+	secret         []byte
+}
+
+func NewClusterMiddleware(clusterService *service.ClusterService, forward bool, secret string) *ClusterMiddleware {
+	return &ClusterMiddleware{
+		clusterService: clusterService,
+		forward:        forward,
+		secret:         []byte(secret),
+	}
+}
+
+// ResolveClientIP stores the IP of the client, which is the one the
+// forwarding instance saw for forwarded requests. See GetClientIP.
+func (cm *ClusterMiddleware) ResolveClientIP(c *fiber.Ctx) error {
+	ip := c.IP()
+	if forwardedIP := c.Get(HeaderForwardedClientIP); forwardedIP != "" && cm.IsForwarded(c) {
+		ip = forwardedIP
+	}
+	c.Locals("client_ip", ip)
+	return c.Next()
+}
+
+// GetClientIP returns the IP of the client that sent the request
+func GetClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals("client_ip").(string); ok {
+		return ip
+	}
+	return c.IP()
+}
+
+// RouteSession sends requests for sessions running on another instance to
+// that instance. Must run after RequireSession.
+func (cm *ClusterMiddleware) RouteSession(c *fiber.Ctx) error {
+	handled, err := cm.Forward(c, GetSessionID(c))
+	if handled || err != nil {
+		return err
+	}
+	return c.Next()
+}
+
+// Forward handles the request on the instance that owns the session. It
+// returns false when the session is ours and the request should be handled
+// here. Event streams are redirected rather than proxied, and when forwarding
+// is disabled the client gets a 421 telling it where to go.
+func (cm *ClusterMiddleware) Forward(c *fiber.Ctx, sessionID string) (bool, error) {
+	if cm.clusterService == nil || sessionID == "" {
+		return false, nil
+	}
+
+	owner, err := cm.clusterService.Owner(sessionID)
+	if err != nil {
+		return true, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to find the instance running this session",
+			"details": err.Error(),
+		})
+	}
+	if owner.Local {
+		return false, nil
+	}
+
+	if !cm.forward || owner.URL == "" || cm.IsForwarded(c) {
+		return true, c.Status(fiber.StatusMisdirectedRequest).JSON(fiber.Map{
+			"error":        "Session is running on another instance",
+			"instance_id":  owner.InstanceID,
+			"instance_url": owner.URL,
+		})
+	}
+
+	target := strings.TrimSuffix(owner.URL, "/") + c.OriginalURL()
+	if strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+		return true, c.Redirect(target, fiber.StatusTemporaryRedirect)
+	}
+
+	cm.signForward(c)
+	if err := proxy.Do(c, target); err != nil {
+		return true, c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":        "Failed to reach the instance running this session",
+			"details":      err.Error(),
+			"instance_id":  owner.InstanceID,
+			"instance_url": owner.URL,
+		})
+	}
+	return true, nil
+}
+
+// IsForwarded reports whether another instance of the cluster forwarded the
+// request. Requests with missing or invalid signatures count as client requests.
+func (cm *ClusterMiddleware) IsForwarded(c *fiber.Ctx) bool {
+	if forwarded, ok := c.Locals("forwarded").(bool); ok {
+		return forwarded
+	}
+
+	forwarded := false
+	instanceID := c.Get(HeaderForwardedByInstance)
+	if cm.clusterService != nil && instanceID != "" {
+		timestamp, err := strconv.ParseInt(c.Get(HeaderForwardTimestamp), 10, 64)
+		age := time.Since(time.Unix(timestamp, 0))
+		if err == nil && age < forwardMaxAge && age > -forwardMaxAge {
+			expected := cm.forwardSignature(c, instanceID, timestamp)
+			forwarded = hmac.Equal([]byte(c.Get(HeaderForwardSignature)), []byte(expected))
+		}
+	}
+	c.Locals("forwarded", forwarded)
+	return forwarded
+}
+
+// signForward marks the request as forwarded by this instance
+func (cm *ClusterMiddleware) signForward(c *fiber.Ctx) {
+	instanceID := cm.clusterService.InstanceID()
+	timestamp := time.Now().Unix()
+	header := &c.Request().Header
+	header.Set(HeaderForwardedByInstance, instanceID)
+	header.Set(HeaderForwardedClientIP, GetClientIP(c))
+	header.Set(HeaderForwardTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderForwardSignature, cm.forwardSignature(c, instanceID, timestamp))
+}
+
+// forwardSignature covers the instance, client IP, time, method, URL and
+// body, so a signature can't be reused for another request
+func (cm *ClusterMiddleware) forwardSignature(c *fiber.Ctx, instanceID string, timestamp int64) string {
+	body := sha256.Sum256(c.Body())
+	mac := hmac.New(sha256.New, cm.secret)
+	mac.Write([]byte(strings.Join([]string{
+		instanceID,
+		c.Get(HeaderForwardedClientIP),
+		strconv.FormatInt(timestamp, 10),
+		c.Method(),
+		c.OriginalURL(),
+		hex.EncodeToString(body[:]),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Holders returns the instance holding each session, nil when clustering is disabled
+func (cm *ClusterMiddleware) Holders(sessionIDs []string) map[string]string {
+	if cm.clusterService == nil {
+		return nil
+	}
+	holders, err := cm.clusterService.Holders(sessionIDs)
+	if err != nil {
+		return nil
+	}
+	return holders
+}
+
+// Clustered reports whether sessions are spread across instances
+func (cm *ClusterMiddleware) Clustered() bool {
+	return cm.clusterService != nil
+}
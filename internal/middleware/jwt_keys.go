@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a public key in JSON Web Key format, served at /.well-known/jwks.json
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// jwtKey is a key tokens are verified with, identified by the kid header.
+// Only the current key has a signing key.
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// jwtKeySet holds the key new tokens are signed with and the previous keys
+// tokens are still accepted from
+type jwtKeySet struct {
+	current *jwtKey
+	keys    map[string]*jwtKey
+	methods []string
+}
+
+// loadJWTKeys builds the key set from the JWT settings. Each key is bound to
+// its own algorithm, so a token can't pick how it is verified.
+func loadJWTKeys(cfg config.JWTConfig) (*jwtKeySet, error) {
+	ks := &jwtKeySet{keys: make(map[string]*jwtKey)}
+
+	if cfg.Algorithm == config.JWTAlgorithmHS256 {
+		ks.add(hmacKey(cfg.Secret))
+	} else {
+		key, err := loadPEMKey(cfg.PrivateKeyFile, cfg.Algorithm, true)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(key)
+	}
+
+	for _, secret := range strings.Split(cfg.PreviousSecrets, ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			ks.add(hmacKey(secret))
+		}
+	}
+	for _, file := range strings.Split(cfg.PreviousKeyFiles, ",") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		key, err := loadPEMKey(file, "", false)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(key)
+	}
+	return ks, nil
+}
+
+func (ks *jwtKeySet) add(key *jwtKey) {
+	if ks.current == nil {
+		ks.current = key
+	} else {
+		key.sign = nil
+	}
+	if _, ok := ks.keys[key.id]; ok {
+		return
+	}
+	ks.keys[key.id] = key
+	for _, method := range ks.methods {
+		if method == key.method.Alg() {
+			return
+		}
+	}
+	ks.methods = append(ks.methods, key.method.Alg())
+}
+
+// keyFunc picks the verification key by kid and rejects tokens whose
+// algorithm isn't the one of that key
+func (ks *jwtKeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("signing key %q doesn't use %s", kid, token.Method.Alg())
+	}
+	return key.verify, nil
+}
+
+// publicKeys returns the keys tokens can be verified with. HMAC secrets are
+// never published.
+func (ks *jwtKeySet) publicKeys() []JWK {
+	jwks := []JWK{}
+	for _, key := range ks.keys {
+		jwk := JWK{Use: "sig", Alg: key.method.Alg(), Kid: key.id}
+		switch public := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	// Current key first, the rest in a stable order
+	sort.Slice(jwks, func(i, j int) bool {
+		if (jwks[i].Kid == ks.current.id) != (jwks[j].Kid == ks.current.id) {
+			return jwks[i].Kid == ks.current.id
+		}
+		return jwks[i].Kid < jwks[j].Kid
+	})
+	return jwks
+}
+
+func hmacKey(secret string) *jwtKey {
+	// Keys are referred to by a fingerprint so the secret itself never shows up
+	sum := sha256.Sum256([]byte(secret))
+	return &jwtKey{
+		id:     "hs-" + hex.EncodeToString(sum[:8]),
+		method: jwt.SigningMethodHS256,
+		sign:   []byte(secret),
+		verify: []byte(secret),
+	}
+}
+
+// loadPEMKey reads an RSA or Ed25519 key. The current key must be a private
+// key of the configured algorithm, previous keys can be public keys.
+func loadPEMKey(file, algorithm string, private bool) (*jwtKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key file %s isn't PEM encoded", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT key file %s holds an unsupported %s", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key file %s: %w", file, err)
+	}
+
+	key := &jwtKey{}
+	var public crypto.PublicKey
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.sign, public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.sign, public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("JWT key file %s must hold an RSA or Ed25519 key", file)
+	}
+	if rsaKey, ok := public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA key in %s must be at least 2048 bits", file)
+	}
+	if private && key.sign == nil {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE must hold a private key")
+	}
+	if algorithm != "" && key.method.Alg() != algorithm {
+		return nil, fmt.Errorf("JWT key file %s holds an %s key, not %s", file, key.method.Alg(), algorithm)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key of %s: %w", file, err)
+	}
+	sum := sha256.Sum256(der)
+	key.id = hex.EncodeToString(sum[:8])
+	key.verify = public
+	return key, nil
+}
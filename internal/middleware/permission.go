@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type PermissionMiddleware struct {
+	userRepo repository.UserRepository
+}
+
+func NewPermissionMiddleware(userRepo repository.UserRepository) *PermissionMiddleware {
+	return &PermissionMiddleware{
+		userRepo: userRepo,
+	}
+}
+
+// Require only lets users whose role grants the permission through. The role
+// is read from the database rather than the token, so changes apply right
+// away. Must run after Auth.
+func (pm *PermissionMiddleware) Require(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := pm.currentUser(c)
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "User no longer exists",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to load user",
+				"details": err.Error(),
+			})
+		}
+
+		if !domain.RoleHasPermission(user.Role, permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":      "Your role doesn't allow this",
+				"role":       user.Role,
+				"permission": permission,
+			})
+		}
+		if key := GetAPIKey(c); key != nil && !key.HasScope(permission) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":      "The API key isn't scoped for this",
+				"permission": permission,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// currentUser loads the authenticated user once per request
+func (pm *PermissionMiddleware) currentUser(c *fiber.Ctx) (*domain.User, error) {
+	if user, ok := c.Locals("user").(*domain.User); ok {
+		return user, nil
+	}
+
+	user, err := pm.userRepo.FindByID(GetUserID(c))
+	if err != nil {
+		return nil, err
+	}
+	setUser(c, user)
+	return user, nil
+}
+
+func setUser(c *fiber.Ctx, user *domain.User) {
+	c.Locals("user", user)
+	c.Locals("role", user.Role)
+	c.Locals("tenant_id", user.TenantID())
+}
+
+// RejectSessionScopedKeys refuses API keys limited to some sessions on
+// routes that create sessions or span all of them
+func RejectSessionScopedKeys(c *fiber.Ctx) error {
+	if key := GetAPIKey(c); key != nil && len(key.SessionIDs) > 0 {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This API key is limited to specific sessions",
+		})
+	}
+	return c.Next()
+}
+
+// RejectAPIKeys refuses requests made with an API key, for routes that need a
+// user who logged in
+func RejectAPIKeys(c *fiber.Ctx) error {
+	if GetAPIKey(c) != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Log in to use this endpoint, API keys aren't accepted",
+		})
+	}
+	return c.Next()
+}
+
+// GetRole extracts the authenticated user's role from context
+func GetRole(c *fiber.Ctx) string {
+	role, ok := c.Locals("role").(string)
+	if !ok {
+		return ""
+	}
+	return role
+}
+
+// IsAdmin reports whether the authenticated user can access every session
+func IsAdmin(c *fiber.Ctx) bool {
+	return GetRole(c) == domain.RoleAdmin
+}
+
+// GetTenantID extracts the account whose sessions the user works on. It is
+// the user's own ID unless they are an agent or read-only user.
+func GetTenantID(c *fiber.Ctx) uint {
+	tenantID, ok := c.Locals("tenant_id").(uint)
+	if !ok {
+		return GetUserID(c)
+	}
+	return tenantID
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type QuotaMiddleware struct {
+	quotaService          *service.QuotaService
+	authRequestsPerMinute int
+}
+
+func NewQuotaMiddleware(quotaService *service.QuotaService, authRequestsPerMinute int) *QuotaMiddleware {
+	return &QuotaMiddleware{
+		quotaService:          quotaService,
+		authRequestsPerMinute: authRequestsPerMinute,
+	}
+}
+
+// RateLimit counts the request against the requests per minute of the
+// account's plan. Must run after a permission check, which loads the account.
+func (qm *QuotaMiddleware) RateLimit(c *fiber.Ctx) error {
+	status, err := qm.quotaService.AllowRequest(GetTenantID(c))
+	return qm.next(c, status, err)
+}
+
+// LimitByIP rate limits unauthenticated endpoints like login per client IP
+func (qm *QuotaMiddleware) LimitByIP(c *fiber.Ctx) error {
+	status, err := qm.quotaService.Allow("ip:"+GetClientIP(c), qm.authRequestsPerMinute)
+	return qm.next(c, status, err)
+}
+
+func (qm *QuotaMiddleware) next(c *fiber.Ctx, status service.RateLimitStatus, err error) error {
+	var quotaErr *service.QuotaError
+	if errors.As(err, &quotaErr) {
+		return QuotaExceeded(c, quotaErr)
+	}
+	if status.Max > 0 {
+		c.Set("X-RateLimit-Limit", strconv.Itoa(status.Max))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+	}
+	return c.Next()
+}
+
+// QuotaExceeded answers 429 with the limit that was reached. Limits that
+// reset carry X-RateLimit-Reset and Retry-After.
+func QuotaExceeded(c *fiber.Ctx, err *service.QuotaError) error {
+	c.Set("X-RateLimit-Limit", strconv.Itoa(err.Max))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(err.Remaining))
+	response := fiber.Map{
+		"error": err.Error(),
+		"limit": err.Limit,
+		"max":   err.Max,
+	}
+	if !err.ResetAt.IsZero() {
+		retryAfter := int(time.Until(err.ResetAt).Seconds()) + 1
+		c.Set("X-RateLimit-Reset", strconv.FormatInt(err.ResetAt.Unix(), 10))
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		response["resetAt"] = err.ResetAt
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(response)
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionMiddleware struct {
+	sessionService *service.SessionService
+}
+
+func NewSessionMiddleware(sessionService *service.SessionService) *SessionMiddleware {
+	return &SessionMiddleware{
+		sessionService: sessionService,
+	}
+}
+
+// RequireSession resolves the session a request targets (userId in the path,
+// query or JSON body, otherwise the caller's default session), checks that it
+// belongs to the caller's account and adds it to the context. Admins can use
+// any session, API keys only the sessions they are limited to. Must run after
+// Auth and a permission check.
+func (sm *SessionMiddleware) RequireSession(c *fiber.Ctx) error {
+	session, err := sm.sessionService.Resolve(GetTenantID(c), requestedSessionID(c), IsAdmin(c))
+	if err != nil {
+		switch err {
+		case service.ErrSessionNotFound:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Session not found. Initialize a session first.",
+			})
+		case service.ErrSessionForbidden:
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You don't have access to this session",
+			})
+		case service.ErrSessionAmbiguous:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to resolve session",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	if key := GetAPIKey(c); key != nil && !key.AllowsSession(session.ID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "The API key can't be used on this session",
+		})
+	}
+
+	c.Locals("session_id", session.ID)
+	return c.Next()
+}
+
+// requestedSessionID finds the session ID a request names. Sessions used to be
+// called users, so userId is accepted alongside sessionId.
+func requestedSessionID(c *fiber.Ctx) string {
+	for _, id := range []string{c.Params("userId"), c.Query("sessionId"), c.Query("userId")} {
+		if id != "" {
+			return id
+		}
+	}
+
+	if len(c.Body()) > 0 && strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		var body struct {
+			SessionID string `json:"sessionId"`
+			UserID    string `json:"userId"`
+		}
+		if err := json.Unmarshal(c.Body(), &body); err == nil {
+			if body.SessionID != "" {
+				return body.SessionID
+			}
+			return body.UserID
+		}
+	}
+	return ""
+}
+
+// GetSessionID extracts the authorized session ID from context
+func GetSessionID(c *fiber.Ctx) string {
+	sessionID, ok := c.Locals("session_id").(string)
+	if !ok {
+		return ""
+	}
+	return sessionID
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByPrefix(prefix string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByUser(userID uint) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke revokes one of the user's keys. It returns false when the user has
+// no such key or it was already revoked.
+func (r *apiKeyRepository) Revoke(userID, id uint) (bool, error) {
+	result := r.db.Model(&domain.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uint, usedAt time.Time) error {
+	return r.db.Model(&domain.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
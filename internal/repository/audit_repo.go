@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(entry *domain.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// Find returns matching entries, newest first
+func (r *auditRepository) Find(filter domain.AuditFilter) ([]domain.AuditLog, error) {
+	query := r.db.Model(&domain.AuditLog{})
+	if !filter.AllTenants {
+		query = query.Where("tenant_id = ?", filter.TenantID)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if strings.HasSuffix(filter.Action, ".") {
+		query = query.Where("action LIKE ?", filter.Action+"%")
+	} else if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.ChatbotID != "" {
+		query = query.Where("chatbot_id = ?", filter.ChatbotID)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var entries []domain.AuditLog
+	if err := query.Order("id DESC").Limit(filter.Limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *auditRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&domain.AuditLog{})
+	return result.RowsAffected, result.Error
+}
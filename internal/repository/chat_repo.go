@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type chatRepository struct {
+	db *gorm.DB
+}
+
+func NewChatRepository(db *gorm.DB) ChatRepository {
+	return &chatRepository{db: db}
+}
+
+var chatSortColumns = map[string]string{
+	"last_activity": "last_activity",
+	"unread_count":  "unread_count",
+	"name":          "name",
+}
+
+func (r *chatRepository) FindByJID(userID, jid string) (*domain.Chat, error) {
+	var chat domain.Chat
+	if err := r.db.Where("user_id = ? AND jid = ?", userID, jid).First(&chat).Error; err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func (r *chatRepository) List(userID string, filter ChatFilter) ([]domain.Chat, int64, error) {
+	query := r.db.Model(&domain.Chat{}).Where("user_id = ?", userID)
+
+	if filter.Search != "" {
+		like := containsPattern(filter.Search)
+		query = query.Where(`name LIKE ? ESCAPE '\\' OR jid LIKE ? ESCAPE '\\'`, like, like)
+	}
+	if filter.Archived != nil {
+		query = query.Where("archived = ?", *filter.Archived)
+	}
+	if filter.UnreadOnly {
+		query = query.Where("unread_count > 0 OR marked_unread = ?", true)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := chatSortColumns[filter.SortBy]
+	if !ok {
+		column = "last_activity"
+	}
+
+	// Pinned chats stay on top like they do in WhatsApp
+	var chats []domain.Chat
+	err := query.
+		Order("pinned DESC").
+		Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: filter.Desc}).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&chats).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return chats, total, nil
+}
+
+// RecordMessage creates the chat if needed and moves its last message forward
+// when the new one is more recent. Assignments run in order on MySQL, so
+// last_activity is compared before it is overwritten.
+func (r *chatRepository) RecordMessage(chat *domain.Chat, unreadDelta int) error {
+	if chat.ID == "" {
+		chat.ID = utils.GenerateID("chat_")
+	}
+	chat.UnreadCount = unreadDelta
+
+	newer := func(value interface{}, column string) clause.Assignment {
+		return clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr("CASE WHEN last_activity IS NULL OR ? >= last_activity THEN ? ELSE "+column+" END", chat.LastActivity, value),
+		}
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "jid"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "name"}, Value: gorm.Expr("CASE WHEN ? <> '' THEN ? ELSE name END", chat.Name, chat.Name)},
+			{Column: clause.Column{Name: "unread_count"}, Value: gorm.Expr("unread_count + ?", unreadDelta)},
+			newer(chat.LastMessage, "last_message"),
+			newer(chat.LastMessageID, "last_message_id"),
+			newer(chat.LastMessageFromMe, "last_message_from_me"),
+			newer(chat.LastActivity, "last_activity"),
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+		},
+	}).Create(chat).Error
+}
+
+// UpsertFromHistory stores chat metadata from a history sync. Unlike live
+// messages, history carries absolute unread counts and chat flags. The last
+// message only replaces one that isn't older, like in RecordMessage.
+func (r *chatRepository) UpsertFromHistory(chat *domain.Chat) error {
+	if chat.ID == "" {
+		chat.ID = utils.GenerateID("chat_")
+	}
+
+	updates := clause.Set{
+		{Column: clause.Column{Name: "name"}, Value: gorm.Expr("CASE WHEN ? <> '' THEN ? ELSE name END", chat.Name, chat.Name)},
+		{Column: clause.Column{Name: "unread_count"}, Value: chat.UnreadCount},
+		{Column: clause.Column{Name: "marked_unread"}, Value: chat.MarkedUnread},
+		{Column: clause.Column{Name: "archived"}, Value: chat.Archived},
+		{Column: clause.Column{Name: "pinned"}, Value: chat.Pinned},
+		{Column: clause.Column{Name: "muted_until"}, Value: chat.MutedUntil},
+	}
+	if chat.LastMessageID != "" {
+		newer := func(value interface{}, column string) clause.Assignment {
+			return clause.Assignment{
+				Column: clause.Column{Name: column},
+				Value:  gorm.Expr("CASE WHEN last_activity IS NULL OR ? >= last_activity THEN ? ELSE "+column+" END", chat.LastActivity, value),
+			}
+		}
+		updates = append(updates,
+			newer(chat.LastMessage, "last_message"),
+			newer(chat.LastMessageID, "last_message_id"),
+			newer(chat.LastMessageFromMe, "last_message_from_me"),
+		)
+	}
+	updates = append(updates,
+		clause.Assignment{Column: clause.Column{Name: "last_activity"}, Value: gorm.Expr("CASE WHEN last_activity IS NULL OR ? > last_activity THEN ? ELSE last_activity END", chat.LastActivity, chat.LastActivity)},
+		clause.Assignment{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+	)
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "jid"}},
+		DoUpdates: updates,
+	}).Create(chat).Error
+}
+
+// MarkRead lowers the unread counter by count, or clears it when count is negative
+func (r *chatRepository) MarkRead(userID, jid string, count int) error {
+	updates := map[string]interface{}{
+		"marked_unread": false,
+		"unread_count":  0,
+	}
+	if count >= 0 {
+		updates["unread_count"] = gorm.Expr("CASE WHEN unread_count > ? THEN unread_count - ? ELSE 0 END", count, count)
+	}
+	return r.db.Model(&domain.Chat{}).Where("user_id = ? AND jid = ?", userID, jid).Updates(updates).Error
+}
+
+// UpdateState sets chat flags such as archived, pinned or muted_until,
+// creating the chat if it hasn't been seen yet
+func (r *chatRepository) UpdateState(userID, jid string, updates map[string]interface{}) error {
+	result := r.db.Model(&domain.Chat{}).Where("user_id = ? AND jid = ?", userID, jid).Updates(updates)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+
+	chat := &domain.Chat{
+		ID:      utils.GenerateID("chat_"),
+		UserID:  userID,
+		JID:     jid,
+		IsGroup: strings.HasSuffix(jid, "@g.us"),
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(chat).Error; err != nil {
+		return err
+	}
+	return r.db.Model(&domain.Chat{}).Where("user_id = ? AND jid = ?", userID, jid).Updates(updates).Error
+}
+
+// likeEscaper escapes LIKE wildcards, used with ESCAPE '\\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// containsPattern is a LIKE pattern matching text anywhere, wildcards in text
+// are matched literally
+func containsPattern(text string) string {
+	return "%" + likeEscaper.Replace(text) + "%"
+}
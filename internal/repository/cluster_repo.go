@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+type clusterRepository struct {
+	db *gorm.DB
+}
+
+func NewClusterRepository(db *gorm.DB) ClusterRepository {
+	return &clusterRepository{db: db}
+}
+
+// Lease and heartbeat times come from the database clock, so instances with
+// skewed clocks still agree on which leases have expired
+
+// Heartbeat registers the instance or refreshes its heartbeat
+func (r *clusterRepository) Heartbeat(instance *domain.Instance) error {
+	return r.db.Exec(`INSERT INTO instances (id, url, started_at, heartbeat_at) VALUES (?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE url = VALUES(url), heartbeat_at = NOW()`,
+		instance.ID, instance.URL, instance.StartedAt).Error
+}
+
+// FindLiveInstances returns the instances with a heartbeat within ttl
+func (r *clusterRepository) FindLiveInstances(ttl time.Duration) ([]domain.Instance, error) {
+	var instances []domain.Instance
+	if err := r.db.Where("heartbeat_at >= NOW() - INTERVAL ? SECOND", seconds(ttl)).Order("id ASC").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func (r *clusterRepository) RemoveInstance(id string) error {
+	return r.db.Where("id = ?", id).Delete(&domain.Instance{}).Error
+}
+
+// FindActiveLease returns a session's lease unless it expired
+func (r *clusterRepository) FindActiveLease(sessionID string) (*domain.SessionLease, error) {
+	var lease domain.SessionLease
+	if err := r.db.Where("session_id = ? AND expires_at > NOW()", sessionID).First(&lease).Error; err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// FindActiveLeases returns the unexpired leases of the given sessions
+func (r *clusterRepository) FindActiveLeases(sessionIDs []string) ([]domain.SessionLease, error) {
+	var leases []domain.SessionLease
+	if len(sessionIDs) == 0 {
+		return leases, nil
+	}
+	if err := r.db.Where("session_id IN ? AND expires_at > NOW()", sessionIDs).Find(&leases).Error; err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+func (r *clusterRepository) FindLeasesByInstance(instanceID string) ([]domain.SessionLease, error) {
+	var leases []domain.SessionLease
+	if err := r.db.Where("instance_id = ? AND expires_at > NOW()", instanceID).Find(&leases).Error; err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// AcquireLease takes a session's lease for ttl if it is free, expired or
+// already ours. The conditional update makes concurrent attempts safe: only
+// one instance gets a row back.
+func (r *clusterRepository) AcquireLease(sessionID, instanceID string, ttl time.Duration) (bool, error) {
+	result := r.db.Exec(`INSERT IGNORE INTO session_leases (session_id, instance_id, acquired_at, expires_at)
+		VALUES (?, ?, NOW(), NOW() + INTERVAL ? SECOND)`, sessionID, instanceID, seconds(ttl))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	result = r.db.Model(&domain.SessionLease{}).
+		Where("session_id = ? AND (expires_at <= NOW() OR instance_id = ?)", sessionID, instanceID).
+		Updates(map[string]interface{}{
+			"instance_id": instanceID,
+			"acquired_at": gorm.Expr("NOW()"),
+			"expires_at":  gorm.Expr("NOW() + INTERVAL ? SECOND", seconds(ttl)),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RenewLeases extends every lease the instance still holds by ttl
+func (r *clusterRepository) RenewLeases(instanceID string, ttl time.Duration) error {
+	return r.db.Model(&domain.SessionLease{}).Where("instance_id = ?", instanceID).
+		Update("expires_at", gorm.Expr("NOW() + INTERVAL ? SECOND", seconds(ttl))).Error
+}
+
+// ReleaseIdleLease gives a lease back if it was acquired more than ttl ago
+func (r *clusterRepository) ReleaseIdleLease(sessionID, instanceID string, ttl time.Duration) (bool, error) {
+	result := r.db.Where("session_id = ? AND instance_id = ? AND acquired_at < NOW() - INTERVAL ? SECOND", sessionID, instanceID, seconds(ttl)).
+		Delete(&domain.SessionLease{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *clusterRepository) ReleaseLease(sessionID, instanceID string) error {
+	return r.db.Where("session_id = ? AND instance_id = ?", sessionID, instanceID).Delete(&domain.SessionLease{}).Error
+}
+
+func (r *clusterRepository) ReleaseLeases(instanceID string) error {
+	return r.db.Where("instance_id = ?", instanceID).Delete(&domain.SessionLease{}).Error
+}
+
+// seconds rounds a duration up to whole seconds for SQL intervals
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type contactRepository struct {
+	db *gorm.DB
+}
+
+func NewContactRepository(db *gorm.DB) ContactRepository {
+	return &contactRepository{db: db}
+}
+
+func (r *contactRepository) FindByJID(userID, jid string) (*domain.Contact, error) {
+	var contact domain.Contact
+	if err := r.db.Where("user_id = ? AND jid = ?", userID, jid).First(&contact).Error; err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (r *contactRepository) List(userID string, filter ContactFilter) ([]domain.Contact, int64, error) {
+	query := r.db.Model(&domain.Contact{}).Where("user_id = ?", userID)
+
+	if filter.Search != "" {
+		like := containsPattern(filter.Search)
+		query = query.Where(`full_name LIKE ? ESCAPE '\\' OR first_name LIKE ? ESCAPE '\\' OR push_name LIKE ? ESCAPE '\\' OR business_name LIKE ? ESCAPE '\\' OR jid LIKE ? ESCAPE '\\'`,
+			like, like, like, like, like)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Sort by the same name DisplayName picks, unnamed contacts last
+	var contacts []domain.Contact
+	err := query.
+		Order("CASE WHEN full_name <> '' THEN full_name WHEN first_name <> '' THEN first_name " +
+			"WHEN push_name <> '' THEN push_name WHEN business_name <> '' THEN business_name ELSE NULL END IS NULL").
+		Order("CASE WHEN full_name <> '' THEN full_name WHEN first_name <> '' THEN first_name " +
+			"WHEN push_name <> '' THEN push_name ELSE business_name END").
+		Order("jid").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&contacts).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return contacts, total, nil
+}
+
+// Upsert creates contacts or, for ones that already exist, only overwrites the
+// given columns. Each source (address book, push names, profile lookups) knows
+// a different part of a contact, so it must not clear what the others stored.
+func (r *contactRepository) Upsert(contacts []domain.Contact, columns ...string) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	for i := range contacts {
+		if contacts[i].ID == "" {
+			contacts[i].ID = utils.GenerateID("contact_")
+		}
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "jid"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "updated_at")),
+	}).CreateInBatches(contacts, 500).Error
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+import (
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+)
 
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
@@ -33,4 +37,125 @@ type ConversationStateRepository interface {
 	FindByUserAndChat(userID, chatID string) (*domain.ConversationState, error)
 	Create(state *domain.ConversationState) error
 	Update(state *domain.ConversationState) error
-}
\ No newline at end of file
+}
+
+// PollRepository defines the interface for poll and poll vote data operations
+type PollRepository interface {
+	FindByID(id string) (*domain.Poll, error)
+	FindBySessionAndID(userID, id string) (*domain.Poll, error)
+	Create(poll *domain.Poll) error
+	FindVotes(pollID string) ([]domain.PollVote, error)
+	UpsertVote(vote *domain.PollVote) error
+}
+
+// ChatFilter narrows and orders a chat listing
+type ChatFilter struct {
+	Search     string // Matches chat name or JID
+	Archived   *bool
+	UnreadOnly bool
+	SortBy     string // last_activity, unread_count or name
+	Desc       bool
+	Limit      int
+	Offset     int
+}
+
+// ChatRepository defines the interface for the per-session chat index
+type ChatRepository interface {
+	FindByJID(userID, jid string) (*domain.Chat, error)
+	List(userID string, filter ChatFilter) ([]domain.Chat, int64, error)
+	RecordMessage(chat *domain.Chat, unreadDelta int) error
+	UpsertFromHistory(chat *domain.Chat) error
+	MarkRead(userID, jid string, count int) error
+	UpdateState(userID, jid string, updates map[string]interface{}) error
+}
+
+// MessageRepository defines the interface for stored message data operations
+type MessageRepository interface {
+	Save(message *domain.Message) (bool, error)
+	SaveBatch(messages []domain.Message) (int64, error)
+}
+
+// ContactFilter narrows a contact listing
+type ContactFilter struct {
+	Search string // Matches any name, JID or about text
+	Limit  int
+	Offset int
+}
+
+// ContactRepository defines the interface for the per-session contact directory
+type ContactRepository interface {
+	FindByJID(userID, jid string) (*domain.Contact, error)
+	List(userID string, filter ContactFilter) ([]domain.Contact, int64, error)
+	Upsert(contacts []domain.Contact, columns ...string) error
+}
+
+// SessionRepository defines the interface for session ownership data operations
+type SessionRepository interface {
+	FindByID(id string) (*domain.Session, error)
+	FindByOwnerAndName(ownerID uint, name string) (*domain.Session, error)
+	FindByOwner(ownerID uint) ([]domain.Session, error)
+	FindWithDevice() ([]domain.Session, error)
+	FindByDeviceJID(deviceJID string) ([]domain.Session, error)
+	Create(session *domain.Session) error
+	CreateChecked(session *domain.Session, allow func(activeSessions int) error) error
+	AssignUnowned(ownerID uint) (int64, error)
+	Delete(id string) error
+	RecordStatus(id string, updates map[string]interface{}, change *domain.SessionStatusChange) error
+	FindStatusHistory(id string, limit int) ([]domain.SessionStatusChange, error)
+}
+
+// NotificationRepository defines the interface for user notification data operations
+type NotificationRepository interface {
+	Create(notification *domain.Notification) error
+	FindByUser(userID uint, unreadOnly bool, limit int) ([]domain.Notification, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(userID, id uint) error
+}
+
+// ClusterRepository defines the interface for instance heartbeats and session leases
+type ClusterRepository interface {
+	Heartbeat(instance *domain.Instance) error
+	FindLiveInstances(ttl time.Duration) ([]domain.Instance, error)
+	RemoveInstance(id string) error
+	FindActiveLease(sessionID string) (*domain.SessionLease, error)
+	FindActiveLeases(sessionIDs []string) ([]domain.SessionLease, error)
+	FindLeasesByInstance(instanceID string) ([]domain.SessionLease, error)
+	AcquireLease(sessionID, instanceID string, ttl time.Duration) (bool, error)
+	RenewLeases(instanceID string, ttl time.Duration) error
+	ReleaseIdleLease(sessionID, instanceID string, ttl time.Duration) (bool, error)
+	ReleaseLease(sessionID, instanceID string) error
+	ReleaseLeases(instanceID string) error
+}
+
+// RefreshTokenRepository defines the interface for refresh token data operations
+type RefreshTokenRepository interface {
+	Create(token *domain.RefreshToken) error
+	FindByHash(hash string) (*domain.RefreshToken, error)
+	Rotate(id uint, next *domain.RefreshToken) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID uint) error
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	Create(key *domain.APIKey) error
+	FindByPrefix(prefix string) (*domain.APIKey, error)
+	FindByUser(userID uint) ([]domain.APIKey, error)
+	Revoke(userID, id uint) (bool, error)
+	TouchLastUsed(id uint, usedAt time.Time) error
+}
+
+// UsageRepository defines the interface for daily usage counters
+type UsageRepository interface {
+	Increment(userID uint, metric, day string, n, limit int) (bool, error)
+	Get(userID uint, metric, day string) (int, error)
+	DeleteBefore(day string) (int64, error)
+}
+
+// AuditRepository defines the interface for audit log data operations
+type AuditRepository interface {
+	Create(entry *domain.AuditLog) error
+	Find(filter domain.AuditFilter) ([]domain.AuditLog, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
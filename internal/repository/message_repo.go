@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type messageRepository struct {
+	db *gorm.DB
+}
+
+func NewMessageRepository(db *gorm.DB) MessageRepository {
+	return &messageRepository{db: db}
+}
+
+// Save stores a message unless the same message of the same chat is already
+// stored, and reports whether it was new
+func (r *messageRepository) Save(message *domain.Message) (bool, error) {
+	if message.ID == "" {
+		message.ID = utils.GenerateID("msg_")
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(message)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SaveBatch stores messages in batches, skipping ones already stored (for example
+// live messages that also show up in a history sync). It returns how many were new.
+func (r *messageRepository) SaveBatch(messages []domain.Message) (int64, error) {
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	for i := range messages {
+		if messages[i].ID == "" {
+			messages[i].ID = utils.GenerateID("msg_")
+		}
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(messages, 500)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+)
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) Create(notification *domain.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) FindByUser(userID uint, unreadOnly bool, limit int) ([]domain.Notification, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []domain.Notification
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read, or all of them when id is 0
+func (r *notificationRepository) MarkRead(userID, id uint) error {
+	query := r.db.Model(&domain.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	}
+	return query.Update("read_at", time.Now()).Error
+}
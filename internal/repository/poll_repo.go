@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) PollRepository {
+	return &pollRepository{db: db}
+}
+
+func (r *pollRepository) FindByID(id string) (*domain.Poll, error) {
+	var poll domain.Poll
+	if err := r.db.Where("id = ?", id).First(&poll).Error; err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// FindBySessionAndID returns a poll only if the session sent it
+func (r *pollRepository) FindBySessionAndID(userID, id string) (*domain.Poll, error) {
+	var poll domain.Poll
+	if err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&poll).Error; err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+func (r *pollRepository) Create(poll *domain.Poll) error {
+	return r.db.Create(poll).Error
+}
+
+func (r *pollRepository) FindVotes(pollID string) ([]domain.PollVote, error) {
+	var votes []domain.PollVote
+	if err := r.db.Where("poll_id = ?", pollID).Order("voted_at ASC").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// UpsertVote stores a vote, replacing any earlier vote by the same voter on the same poll.
+// WhatsApp always sends the voter's full current selection, so the latest vote wins.
+func (r *pollRepository) UpsertVote(vote *domain.PollVote) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "poll_id"}, {Name: "voter_jid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"selected_options", "voted_at", "updated_at"}),
+	}).Create(vote).Error
+}
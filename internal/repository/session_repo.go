@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) FindByID(id string) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByOwnerAndName(ownerID uint, name string) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.Where("owner_id = ? AND name = ?", ownerID, name).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindByOwner(ownerID uint) ([]domain.Session, error) {
+	var sessions []domain.Session
+	if err := r.db.Where("owner_id = ?", ownerID).Order("created_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// FindWithDevice returns sessions that have a linked device to restore
+func (r *sessionRepository) FindWithDevice() ([]domain.Session, error) {
+	var sessions []domain.Session
+	if err := r.db.Where("device_jid <> '' AND status <> ?", domain.SessionStatusExported).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// FindByDeviceJID returns the sessions linked to a WhatsApp account
+func (r *sessionRepository) FindByDeviceJID(deviceJID string) ([]domain.Session, error) {
+	var sessions []domain.Session
+	if err := r.db.Where("device_jid = ?", deviceJID).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Create(session *domain.Session) error {
+	return r.db.Create(session).Error
+}
+
+// CreateChecked creates a session if allow accepts the owner's number of
+// sessions. The owner's row stays locked until the session is created, so
+// concurrent creates are counted one after the other.
+func (r *sessionRepository) CreateChecked(session *domain.Session, allow func(activeSessions int) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var owner domain.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", session.OwnerID).First(&owner).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&domain.Session{}).
+			Where("owner_id = ? AND status <> ?", session.OwnerID, domain.SessionStatusExported).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if err := allow(int(count)); err != nil {
+			return err
+		}
+		return tx.Create(session).Error
+	})
+}
+
+// AssignUnowned gives every session without an owner to ownerID
+func (r *sessionRepository) AssignUnowned(ownerID uint) (int64, error) {
+	result := r.db.Model(&domain.Session{}).Where("owner_id = 0").Update("owner_id", ownerID)
+	return result.RowsAffected, result.Error
+}
+
+func (r *sessionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&domain.Session{}).Error
+}
+
+// RecordStatus updates a session and appends to its status history in one
+// transaction, so the history always matches the session's current state
+func (r *sessionRepository) RecordStatus(id string, updates map[string]interface{}, change *domain.SessionStatusChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Session{}).Where("id = ?", id).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *sessionRepository) FindStatusHistory(id string, limit int) ([]domain.SessionStatusChange, error) {
+	var changes []domain.SessionStatusChange
+	err := r.db.Where("session_id = ?", id).Order("created_at DESC, id DESC").Limit(limit).Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+// Increment adds n to a counter unless that would take it over limit (0 means
+// no limit). It returns false when the limit would be exceeded.
+func (r *usageRepository) Increment(userID uint, metric, day string, n, limit int) (bool, error) {
+	counter := domain.UsageCounter{UserID: userID, Metric: metric, Day: day}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+		return false, err
+	}
+
+	query := r.db.Model(&domain.UsageCounter{}).Where("user_id = ? AND metric = ? AND day = ?", userID, metric, day)
+	if limit > 0 {
+		query = query.Where("count + ? <= ?", n, limit)
+	}
+	result := query.Update("count", gorm.Expr("count + ?", n))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *usageRepository) Get(userID uint, metric, day string) (int, error) {
+	var counter domain.UsageCounter
+	err := r.db.Where("user_id = ? AND metric = ? AND day = ?", userID, metric, day).First(&counter).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	return counter.Count, err
+}
+
+// DeleteBefore removes counters of days before the given one
+func (r *usageRepository) DeleteBefore(day string) (int64, error) {
+	result := r.db.Where("day < ?", day).Delete(&domain.UsageCounter{})
+	return result.RowsAffected, result.Error
+}
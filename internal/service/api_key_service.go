@@ -0,0 +1,201 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"gorm.io/gorm"
+)
+
+// apiKeyTouchInterval limits how often last_used_at is written for a key
+const apiKeyTouchInterval = time.Minute
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyIPNotAllowed = errors.New("API key can't be used from this IP address")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+)
+
+type APIKeyService struct {
+	apiKeyRepo     repository.APIKeyRepository
+	userRepo       repository.UserRepository
+	sessionService *SessionService
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository, sessionService *SessionService) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:     apiKeyRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+	}
+}
+
+// CreateAPIKeyRequest describes a new key. Scopes are permissions the user's
+// role grants, SessionIDs and AllowedIPs are optional limits.
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	SessionIDs []string   `json:"sessionIds"`
+	AllowedIPs []string   `json:"allowedIps"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// Create issues a key for a user. The key itself is only returned here.
+func (s *APIKeyService) Create(user *domain.User, req CreateAPIKeyRequest) (string, *domain.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, fmt.Errorf("name must be between 1 and 100 characters")
+	}
+	if len(req.Scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !domain.RoleHasPermission(user.Role, scope) {
+			return "", nil, fmt.Errorf("your role can't grant the scope %q", scope)
+		}
+	}
+	for _, sessionID := range req.SessionIDs {
+		if sessionID == "" {
+			return "", nil, fmt.Errorf("session IDs can't be empty")
+		}
+		if _, err := s.sessionService.Resolve(user.TenantID(), sessionID, user.Role == domain.RoleAdmin); err != nil {
+			return "", nil, fmt.Errorf("session %q not found", sessionID)
+		}
+	}
+	for _, entry := range req.AllowedIPs {
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return "", nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+			}
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return "", nil, fmt.Errorf("expiresAt must be in the future")
+	}
+
+	prefix, secret := make([]byte, 4), make([]byte, 32)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	key := &domain.APIKey{
+		UserID:     user.ID,
+		Name:       name,
+		Prefix:     domain.APIKeyPrefix + hex.EncodeToString(prefix),
+		Scopes:     req.Scopes,
+		SessionIDs: req.SessionIDs,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	raw := key.Prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	key.KeyHash = hashAPIKey(raw)
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return "", nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	log.Printf("🔑 Created API key %s for user %d", key.Prefix, user.ID)
+	return raw, key, nil
+}
+
+// List returns a user's keys, including revoked and expired ones
+func (s *APIKeyService) List(userID uint) ([]domain.APIKey, error) {
+	return s.apiKeyRepo.FindByUser(userID)
+}
+
+// Revoke revokes one of the user's keys right away
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	revoked, err := s.apiKeyRepo.Revoke(userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate checks a key presented from an IP address and returns it with
+// its user
+func (s *APIKeyService) Authenticate(raw, ip string) (*domain.APIKey, *domain.User, error) {
+	// Keys are "wak_<prefix>_<secret>", the secret can contain underscores too
+	if !strings.HasPrefix(raw, domain.APIKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	separator := strings.IndexByte(raw[len(domain.APIKeyPrefix):], '_')
+	if separator <= 0 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.FindByPrefix(raw[:len(domain.APIKeyPrefix)+separator])
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashAPIKey(raw))) != 1 ||
+		key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if !ipAllowed(key.AllowedIPs, ip) {
+		return nil, nil, ErrAPIKeyIPNotAllowed
+	}
+
+	user, err := s.userRepo.FindByID(key.UserID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(key.ID, now); err != nil {
+			log.Printf("Warning: failed to record use of API key %s: %v", key.Prefix, err)
+		}
+	}
+	return key, user, nil
+}
+
+func hashAPIKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// ipAllowed matches an IP against addresses and CIDR ranges, an empty list
+// allows any IP
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(parsed) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+)
+
+// AuditService keeps the audit log. Failing to write an entry never fails the
+// action itself, it is logged instead.
+type AuditService struct {
+	auditRepo repository.AuditRepository
+	retention time.Duration
+}
+
+func NewAuditService(auditRepo repository.AuditRepository, retentionDays int) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+// Record writes an entry
+func (s *AuditService) Record(entry *domain.AuditLog) {
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Warning: failed to write audit log entry %s: %v", entry.Action, err)
+	}
+}
+
+// RecordEvent writes an entry for something that didn't come from a request
+func (s *AuditService) RecordEvent(tenantID, actorID uint, action, sessionID, outcome string, details map[string]interface{}) {
+	s.Record(&domain.AuditLog{
+		TenantID:  tenantID,
+		ActorID:   actorID,
+		Action:    action,
+		SessionID: sessionID,
+		Outcome:   outcome,
+		Details:   details,
+	})
+}
+
+// Find returns audit log entries, newest first
+func (s *AuditService) Find(filter domain.AuditFilter) ([]domain.AuditLog, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	if filter.Limit > 500 {
+		filter.Limit = 500
+	}
+	entries, err := s.auditRepo.Find(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Cleanup deletes entries past the retention period, 0 keeps them forever
+func (s *AuditService) Cleanup() {
+	if s.retention <= 0 {
+		return
+	}
+	deleted, err := s.auditRepo.DeleteBefore(time.Now().Add(-s.retention))
+	if err != nil {
+		log.Printf("Warning: failed to delete old audit log entries: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Deleted %d audit log entries older than %s", deleted, s.retention)
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+var (
+	ErrRegistrationClosed  = errors.New("registration is disabled on this server")
+	ErrEmailTaken          = errors.New("an account with this email already exists")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// AccessTokenIssuer signs the short-lived tokens the API is called with
+type AccessTokenIssuer interface {
+	GenerateToken(userID uint, chatbotID string) (string, error)
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"` // Seconds until the access token expires
+}
+
+type AuthService struct {
+	userRepo         repository.UserRepository
+	tokenRepo        repository.RefreshTokenRepository
+	issuer           AccessTokenIssuer
+	auditService     *AuditService
+	accessTTL        time.Duration
+	refreshTTL       time.Duration
+	allowRegistering bool
+	dummyHash        []byte // Compared against for unknown emails so they take as long as wrong passwords
+}
+
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.RefreshTokenRepository, issuer AccessTokenIssuer, auditService *AuditService, accessTTL, refreshTTL time.Duration, allowRegistering bool) *AuthService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return &AuthService{
+		userRepo:         userRepo,
+		tokenRepo:        tokenRepo,
+		issuer:           issuer,
+		auditService:     auditService,
+		accessTTL:        accessTTL,
+		refreshTTL:       refreshTTL,
+		allowRegistering: allowRegistering,
+		dummyHash:        dummyHash,
+	}
+}
+
+// Register creates an owner account
+func (s *AuthService) Register(name, email, password string) (*domain.User, error) {
+	if !s.allowRegistering {
+		return nil, ErrRegistrationClosed
+	}
+
+	name = strings.TrimSpace(name)
+	email = normalizeEmail(email)
+	if name == "" || len(name) > 255 {
+		return nil, fmt.Errorf("name must be between 1 and 255 characters")
+	}
+	if _, err := mail.ParseAddress(email); err != nil || len(email) > 255 {
+		return nil, fmt.Errorf("invalid email address")
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+	}
+
+	if _, err := s.userRepo.FindByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &domain.User{
+		Name:     name,
+		Email:    email,
+		Password: string(hash),
+		Role:     domain.RoleOwner,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	log.Printf("👤 Registered user %d (%s)", user.ID, user.Email)
+	return user, nil
+}
+
+// Login checks a user's password and starts a new refresh token family
+func (s *AuthService) Login(email, password string) (*domain.User, *TokenPair, error) {
+	user, err := s.userRepo.FindByEmail(normalizeEmail(email))
+	if err == gorm.ErrRecordNotFound {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	refreshToken, record, err := s.newRefreshToken(user.ID, utils.GenerateID("fam_"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.tokenRepo.Create(record); err != nil {
+		return nil, nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	tokens, err := s.tokenPair(user, refreshToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// Refresh replaces a refresh token with a new one and issues an access token.
+// A token that was already replaced means it leaked, so its family is revoked.
+func (s *AuthService) Refresh(refreshToken string) (*TokenPair, error) {
+	record, err := s.tokenRepo.FindByHash(hashRefreshToken(refreshToken))
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	if record.RevokedAt != nil {
+		s.revokeReused(record)
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindByID(record.UserID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	next, nextRecord, err := s.newRefreshToken(user.ID, record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := s.tokenRepo.Rotate(record.ID, nextRecord)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// Another request used the same token first
+		s.revokeReused(record)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.tokenPair(user, next)
+}
+
+// Logout revokes one of the user's refresh tokens, or all of them. Access
+// tokens already issued stay valid until they expire.
+func (s *AuthService) Logout(userID uint, refreshToken string, all bool) error {
+	if all {
+		return s.tokenRepo.RevokeAllForUser(userID)
+	}
+
+	record, err := s.tokenRepo.FindByHash(hashRefreshToken(refreshToken))
+	if err == gorm.ErrRecordNotFound || (err == nil && record.UserID != userID) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+	return s.tokenRepo.RevokeFamily(record.FamilyID)
+}
+
+// CleanupExpiredTokens deletes refresh tokens that expired a day ago or earlier
+func (s *AuthService) CleanupExpiredTokens() {
+	deleted, err := s.tokenRepo.DeleteExpired(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		log.Printf("Warning: failed to delete expired refresh tokens: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Deleted %d expired refresh tokens", deleted)
+	}
+}
+
+func (s *AuthService) revokeReused(record *domain.RefreshToken) {
+	log.Printf("⚠️ Refresh token reused for user %d, revoking token family %s", record.UserID, record.FamilyID)
+	if err := s.tokenRepo.RevokeFamily(record.FamilyID); err != nil {
+		log.Printf("Warning: failed to revoke token family %s: %v", record.FamilyID, err)
+	}
+	s.auditService.RecordEvent(record.UserID, record.UserID, "auth.refresh_token_reused", "", domain.AuditDenied, map[string]interface{}{
+		"familyId": record.FamilyID,
+	})
+}
+
+func (s *AuthService) tokenPair(user *domain.User, refreshToken string) (*TokenPair, error) {
+	accessToken, err := s.issuer.GenerateToken(user.ID, utils.SafeString(user.ChatbotID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+	}, nil
+}
+
+func (s *AuthService) newRefreshToken(userID uint, familyID string) (string, *domain.RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, &domain.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(token),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}, nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
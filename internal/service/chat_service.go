@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"gorm.io/gorm"
+)
+
+type ChatService struct {
+	chatRepo  repository.ChatRepository
+	waManager *whatsmeow_client.Manager
+}
+
+func NewChatService(chatRepo repository.ChatRepository, waManager *whatsmeow_client.Manager) *ChatService {
+	return &ChatService{
+		chatRepo:  chatRepo,
+		waManager: waManager,
+	}
+}
+
+type ChatListResponse struct {
+	Chats  []domain.Chat `json:"chats"`
+	Total  int64         `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// ChatAction is an app state change applied to a chat
+type ChatAction string
+
+const (
+	ChatActionArchive   ChatAction = "archive"
+	ChatActionUnarchive ChatAction = "unarchive"
+	ChatActionPin       ChatAction = "pin"
+	ChatActionUnpin     ChatAction = "unpin"
+	ChatActionMute      ChatAction = "mute"
+	ChatActionUnmute    ChatAction = "unmute"
+	ChatActionUnread    ChatAction = "mark_unread"
+)
+
+// parseChatJID accepts a full JID (including groups) or a plain phone number
+func parseChatJID(chat string) (types.JID, error) {
+	if decoded, err := url.PathUnescape(chat); err == nil {
+		chat = decoded
+	}
+	if !strings.Contains(chat, "@") {
+		return parseRecipient(chat)
+	}
+
+	jid, err := types.ParseJID(chat)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid chat JID: %w", err)
+	}
+	return jid, nil
+}
+
+// MarkRead sends read receipts for a chat. With explicit message IDs only those
+// are acknowledged (sender is required in groups), otherwise every pending
+// incoming message of the chat is. Returns the number of messages marked read.
+func (s *ChatService) MarkRead(userID, chat string, messageIDs []string, sender string) (int, error) {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	chatJID, err := parseChatJID(chat)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(messageIDs) == 0 {
+		return clientData.MarkChatRead(chatJID)
+	}
+
+	senderJID := types.EmptyJID
+	if chatJID.Server == types.GroupServer {
+		if sender == "" {
+			return 0, fmt.Errorf("sender is required to mark group messages read")
+		}
+		if senderJID, err = parseChatJID(sender); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := clientData.MarkMessagesRead(chatJID, senderJID, messageIDs, time.Now()); err != nil {
+		return 0, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+	return len(messageIDs), nil
+}
+
+// ApplyAction archives, pins, mutes or marks a chat unread. muteDuration is
+// only used when muting, zero mutes forever.
+func (s *ChatService) ApplyAction(userID, chat string, action ChatAction, muteDuration time.Duration) error {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return err
+	}
+
+	chatJID, err := parseChatJID(chat)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case ChatActionArchive, ChatActionUnarchive:
+		err = clientData.ArchiveChat(chatJID, action == ChatActionArchive)
+	case ChatActionPin, ChatActionUnpin:
+		err = clientData.PinChat(chatJID, action == ChatActionPin)
+	case ChatActionMute, ChatActionUnmute:
+		err = clientData.MuteChat(chatJID, action == ChatActionMute, muteDuration)
+	case ChatActionUnread:
+		err = clientData.MarkChatUnread(chatJID)
+	default:
+		return fmt.Errorf("unknown chat action %q", action)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to %s chat: %w", strings.ReplaceAll(string(action), "_", " "), err)
+	}
+	return nil
+}
+
+// ListChats returns the chat index of a session
+func (s *ChatService) ListChats(userID string, filter repository.ChatFilter) (*ChatListResponse, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	chats, total, err := s.chatRepo.List(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	return &ChatListResponse{
+		Chats:  chats,
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// HandleMessage implements the whatsmeow_client.EventHandler interface and keeps the chat index up to date
+func (s *ChatService) HandleMessage(userID string, message interface{}) {
+	clientData, exists := s.waManager.GetClient(userID)
+	if !exists {
+		return
+	}
+
+	var err error
+	switch evt := message.(type) {
+	case *events.Message:
+		err = s.recordMessage(userID, clientData, evt)
+
+	case *events.Receipt:
+		if evt.IsFromMe && (evt.Type == types.ReceiptTypeRead || evt.Type == types.ReceiptTypeReadSelf) {
+			chat := clientData.NormalizeJID(evt.Chat).String()
+			err = s.chatRepo.MarkRead(userID, chat, len(evt.MessageIDs))
+		}
+
+	case *events.MarkChatAsRead:
+		chat := clientData.NormalizeJID(evt.JID).String()
+		if evt.Action.GetRead() {
+			err = s.chatRepo.MarkRead(userID, chat, -1)
+		} else {
+			err = s.chatRepo.UpdateState(userID, chat, map[string]interface{}{"marked_unread": true})
+		}
+
+	case *events.Archive:
+		err = s.chatRepo.UpdateState(userID, clientData.NormalizeJID(evt.JID).String(), map[string]interface{}{
+			"archived": evt.Action.GetArchived(),
+		})
+
+	case *events.Pin:
+		err = s.chatRepo.UpdateState(userID, clientData.NormalizeJID(evt.JID).String(), map[string]interface{}{
+			"pinned": evt.Action.GetPinned(),
+		})
+
+	case *events.Mute:
+		err = s.chatRepo.UpdateState(userID, clientData.NormalizeJID(evt.JID).String(), map[string]interface{}{
+			"muted_until": muteEnd(evt.Action.GetMuted(), evt.Action.GetMuteEndTimestamp()),
+		})
+
+	case *events.HistorySync:
+		s.recordHistoryConversations(userID, clientData, evt)
+	}
+
+	if err != nil {
+		log.Printf("Failed to update chat index for user %s: %v", userID, err)
+	}
+}
+
+func (s *ChatService) recordMessage(userID string, clientData *whatsmeow_client.ClientData, evt *events.Message) error {
+	preview := whatsmeow_client.MessagePreview(evt.Message)
+	if preview == "" {
+		return nil // Reactions, receipts and protocol messages don't move the chat
+	}
+
+	chatJID := clientData.NormalizeJID(evt.Info.Chat)
+	if chatJID.Server == types.BroadcastServer {
+		return nil // Status updates aren't conversations
+	}
+
+	name := ""
+	if !evt.Info.IsGroup {
+		if name = clientData.ContactName(chatJID); name == "" && !evt.Info.IsFromMe {
+			name = evt.Info.PushName
+		}
+	} else if existing, err := s.chatRepo.FindByJID(userID, chatJID.String()); err == gorm.ErrRecordNotFound || (err == nil && existing.Name == "") {
+		// Group names aren't in messages, look them up once
+		if info, err := clientData.Client.GetGroupInfo(context.Background(), chatJID); err == nil {
+			name = info.Name
+		}
+	}
+
+	unread := 0
+	if !evt.Info.IsFromMe {
+		unread = 1
+	}
+
+	timestamp := evt.Info.Timestamp
+	return s.chatRepo.RecordMessage(&domain.Chat{
+		UserID:            userID,
+		JID:               chatJID.String(),
+		Name:              name,
+		IsGroup:           evt.Info.IsGroup,
+		LastMessage:       preview,
+		LastMessageID:     evt.Info.ID,
+		LastMessageFromMe: evt.Info.IsFromMe,
+		LastActivity:      &timestamp,
+	}, unread)
+}
+
+// recordHistoryConversations stores chat metadata (names, unread counts, flags
+// and the last message) delivered by a history sync after pairing
+func (s *ChatService) recordHistoryConversations(userID string, clientData *whatsmeow_client.ClientData, evt *events.HistorySync) {
+	for _, conv := range evt.Data.GetConversations() {
+		rawJID, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+		jid := clientData.NormalizeJID(rawJID)
+		if jid.Server == types.BroadcastServer {
+			continue
+		}
+
+		name := conv.GetName()
+		if name == "" {
+			name = conv.GetDisplayName()
+		}
+		if name == "" && jid.Server != types.GroupServer {
+			name = clientData.ContactName(jid)
+		}
+
+		chat := &domain.Chat{
+			UserID:       userID,
+			JID:          jid.String(),
+			Name:         name,
+			IsGroup:      jid.Server == types.GroupServer,
+			UnreadCount:  int(conv.GetUnreadCount()),
+			MarkedUnread: conv.GetMarkedAsUnread(),
+			Archived:     conv.GetArchived(),
+			Pinned:       conv.GetPinned() > 0,
+			MutedUntil:   muteEnd(conv.GetMuteEndTime() != 0, int64(conv.GetMuteEndTime())),
+		}
+		if ts := conv.GetConversationTimestamp(); ts > 0 {
+			lastActivity := time.Unix(int64(ts), 0)
+			chat.LastActivity = &lastActivity
+		}
+		if last, preview := latestHistoryMessage(clientData, rawJID, conv); last != nil {
+			chat.LastMessage = preview
+			chat.LastMessageID = last.Info.ID
+			chat.LastMessageFromMe = last.Info.IsFromMe
+			if chat.LastActivity == nil || last.Info.Timestamp.After(*chat.LastActivity) {
+				timestamp := last.Info.Timestamp
+				chat.LastActivity = &timestamp
+			}
+		}
+
+		if err := s.chatRepo.UpsertFromHistory(chat); err != nil {
+			log.Printf("Failed to store history chat %s for user %s: %v", jid, userID, err)
+		}
+	}
+}
+
+// latestHistoryMessage returns the newest message of a history conversation
+// that shows up in chat lists, and its preview
+func latestHistoryMessage(clientData *whatsmeow_client.ClientData, chatJID types.JID, conv *waHistorySync.Conversation) (*events.Message, string) {
+	var latest *events.Message
+	var latestPreview string
+	for _, historyMsg := range conv.GetMessages() {
+		parsed, err := clientData.Client.ParseWebMessage(chatJID, historyMsg.GetMessage())
+		if err != nil {
+			continue
+		}
+		preview := whatsmeow_client.MessagePreview(parsed.Message)
+		if preview == "" {
+			continue
+		}
+		if latest == nil || parsed.Info.Timestamp.After(latest.Info.Timestamp) {
+			latest, latestPreview = parsed, preview
+		}
+	}
+	return latest, latestPreview
+}
+
+// foreverMute is stored for chats muted without an end time
+var foreverMute = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// muteEnd converts a WhatsApp mute end (unix seconds or milliseconds, -1 for forever) to a nullable time
+func muteEnd(muted bool, endTimestamp int64) *time.Time {
+	if !muted {
+		return nil
+	}
+	if endTimestamp < 0 || endTimestamp == 0 {
+		return &foreverMute
+	}
+	// App state uses milliseconds, history sync uses seconds
+	if endTimestamp > 1e12 {
+		endTimestamp /= 1000
+	}
+	end := time.Unix(endTimestamp, 0)
+	return &end
+}
@@ -26,6 +26,16 @@ type ChatbotService struct {
 	conversationRepo repository.ConversationStateRepository
 	userRepo         repository.UserRepository
 	waManager        *whatsmeow_client.Manager
+	getClient        func(userID string) (chatbotClient, bool)
+}
+
+// chatbotClient is what the chatbot needs of a session to answer, implemented
+// by whatsmeow_client.ClientData
+type chatbotClient interface {
+	SendMessage(ctx context.Context, to types.JID, msg *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error)
+	Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error)
+	MarkMessagesRead(chat, sender types.JID, ids []types.MessageID, timestamp time.Time) error
+	SimulateTyping(jid types.JID, duration time.Duration)
 }
 
 func NewChatbotService(
@@ -41,6 +51,13 @@ func NewChatbotService(
 		conversationRepo: conversationRepo,
 		userRepo:         userRepo,
 		waManager:        waManager,
+		getClient: func(userID string) (chatbotClient, bool) {
+			clientData, exists := waManager.GetClient(userID)
+			if !exists {
+				return nil, false
+			}
+			return clientData, true
+		},
 	}
 }
 
@@ -82,7 +99,7 @@ func (s *ChatbotService) HandleIncomingMessage(userID string, messageEvt interfa
 	}
 
 	// Get WhatsApp client
-	clientData, exists := s.waManager.GetClient(userID)
+	clientData, exists := s.getClient(userID)
 	if !exists {
 		log.Printf("WhatsApp client not found for user %s", userID)
 		return
@@ -90,6 +107,7 @@ func (s *ChatbotService) HandleIncomingMessage(userID string, messageEvt interfa
 	fmt.Print("~ Recieved Message - " + messageBody)
 	// Check if it's a greeting
 	if utils.IsGreeting(messageBody) {
+		s.markHandled(chatbot, msgEvent, clientData)
 		s.handleGreeting(chatbot, chatID, clientData)
 		s.updateConversationState(userID, chatID)
 		return
@@ -112,45 +130,90 @@ func (s *ChatbotService) HandleIncomingMessage(userID string, messageEvt interfa
 	}
 
 	if matchedOption != nil {
-		s.handleOptionResponse(matchedOption, chatID, clientData)
+		s.markHandled(chatbot, msgEvent, clientData)
+		s.handleOptionResponse(chatbot, matchedOption, chatID, clientData)
 		s.updateConversationState(userID, chatID)
 	}
 
 	// If no match, don't reply (as per requirement)
 }
 
-func (s *ChatbotService) handleGreeting(chatbot *domain.Chatbot, chatID string, clientData *whatsmeow_client.ClientData) {
-	jid, err := types.ParseJID(chatID)
+// markHandled sends a read receipt for a message the chatbot answers, if the chatbot auto-reads
+func (s *ChatbotService) markHandled(chatbot *domain.Chatbot, msgEvent *whatsmeow_client.MessageEvent, clientData chatbotClient) {
+	if !chatbot.AutoRead {
+		return
+	}
+
+	chat, err := types.ParseJID(msgEvent.From)
 	if err != nil {
 		log.Printf("Failed to parse JID: %v", err)
 		return
 	}
 
-	// Send media if available, otherwise send text
-	if chatbot.MediaURL != nil && *chatbot.MediaURL != "" {
-		s.sendMediaMessage(clientData, jid, *chatbot.MediaURL, chatbot.WelcomeMessage)
-	} else {
-		s.sendTextMessage(clientData, jid, chatbot.WelcomeMessage)
+	sender := types.EmptyJID
+	if msgEvent.IsGroup {
+		if sender, err = types.ParseJID(msgEvent.Sender); err != nil {
+			log.Printf("Failed to parse sender JID: %v", err)
+			return
+		}
+	}
+
+	if err := clientData.MarkMessagesRead(chat, sender, []types.MessageID{msgEvent.ID}, time.Unix(msgEvent.Timestamp, 0)); err != nil {
+		log.Printf("Failed to mark message %s read: %v", msgEvent.ID, err)
 	}
 }
 
-func (s *ChatbotService) handleOptionResponse(option *domain.ChatbotOption, chatID string, clientData *whatsmeow_client.ClientData) {
+func (s *ChatbotService) handleGreeting(chatbot *domain.Chatbot, chatID string, clientData chatbotClient) {
 	jid, err := types.ParseJID(chatID)
 	if err != nil {
 		log.Printf("Failed to parse JID: %v", err)
 		return
 	}
 
-	// Send media with caption if available, otherwise send text
-	if option.MediaURL != nil && *option.MediaURL != "" {
-		s.sendMediaMessage(clientData, jid, *option.MediaURL, option.Answer)
-	} else {
-		s.sendTextMessage(clientData, jid, option.Answer)
+	s.sendReply(chatbot, clientData, jid, chatbot.WelcomeMessage, func() {
+		// Send media if available, otherwise send text
+		if chatbot.MediaURL != nil && *chatbot.MediaURL != "" {
+			s.sendMediaMessage(clientData, jid, *chatbot.MediaURL, chatbot.WelcomeMessage)
+		} else {
+			s.sendTextMessage(clientData, jid, chatbot.WelcomeMessage)
+		}
+	})
+}
+
+func (s *ChatbotService) handleOptionResponse(chatbot *domain.Chatbot, option *domain.ChatbotOption, chatID string, clientData chatbotClient) {
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		log.Printf("Failed to parse JID: %v", err)
+		return
+	}
+
+	s.sendReply(chatbot, clientData, jid, option.Answer, func() {
+		// Send media with caption if available, otherwise send text
+		if option.MediaURL != nil && *option.MediaURL != "" {
+			s.sendMediaMessage(clientData, jid, *option.MediaURL, option.Answer)
+		} else {
+			s.sendTextMessage(clientData, jid, option.Answer)
+		}
+	})
+}
+
+// sendReply runs send straight away, or after showing "typing…" for a time
+// proportional to the reply when the chatbot simulates typing. The delay runs
+// in its own goroutine so it doesn't hold up the session's event processing.
+func (s *ChatbotService) sendReply(chatbot *domain.Chatbot, clientData chatbotClient, jid types.JID, text string, send func()) {
+	if !chatbot.SimulateTyping {
+		send()
+		return
 	}
+
+	go func() {
+		clientData.SimulateTyping(jid, utils.TypingDelay(text, chatbot.TypingSpeed))
+		send()
+	}()
 }
 
-func (s *ChatbotService) sendTextMessage(clientData *whatsmeow_client.ClientData, jid types.JID, message string) {
-	_, err := clientData.Client.SendMessage(context.Background(), jid, &waProto.Message{
+func (s *ChatbotService) sendTextMessage(clientData chatbotClient, jid types.JID, message string) {
+	_, err := clientData.SendMessage(context.Background(), jid, &waProto.Message{
 		Conversation: proto.String(message),
 	})
 	if err != nil {
@@ -158,7 +221,7 @@ func (s *ChatbotService) sendTextMessage(clientData *whatsmeow_client.ClientData
 	}
 }
 
-func (s *ChatbotService) sendMediaMessage(clientData *whatsmeow_client.ClientData, jid types.JID, mediaURL, caption string) {
+func (s *ChatbotService) sendMediaMessage(clientData chatbotClient, jid types.JID, mediaURL, caption string) {
 	// Download media
 	resp, err := http.Get(mediaURL)
 	if err != nil {
@@ -176,7 +239,7 @@ func (s *ChatbotService) sendMediaMessage(clientData *whatsmeow_client.ClientDat
 	}
 
 	// Upload media to WhatsApp
-	uploaded, err := clientData.Client.Upload(context.Background(), data, whatsmeow.MediaImage)
+	uploaded, err := clientData.Upload(context.Background(), data, whatsmeow.MediaImage)
 	if err != nil {
 		log.Printf("Failed to upload media: %v", err)
 		s.sendTextMessage(clientData, jid, caption) // Fallback to text
@@ -197,7 +260,7 @@ func (s *ChatbotService) sendMediaMessage(clientData *whatsmeow_client.ClientDat
 		},
 	}
 
-	_, err = clientData.Client.SendMessage(context.Background(), jid, msg)
+	_, err = clientData.SendMessage(context.Background(), jid, msg)
 	if err != nil {
 		log.Printf("Failed to send media message: %v", err)
 	}
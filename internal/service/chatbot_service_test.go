@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"gorm.io/gorm"
+)
+
+type fakeChatbotRepo struct{ chatbot *domain.Chatbot }
+
+func (r *fakeChatbotRepo) FindByID(id string) (*domain.Chatbot, error) { return r.FindByUserID("") }
+func (r *fakeChatbotRepo) FindByUserID(userID string) (*domain.Chatbot, error) {
+	if r.chatbot == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.chatbot, nil
+}
+func (r *fakeChatbotRepo) Create(chatbot *domain.Chatbot) error { return nil }
+func (r *fakeChatbotRepo) Update(chatbot *domain.Chatbot) error { return nil }
+func (r *fakeChatbotRepo) Delete(id string) error               { return nil }
+
+type fakeOptionRepo struct{ options []domain.ChatbotOption }
+
+func (r *fakeOptionRepo) FindByChatbotID(chatbotID string) ([]domain.ChatbotOption, error) {
+	return r.options, nil
+}
+func (r *fakeOptionRepo) FindByKey(chatbotID, optionKey string) (*domain.ChatbotOption, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+func (r *fakeOptionRepo) Create(option *domain.ChatbotOption) error { return nil }
+func (r *fakeOptionRepo) Update(option *domain.ChatbotOption) error { return nil }
+func (r *fakeOptionRepo) Delete(id string) error                    { return nil }
+
+type fakeConversationRepo struct{}
+
+func (r *fakeConversationRepo) FindByUserAndChat(userID, chatID string) (*domain.ConversationState, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+func (r *fakeConversationRepo) Create(state *domain.ConversationState) error { return nil }
+func (r *fakeConversationRepo) Update(state *domain.ConversationState) error { return nil }
+
+// fakeChatbotClient records what the chatbot does, in order
+type fakeChatbotClient struct {
+	mu      sync.Mutex
+	calls   []string
+	typedAs time.Duration
+	readFor []types.JID // chat, sender
+	readIDs []types.MessageID
+	sent    []string
+	done    chan struct{}
+}
+
+func (c *fakeChatbotClient) record(call string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+func (c *fakeChatbotClient) SendMessage(ctx context.Context, to types.JID, msg *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	c.mu.Lock()
+	c.sent = append(c.sent, msg.GetConversation())
+	c.mu.Unlock()
+	c.record("send")
+	close(c.done)
+	return whatsmeow.SendResponse{}, nil
+}
+
+func (c *fakeChatbotClient) Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	return whatsmeow.UploadResponse{}, nil
+}
+
+func (c *fakeChatbotClient) MarkMessagesRead(chat, sender types.JID, ids []types.MessageID, timestamp time.Time) error {
+	c.mu.Lock()
+	c.readFor = []types.JID{chat, sender}
+	c.readIDs = ids
+	c.mu.Unlock()
+	c.record("read")
+	return nil
+}
+
+func (c *fakeChatbotClient) SimulateTyping(jid types.JID, duration time.Duration) {
+	c.mu.Lock()
+	c.typedAs = duration
+	c.mu.Unlock()
+	c.record("typing")
+}
+
+func incomingMessage(text string, group bool) *events.Message {
+	chat := types.NewJID("919876543210", types.DefaultUserServer)
+	sender := chat
+	if group {
+		chat = types.NewJID("120363000000000000", types.GroupServer)
+		sender = types.NewJID("919876543211", types.DefaultUserServer)
+	}
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: chat, Sender: sender, IsGroup: group},
+			ID:            "MSG1",
+			Timestamp:     time.Unix(1700000000, 0),
+		},
+		Message: &waProto.Message{Conversation: &text},
+	}
+}
+
+func TestChatbotHandleMessage(t *testing.T) {
+	welcome := "Welcome! Reply 1 for prices"
+	options := []domain.ChatbotOption{{ID: "opt1", ChatbotID: "bot1", OptionKey: "1", Answer: "Prices start at $10"}}
+
+	tests := []struct {
+		name      string
+		chatbot   domain.Chatbot
+		text      string
+		group     bool
+		inactive  bool
+		wantCalls []string
+		wantSent  string
+	}{
+		{
+			name:      "greeting with typing and auto-read",
+			chatbot:   domain.Chatbot{SimulateTyping: true, TypingSpeed: 20, AutoRead: true},
+			text:      "hi",
+			wantCalls: []string{"read", "typing", "send"},
+			wantSent:  welcome,
+		},
+		{
+			name:      "option without typing or auto-read",
+			chatbot:   domain.Chatbot{},
+			text:      "1",
+			wantCalls: []string{"send"},
+			wantSent:  "Prices start at $10",
+		},
+		{
+			name:      "group option read for its sender",
+			chatbot:   domain.Chatbot{AutoRead: true},
+			text:      "1",
+			group:     true,
+			wantCalls: []string{"read", "send"},
+			wantSent:  "Prices start at $10",
+		},
+		{
+			name:    "unknown text gets no answer",
+			chatbot: domain.Chatbot{SimulateTyping: true, AutoRead: true},
+			text:    "what?",
+		},
+		{
+			name:     "inactive chatbot stays quiet",
+			chatbot:  domain.Chatbot{AutoRead: true},
+			text:     "hi",
+			inactive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatbot := tt.chatbot
+			chatbot.ID = "bot1"
+			chatbot.UserID = "session_1"
+			chatbot.WelcomeMessage = welcome
+			chatbot.IsActive = !tt.inactive
+
+			client := &fakeChatbotClient{done: make(chan struct{})}
+			s := &ChatbotService{
+				chatbotRepo:      &fakeChatbotRepo{chatbot: &chatbot},
+				optionRepo:       &fakeOptionRepo{options: options},
+				conversationRepo: &fakeConversationRepo{},
+				getClient:        func(userID string) (chatbotClient, bool) { return client, userID == "session_1" },
+			}
+
+			// The same entry point the manager dispatches events to
+			s.HandleMessage("session_1", incomingMessage(tt.text, tt.group))
+
+			if tt.wantSent != "" {
+				select {
+				case <-client.done:
+				case <-time.After(2 * time.Second):
+					t.Fatal("no reply was sent")
+				}
+			}
+
+			client.mu.Lock()
+			defer client.mu.Unlock()
+			if len(client.calls) != len(tt.wantCalls) {
+				t.Fatalf("calls = %v, want %v", client.calls, tt.wantCalls)
+			}
+			for i := range tt.wantCalls {
+				if client.calls[i] != tt.wantCalls[i] {
+					t.Fatalf("calls = %v, want %v", client.calls, tt.wantCalls)
+				}
+			}
+			if tt.wantSent == "" {
+				return
+			}
+			if client.sent[0] != tt.wantSent {
+				t.Errorf("sent %q, want %q", client.sent[0], tt.wantSent)
+			}
+			if chatbot.SimulateTyping {
+				if want := utils.TypingDelay(tt.wantSent, chatbot.TypingSpeed); client.typedAs != want {
+					t.Errorf("typed for %s, want %s", client.typedAs, want)
+				}
+			}
+			if chatbot.AutoRead {
+				msg := incomingMessage(tt.text, tt.group)
+				wantSender := types.EmptyJID
+				if tt.group {
+					wantSender = msg.Info.Sender
+				}
+				if client.readFor[0] != msg.Info.Chat || client.readFor[1] != wantSender {
+					t.Errorf("read chat %s sender %s, want %s and %s", client.readFor[0], client.readFor[1], msg.Info.Chat, wantSender)
+				}
+				if len(client.readIDs) != 1 || client.readIDs[0] != "MSG1" {
+					t.Errorf("read IDs %v, want [MSG1]", client.readIDs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"gorm.io/gorm"
+)
+
+// SessionOwner is the instance that runs a session
+type SessionOwner struct {
+	InstanceID string `json:"instance_id"`
+	URL        string `json:"instance_url"`
+	Local      bool   `json:"-"`
+}
+
+// ClusterService spreads sessions over the running instances. Each session is
+// assigned to one instance by rendezvous hashing, which only moves the
+// sessions of an instance that joins or leaves. The assigned instance takes a
+// lease on the session in the database and renews it with every heartbeat;
+// when an instance dies its leases expire and the survivors take over.
+type ClusterService struct {
+	clusterRepo   repository.ClusterRepository
+	sessionRepo   repository.SessionRepository
+	waManager     *whatsmeow_client.Manager
+	instance      domain.Instance
+	leaseTTL      time.Duration
+	concurrency   int
+	stop          chan struct{}
+	stopOnce      sync.Once
+	mu            sync.RWMutex
+	liveInstances []domain.Instance
+	lastRenewal   time.Time
+}
+
+func NewClusterService(clusterRepo repository.ClusterRepository, sessionRepo repository.SessionRepository, waManager *whatsmeow_client.Manager, instanceID, instanceURL string, leaseTTL time.Duration, restoreConcurrency int) *ClusterService {
+	now := time.Now()
+	return &ClusterService{
+		clusterRepo: clusterRepo,
+		sessionRepo: sessionRepo,
+		waManager:   waManager,
+		instance: domain.Instance{
+			ID:        instanceID,
+			URL:       instanceURL,
+			StartedAt: now,
+		},
+		leaseTTL:    leaseTTL,
+		concurrency: restoreConcurrency,
+		stop:        make(chan struct{}),
+	}
+}
+
+// InstanceID returns the ID of this instance
+func (s *ClusterService) InstanceID() string {
+	return s.instance.ID
+}
+
+// Start joins the cluster, restores the sessions assigned to this instance and
+// keeps leases up to date in the background
+func (s *ClusterService) Start() {
+	log.Printf("🌐 Joining cluster as %s (%s)", s.instance.ID, s.instance.URL)
+
+	if err := s.heartbeat(); err != nil {
+		log.Printf("Warning: cluster heartbeat failed: %v", err)
+	}
+
+	// Other instances may still be running sessions they haven't been told to
+	// hand over yet, so only sessions whose lease we get are restored. The
+	// leases count as renewed from here, or the first failed heartbeat would
+	// stop them right away.
+	s.mu.Lock()
+	s.lastRenewal = time.Now()
+	s.mu.Unlock()
+	acquired := make(map[string]bool)
+	for _, record := range s.assignedSessions() {
+		if s.acquire(record.UserID) {
+			acquired[record.UserID] = true
+		}
+	}
+	go s.waManager.RestoreSessions(s.concurrency, func(record whatsmeow_client.SessionRecord) bool {
+		return acquired[record.UserID]
+	})
+
+	go s.run()
+}
+
+// Stop hands all sessions back to the cluster, for a graceful shutdown
+func (s *ClusterService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+
+		for _, userID := range s.waManager.RunningSessions() {
+			s.waManager.ReleaseSession(userID)
+		}
+		if err := s.clusterRepo.ReleaseLeases(s.instance.ID); err != nil {
+			log.Printf("Warning: failed to release session leases: %v", err)
+		}
+		if err := s.clusterRepo.RemoveInstance(s.instance.ID); err != nil {
+			log.Printf("Warning: failed to leave the cluster: %v", err)
+		}
+		log.Printf("🌐 Left cluster")
+	})
+}
+
+func (s *ClusterService) run() {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.rebalance()
+		}
+	}
+}
+
+// rebalance renews our leases, stops sessions whose lease we lost, hands
+// sessions over to the instance they belong to and takes the ones that belong
+// to us once their lease is free
+func (s *ClusterService) rebalance() {
+	if err := s.heartbeat(); err != nil {
+		log.Printf("Warning: cluster heartbeat failed: %v", err)
+		s.fenceIfExpired()
+		return
+	}
+
+	now := time.Now()
+	if err := s.clusterRepo.RenewLeases(s.instance.ID, s.leaseTTL); err != nil {
+		log.Printf("Warning: failed to renew session leases: %v", err)
+		s.fenceIfExpired()
+		return
+	}
+	s.mu.Lock()
+	s.lastRenewal = now
+	s.mu.Unlock()
+
+	leases, err := s.clusterRepo.FindLeasesByInstance(s.instance.ID)
+	if err != nil {
+		log.Printf("Warning: failed to load session leases: %v", err)
+		return
+	}
+	held := make(map[string]domain.SessionLease, len(leases))
+	for _, lease := range leases {
+		held[lease.SessionID] = lease
+	}
+
+	running := make(map[string]bool)
+	for _, userID := range s.waManager.RunningSessions() {
+		running[userID] = true
+		if _, ok := held[userID]; !ok {
+			log.Printf("⚠️  Lost the lease of session %s, stopping it", userID)
+			s.waManager.ReleaseSession(userID)
+		}
+	}
+
+	// Leases of sessions that stopped here (logged out) are given back. Fresh
+	// leases are kept, init takes one before it starts the session.
+	for userID := range held {
+		if running[userID] {
+			continue
+		}
+		released, err := s.clusterRepo.ReleaseIdleLease(userID, s.instance.ID, s.leaseTTL)
+		if err != nil {
+			log.Printf("Warning: failed to release lease of session %s: %v", userID, err)
+		} else if released {
+			delete(held, userID)
+		}
+	}
+
+	for _, record := range s.linkedSessions() {
+		owner := s.assign(record.UserID)
+		_, holding := held[record.UserID]
+
+		switch {
+		case holding && owner.InstanceID != s.instance.ID:
+			log.Printf("🌐 Handing session %s over to %s", record.UserID, owner.InstanceID)
+			s.waManager.ReleaseSession(record.UserID)
+			s.release(record.UserID)
+
+		case !holding && owner.InstanceID == s.instance.ID:
+			if !s.acquire(record.UserID) {
+				continue // The previous owner hasn't let go yet
+			}
+			log.Printf("🌐 Taking over session %s", record.UserID)
+			if err := s.waManager.StartSession(record); err != nil {
+				log.Printf("Failed to start session %s: %v", record.UserID, err)
+			}
+		}
+	}
+}
+
+// fenceIfExpired stops every session once our leases may have expired, so two
+// instances never connect the same device while the database is unreachable
+func (s *ClusterService) fenceIfExpired() {
+	s.mu.RLock()
+	lastRenewal := s.lastRenewal
+	s.mu.RUnlock()
+
+	if time.Since(lastRenewal) < s.leaseTTL {
+		return
+	}
+	for _, userID := range s.waManager.RunningSessions() {
+		log.Printf("⚠️  Leases may have expired, stopping session %s", userID)
+		s.waManager.ReleaseSession(userID)
+	}
+}
+
+func (s *ClusterService) heartbeat() error {
+	if err := s.clusterRepo.Heartbeat(&s.instance); err != nil {
+		return err
+	}
+
+	instances, err := s.clusterRepo.FindLiveInstances(s.leaseTTL)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.liveInstances = instances
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ClusterService) acquire(sessionID string) bool {
+	ok, err := s.clusterRepo.AcquireLease(sessionID, s.instance.ID, s.leaseTTL)
+	if err != nil {
+		log.Printf("Warning: failed to acquire lease of session %s: %v", sessionID, err)
+		return false
+	}
+	return ok
+}
+
+func (s *ClusterService) release(sessionID string) {
+	if err := s.clusterRepo.ReleaseLease(sessionID, s.instance.ID); err != nil {
+		log.Printf("Warning: failed to release lease of session %s: %v", sessionID, err)
+	}
+}
+
+// linkedSessions returns the sessions that have a device to connect
+func (s *ClusterService) linkedSessions() []whatsmeow_client.SessionRecord {
+	sessions, err := s.sessionRepo.FindWithDevice()
+	if err != nil {
+		log.Printf("Warning: failed to load sessions: %v", err)
+		return nil
+	}
+
+	records := make([]whatsmeow_client.SessionRecord, 0, len(sessions))
+	for _, session := range sessions {
+		records = append(records, whatsmeow_client.SessionRecord{
+			UserID:    session.ID,
+			DeviceJID: session.DeviceJID,
+		})
+	}
+	return records
+}
+
+// assignedSessions returns the linked sessions assigned to this instance
+func (s *ClusterService) assignedSessions() []whatsmeow_client.SessionRecord {
+	var assigned []whatsmeow_client.SessionRecord
+	for _, record := range s.linkedSessions() {
+		if s.assign(record.UserID).InstanceID == s.instance.ID {
+			assigned = append(assigned, record)
+		}
+	}
+	return assigned
+}
+
+// assign picks the live instance a session belongs to: the one with the
+// highest hash of instance and session ID
+func (s *ClusterService) assign(sessionID string) SessionOwner {
+	s.mu.RLock()
+	instances := s.liveInstances
+	s.mu.RUnlock()
+
+	best := SessionOwner{InstanceID: s.instance.ID, URL: s.instance.URL, Local: true}
+	var bestScore uint64
+	for _, instance := range instances {
+		hash := fnv.New64a()
+		hash.Write([]byte(instance.ID + "/" + sessionID))
+		if score := hash.Sum64(); score > bestScore {
+			bestScore = score
+			best = SessionOwner{InstanceID: instance.ID, URL: instance.URL, Local: instance.ID == s.instance.ID}
+		}
+	}
+	return best
+}
+
+// ownerAttempts is how often Owner tries to take a lease that keeps changing hands
+const ownerAttempts = 3
+
+// Owner returns the instance that runs a session. A session nobody holds is
+// claimed when it is assigned to this instance, so requests for new sessions
+// (init) stay here and everything else is sent to the same place.
+func (s *ClusterService) Owner(sessionID string) (*SessionOwner, error) {
+	lease, err := s.clusterRepo.FindActiveLease(sessionID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if lease != nil {
+		if lease.InstanceID == s.instance.ID {
+			return &SessionOwner{InstanceID: s.instance.ID, URL: s.instance.URL, Local: true}, nil
+		}
+		return s.instanceOwner(lease.InstanceID), nil
+	}
+
+	owner := s.assign(sessionID)
+	if !owner.Local {
+		return &owner, nil
+	}
+	// Another instance may claim it first, and its lease may be gone again by
+	// the time we look, so try a few times before giving up on the lease
+	for attempt := 0; attempt < ownerAttempts; attempt++ {
+		acquired, err := s.clusterRepo.AcquireLease(sessionID, s.instance.ID, s.leaseTTL)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return &owner, nil
+		}
+
+		lease, err := s.clusterRepo.FindActiveLease(sessionID)
+		if err == nil {
+			return s.instanceOwner(lease.InstanceID), nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+	}
+	// The session is assigned here, rebalance takes the lease once it settles
+	return &owner, nil
+}
+
+// Holders returns which instance holds each of the given sessions
+func (s *ClusterService) Holders(sessionIDs []string) (map[string]string, error) {
+	leases, err := s.clusterRepo.FindActiveLeases(sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := make(map[string]string, len(leases))
+	for _, lease := range leases {
+		holders[lease.SessionID] = lease.InstanceID
+	}
+	return holders, nil
+}
+
+func (s *ClusterService) instanceOwner(instanceID string) *SessionOwner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, instance := range s.liveInstances {
+		if instance.ID == instanceID {
+			return &SessionOwner{InstanceID: instance.ID, URL: instance.URL}
+		}
+	}
+	return &SessionOwner{InstanceID: instanceID}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"gorm.io/gorm"
+)
+
+// profileMaxAge is how long fetched profiles (about text, pictures) are reused
+// before they are looked up again. Picture URLs expire, so this stays short.
+const profileMaxAge = 6 * time.Hour
+
+type ContactService struct {
+	contactRepo repository.ContactRepository
+	waManager   *whatsmeow_client.Manager
+}
+
+func NewContactService(contactRepo repository.ContactRepository, waManager *whatsmeow_client.Manager) *ContactService {
+	return &ContactService{
+		contactRepo: contactRepo,
+		waManager:   waManager,
+	}
+}
+
+type ContactListResponse struct {
+	Contacts []domain.Contact `json:"contacts"`
+	Total    int64            `json:"total"`
+	Limit    int              `json:"limit"`
+	Offset   int              `json:"offset"`
+}
+
+// ListContacts returns the contact directory of a session
+func (s *ContactService) ListContacts(userID string, filter repository.ContactFilter) (*ContactListResponse, error) {
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	contacts, total, err := s.contactRepo.List(userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+
+	for i := range contacts {
+		contacts[i].Name = contacts[i].DisplayName()
+	}
+
+	return &ContactListResponse{
+		Contacts: contacts,
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	}, nil
+}
+
+// GetContact returns a contact with its profile picture, about text and business
+// profile, looking them up on WhatsApp when they're missing, stale or refresh is set
+func (s *ContactService) GetContact(userID, contact string, refresh bool) (*domain.Contact, error) {
+	jid, err := parseChatJID(contact)
+	if err != nil {
+		return nil, err
+	}
+	if jid.Server == types.GroupServer {
+		return nil, fmt.Errorf("%s is a group, not a contact", jid)
+	}
+
+	stored, err := s.contactRepo.FindByJID(userID, jid.String())
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to load contact: %w", err)
+	}
+	if stored == nil {
+		stored = &domain.Contact{UserID: userID, JID: jid.String()}
+	}
+
+	stale := stored.ProfileFetchedAt == nil || time.Since(*stored.ProfileFetchedAt) > profileMaxAge
+	if refresh || stale {
+		if err := s.refreshProfile(userID, jid, stored); err != nil {
+			// Serve what we have if the session can't look the profile up right now
+			if stored.ID == "" {
+				return nil, err
+			}
+			log.Printf("Warning: failed to refresh profile of %s for user %s: %v", jid, userID, err)
+		}
+	}
+
+	stored.Name = stored.DisplayName()
+	return stored, nil
+}
+
+func (s *ContactService) refreshProfile(userID string, jid types.JID, contact *domain.Contact) error {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return err
+	}
+
+	profile, err := clientData.FetchUserProfile(jid)
+	if err != nil {
+		return fmt.Errorf("failed to fetch profile: %w", err)
+	}
+
+	now := time.Now()
+	contact.About = profile.About
+	contact.ProfilePictureID = profile.PictureID
+	contact.ProfilePictureURL = profile.PictureURL
+	contact.IsBusiness = profile.BusinessName != ""
+	contact.BusinessProfile = toBusinessProfile(profile.BusinessProfile)
+	contact.ProfileFetchedAt = &now
+	if profile.BusinessName != "" {
+		contact.BusinessName = profile.BusinessName
+	}
+
+	// Names the user saved may only live in the device store so far
+	if contact.ID == "" {
+		if info, err := clientData.Client.Store.Contacts.GetContact(context.Background(), jid); err == nil && info.Found {
+			contact.FullName = info.FullName
+			contact.FirstName = info.FirstName
+			contact.PushName = info.PushName
+		}
+	}
+
+	contacts := []domain.Contact{*contact}
+	if err := s.contactRepo.Upsert(contacts, "about", "profile_picture_id", "profile_picture_url",
+		"is_business", "business_name", "business_profile", "profile_fetched_at"); err != nil {
+		return fmt.Errorf("failed to store profile: %w", err)
+	}
+	contact.ID = contacts[0].ID
+	return nil
+}
+
+// HandleMessage implements the whatsmeow_client.EventHandler interface and keeps
+// the contact directory in sync with the device's contact store
+func (s *ContactService) HandleMessage(userID string, message interface{}) {
+	clientData, exists := s.waManager.GetClient(userID)
+	if !exists {
+		return
+	}
+
+	var err error
+	switch evt := message.(type) {
+	case *events.Connected:
+		// The device store holds everything synced while we weren't listening
+		go s.syncDeviceContacts(userID, clientData)
+
+	case *events.Contact:
+		err = s.contactRepo.Upsert([]domain.Contact{{
+			UserID:    userID,
+			JID:       clientData.NormalizeJID(evt.JID).String(),
+			FullName:  evt.Action.GetFullName(),
+			FirstName: evt.Action.GetFirstName(),
+		}}, "full_name", "first_name")
+
+	case *events.PushName:
+		err = s.contactRepo.Upsert([]domain.Contact{{
+			UserID:   userID,
+			JID:      clientData.NormalizeJID(evt.JID).String(),
+			PushName: evt.NewPushName,
+		}}, "push_name")
+
+	case *events.BusinessName:
+		err = s.contactRepo.Upsert([]domain.Contact{{
+			UserID:       userID,
+			JID:          clientData.NormalizeJID(evt.JID).String(),
+			BusinessName: evt.NewBusinessName,
+			IsBusiness:   evt.NewBusinessName != "",
+		}}, "business_name", "is_business")
+
+	case *events.UserAbout:
+		err = s.contactRepo.Upsert([]domain.Contact{{
+			UserID: userID,
+			JID:    clientData.NormalizeJID(evt.JID).String(),
+			About:  evt.Status,
+		}}, "about")
+
+	case *events.Picture:
+		if evt.JID.Server == types.GroupServer {
+			return
+		}
+		// Picture URLs can't be derived from the ID, clear them so the next lookup fetches the new one
+		err = s.contactRepo.Upsert([]domain.Contact{{
+			UserID:           userID,
+			JID:              clientData.NormalizeJID(evt.JID).String(),
+			ProfilePictureID: evt.PictureID,
+		}}, "profile_picture_id", "profile_picture_url", "profile_fetched_at")
+
+	case *events.HistorySync:
+		var contacts []domain.Contact
+		for _, pushName := range evt.Data.GetPushnames() {
+			jid, parseErr := types.ParseJID(pushName.GetID())
+			if parseErr != nil || pushName.GetPushname() == "" {
+				continue
+			}
+			contacts = append(contacts, domain.Contact{
+				UserID:   userID,
+				JID:      clientData.NormalizeJID(jid).String(),
+				PushName: pushName.GetPushname(),
+			})
+		}
+		err = s.contactRepo.Upsert(contacts, "push_name")
+	}
+
+	if err != nil {
+		log.Printf("Failed to update contacts for user %s: %v", userID, err)
+	}
+}
+
+func (s *ContactService) syncDeviceContacts(userID string, clientData *whatsmeow_client.ClientData) {
+	deviceContacts, err := clientData.DeviceContacts()
+	if err != nil {
+		log.Printf("Failed to read device contacts for user %s: %v", userID, err)
+		return
+	}
+
+	contacts := make([]domain.Contact, 0, len(deviceContacts))
+	for jid, info := range deviceContacts {
+		contacts = append(contacts, domain.Contact{
+			UserID:       userID,
+			JID:          clientData.NormalizeJID(jid).String(),
+			FullName:     info.FullName,
+			FirstName:    info.FirstName,
+			PushName:     info.PushName,
+			BusinessName: info.BusinessName,
+			IsBusiness:   info.BusinessName != "",
+		})
+	}
+
+	if err := s.contactRepo.Upsert(contacts, "full_name", "first_name", "push_name", "business_name", "is_business"); err != nil {
+		log.Printf("Failed to store device contacts for user %s: %v", userID, err)
+		return
+	}
+	log.Printf("📇 Synced %d contacts for user %s", len(contacts), userID)
+}
+
+func toBusinessProfile(profile *types.BusinessProfile) *domain.BusinessProfile {
+	if profile == nil {
+		return nil
+	}
+
+	business := &domain.BusinessProfile{
+		Address:  profile.Address,
+		Email:    profile.Email,
+		TimeZone: profile.BusinessHoursTimeZone,
+	}
+	for _, category := range profile.Categories {
+		business.Categories = append(business.Categories, category.Name)
+	}
+	for _, hours := range profile.BusinessHours {
+		business.BusinessHours = append(business.BusinessHours, domain.BusinessHours{
+			DayOfWeek: hours.DayOfWeek,
+			Mode:      hours.Mode,
+			OpenTime:  hours.OpenTime,
+			CloseTime: hours.CloseTime,
+		})
+	}
+	return business
+}
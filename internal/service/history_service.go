@@ -0,0 +1,114 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	MessageSourceLive    = "live"
+	MessageSourceHistory = "history"
+)
+
+// HistoryService stores live messages and the history a phone sends after pairing
+// in the message store. Both go through the same unique key, so messages that
+// arrive live and again in a history chunk are only stored once.
+type HistoryService struct {
+	messageRepo repository.MessageRepository
+	waManager   *whatsmeow_client.Manager
+	depthDays   int
+}
+
+func NewHistoryService(messageRepo repository.MessageRepository, waManager *whatsmeow_client.Manager, depthDays int) *HistoryService {
+	return &HistoryService{
+		messageRepo: messageRepo,
+		waManager:   waManager,
+		depthDays:   depthDays,
+	}
+}
+
+// HandleMessage implements the whatsmeow_client.EventHandler interface
+func (s *HistoryService) HandleMessage(userID string, message interface{}) {
+	clientData, exists := s.waManager.GetClient(userID)
+	if !exists {
+		return
+	}
+
+	switch evt := message.(type) {
+	case *events.Message:
+		stored := s.toStoredMessage(userID, clientData, evt, MessageSourceLive)
+		if stored == nil {
+			return
+		}
+		if _, err := s.messageRepo.Save(stored); err != nil {
+			log.Printf("Failed to store message %s for user %s: %v", evt.Info.ID, userID, err)
+		}
+
+	case *events.HistorySync:
+		s.importHistory(userID, clientData, evt)
+	}
+}
+
+func (s *HistoryService) importHistory(userID string, clientData *whatsmeow_client.ClientData, evt *events.HistorySync) {
+	if s.depthDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -s.depthDays)
+
+	var batch []domain.Message
+	for _, conv := range evt.Data.GetConversations() {
+		chatJID, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+
+		for _, historyMsg := range conv.GetMessages() {
+			parsed, err := clientData.Client.ParseWebMessage(chatJID, historyMsg.GetMessage())
+			if err != nil {
+				continue
+			}
+			if parsed.Info.Timestamp.Before(cutoff) {
+				continue
+			}
+			if stored := s.toStoredMessage(userID, clientData, parsed, MessageSourceHistory); stored != nil {
+				batch = append(batch, *stored)
+			}
+		}
+	}
+
+	stored, err := s.messageRepo.SaveBatch(batch)
+	if err != nil {
+		log.Printf("Failed to store history sync messages for user %s: %v", userID, err)
+	}
+	clientData.RecordHistoryMessagesStored(int(stored))
+
+	log.Printf("📜 History sync chunk for user %s: %d conversations, %d new messages (%d%%)",
+		userID, len(evt.Data.GetConversations()), stored, evt.Data.GetProgress())
+}
+
+// toStoredMessage converts a message event to the stored form, or returns nil
+// for messages without visible content (reactions, protocol messages, status updates)
+func (s *HistoryService) toStoredMessage(userID string, clientData *whatsmeow_client.ClientData, evt *events.Message, source string) *domain.Message {
+	body := whatsmeow_client.MessagePreview(evt.Message)
+	if body == "" || evt.Info.Chat.Server == types.BroadcastServer {
+		return nil
+	}
+
+	return &domain.Message{
+		UserID:    userID,
+		ChatJID:   clientData.NormalizeJID(evt.Info.Chat).String(),
+		MessageID: evt.Info.ID,
+		SenderJID: clientData.NormalizeJID(evt.Info.Sender).String(),
+		FromMe:    evt.Info.IsFromMe,
+		PushName:  evt.Info.PushName,
+		Body:      body,
+		Timestamp: evt.Info.Timestamp,
+		Source:    source,
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/audio"
 	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
@@ -18,12 +20,16 @@ import (
 )
 
 type MessageService struct {
-	waManager *whatsmeow_client.Manager
+	waManager  *whatsmeow_client.Manager
+	transcoder audio.Transcoder
+	quota      *QuotaService
 }
 
-func NewMessageService(waManager *whatsmeow_client.Manager) *MessageService {
+func NewMessageService(waManager *whatsmeow_client.Manager, transcoder audio.Transcoder, quota *QuotaService) *MessageService {
 	return &MessageService{
-		waManager: waManager,
+		waManager:  waManager,
+		transcoder: transcoder,
+		quota:      quota,
 	}
 }
 
@@ -38,17 +44,59 @@ type BulkSendResult struct {
 	Error   string `json:"error,omitempty"`
 }
 
-// SendTextMessage sends a text message to a single recipient
-func (s *MessageService) SendTextMessage(userID, phone, message string) (*SendMessageResponse, error) {
-	clientData, exists := s.waManager.GetClient(userID)
+// LocationPin describes a location to share
+type LocationPin struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name"`
+	Address   string  `json:"address"`
+}
+
+// ContactCard describes a contact to share as a vCard
+type ContactCard struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	Organization string `json:"organization"`
+}
+
+// getReadyClient returns the client for a session that exists and is ready to
+// send, waking it up first if it is hibernating
+func getReadyClient(waManager *whatsmeow_client.Manager, userID string) (*whatsmeow_client.ClientData, error) {
+	clientData, exists := waManager.GetClient(userID)
 	if !exists {
 		return nil, fmt.Errorf("WhatsApp session not found. Please initialize session first")
 	}
 
+	if err := waManager.Wake(userID); err != nil {
+		return nil, fmt.Errorf("WhatsApp session is hibernating and couldn't be woken up: %w", err)
+	}
+
 	if clientData.GetStatus() != whatsmeow_client.StatusReady {
 		return nil, fmt.Errorf("WhatsApp session not ready. Current status: %s", clientData.GetStatus())
 	}
 
+	return clientData, nil
+}
+
+// parseRecipient converts a phone number into a WhatsApp JID
+func parseRecipient(phone string) (types.JID, error) {
+	jid, err := types.ParseJID(utils.FormatPhoneNumber(phone))
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid phone number: %w", err)
+	}
+	return jid, nil
+}
+
+// SendTextMessage sends a text message to a single recipient
+func (s *MessageService) SendTextMessage(userID, phone, message string) (*SendMessageResponse, error) {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
+	}
+
 	// Format phone number
 	jidString := utils.FormatPhoneNumber(phone)
 	jid, err := types.ParseJID(jidString)
@@ -57,7 +105,7 @@ func (s *MessageService) SendTextMessage(userID, phone, message string) (*SendMe
 	}
 
 	// Send message
-	resp, err := clientData.Client.SendMessage(context.Background(), jid, &waProto.Message{
+	resp, err := clientData.SendMessage(context.Background(), jid, &waProto.Message{
 		Conversation: proto.String(message),
 	})
 	if err != nil {
@@ -133,13 +181,12 @@ func (s *MessageService) SendTextMessage(userID, phone, message string) (*SendMe
 
 // SendMediaMessage sends a media message (image/video/document) with caption
 func (s *MessageService) SendMediaMessage(userID, phone, mediaURL, caption string) (*SendMessageResponse, error) {
-	clientData, exists := s.waManager.GetClient(userID)
-	if !exists {
-		return nil, fmt.Errorf("WhatsApp session not found. Please initialize session first")
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
 	}
-
-	if clientData.GetStatus() != whatsmeow_client.StatusReady {
-		return nil, fmt.Errorf("WhatsApp session not ready. Current status: %s", clientData.GetStatus())
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
 	}
 
 	// Format phone number
@@ -167,6 +214,22 @@ func (s *MessageService) SendMediaMessage(userID, phone, mediaURL, caption strin
 		mimeType = http.DetectContentType(data)
 	}
 
+	// Audio goes through transcoding and is sent as an audio message (which has no caption)
+	if strings.HasPrefix(mimeType, "audio/") {
+		msg, err := s.buildAudioMessage(clientData, data, mimeType, false)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := clientData.SendMessage(context.Background(), jid, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send audio message: %w", err)
+		}
+		return &SendMessageResponse{
+			MessageID: resp.ID,
+			Timestamp: resp.Timestamp.Unix(),
+		}, nil
+	}
+
 	// Determine media type and upload accordingly
 	var mediaType whatsmeow.MediaType
 
@@ -237,7 +300,7 @@ func (s *MessageService) SendMediaMessage(userID, phone, mediaURL, caption strin
 		}
 	}
 
-	resp, err := clientData.Client.SendMessage(context.Background(), jid, msg)
+	resp, err := clientData.SendMessage(context.Background(), jid, msg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send media message: %w", err)
 	}
@@ -249,19 +312,22 @@ func (s *MessageService) SendMediaMessage(userID, phone, mediaURL, caption strin
 }
 
 // SendBulkTextMessages sends text messages to multiple recipients
-func (s *MessageService) SendBulkTextMessages(userID string, phones []string, message string) []BulkSendResult {
-	clientData, exists := s.waManager.GetClient(userID)
-	if !exists {
+func (s *MessageService) SendBulkTextMessages(userID string, phones []string, message string) ([]BulkSendResult, error) {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
 		// Return error for all phones
 		results := make([]BulkSendResult, len(phones))
 		for i, phone := range phones {
 			results[i] = BulkSendResult{
 				Phone:   phone,
 				Success: false,
-				Error:   "WhatsApp session not found",
+				Error:   err.Error(),
 			}
 		}
-		return results
+		return results, nil
+	}
+	if err := s.quota.UseMessages(userID, len(phones)); err != nil {
+		return nil, err
 	}
 
 	results := make([]BulkSendResult, len(phones))
@@ -286,7 +352,7 @@ func (s *MessageService) SendBulkTextMessages(userID string, phones []string, me
 				return
 			}
 
-			_, err = clientData.Client.SendMessage(context.Background(), jid, &waProto.Message{
+			_, err = clientData.SendMessage(context.Background(), jid, &waProto.Message{
 				Conversation: proto.String(message),
 			})
 
@@ -308,7 +374,7 @@ func (s *MessageService) SendBulkTextMessages(userID string, phones []string, me
 	}
 
 	wg.Wait()
-	return results
+	return results, nil
 }
 
 // // SendBulkMediaMessages sends media messages to multiple recipients
@@ -426,18 +492,21 @@ func (s *MessageService) SendBulkTextMessages(userID string, phones []string, me
 // }
 
 // SendBulkMediaMessages sends media messages (image/video/document) to multiple recipients
-func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, mediaURL, message string) []BulkSendResult {
-	clientData, exists := s.waManager.GetClient(userID)
-	if !exists {
+func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, mediaURL, message string) ([]BulkSendResult, error) {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
 		results := make([]BulkSendResult, len(phones))
 		for i, phone := range phones {
 			results[i] = BulkSendResult{
 				Phone:   phone,
 				Success: false,
-				Error:   "WhatsApp session not found",
+				Error:   err.Error(),
 			}
 		}
-		return results
+		return results, nil
+	}
+	if err := s.quota.UseMessages(userID, len(phones)); err != nil {
+		return nil, err
 	}
 
 	// Download media once
@@ -451,7 +520,7 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 				Error:   fmt.Sprintf("failed to download media: %v", err),
 			}
 		}
-		return results
+		return results, nil
 	}
 	defer httpResp.Body.Close()
 
@@ -465,7 +534,7 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 				Error:   fmt.Sprintf("failed to read media: %v", err),
 			}
 		}
-		return results
+		return results, nil
 	}
 
 	// Detect media type from content-type header and URL
@@ -474,6 +543,15 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 		mimeType = http.DetectContentType(data)
 	}
 
+	// Audio is transcoded and uploaded once, then the same message goes to everyone
+	if strings.HasPrefix(mimeType, "audio/") {
+		msg, err := s.buildAudioMessage(clientData, data, mimeType, false)
+		if err != nil {
+			return failAllResults(phones, err.Error()), nil
+		}
+		return sendToMany(clientData, phones, msg), nil
+	}
+
 	// Determine media type and upload accordingly
 	var mediaType whatsmeow.MediaType
 	var uploaded whatsmeow.UploadResponse
@@ -497,7 +575,7 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 				Error:   fmt.Sprintf("failed to upload media: %v", err),
 			}
 		}
-		return results
+		return results, nil
 	}
 
 	// Extract filename from URL for documents
@@ -574,7 +652,7 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 				}
 			}
 
-			_, err = clientData.Client.SendMessage(context.Background(), jid, msg)
+			_, err = clientData.SendMessage(context.Background(), jid, msg)
 
 			mu.Lock()
 			if err != nil {
@@ -593,6 +671,252 @@ func (s *MessageService) SendBulkMediaMessages(userID string, phones []string, m
 		}(i, phone)
 	}
 
+	wg.Wait()
+	return results, nil
+}
+
+// SendLocationMessage sends a location pin to a single recipient
+func (s *MessageService) SendLocationMessage(userID, phone string, location LocationPin) (*SendMessageResponse, error) {
+	if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+		return nil, fmt.Errorf("invalid coordinates: latitude must be within ±90 and longitude within ±180")
+	}
+
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
+	}
+
+	jid, err := parseRecipient(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &waProto.Message{
+		LocationMessage: &waProto.LocationMessage{
+			DegreesLatitude:  proto.Float64(location.Latitude),
+			DegreesLongitude: proto.Float64(location.Longitude),
+		},
+	}
+	if location.Name != "" {
+		msg.LocationMessage.Name = proto.String(location.Name)
+	}
+	if location.Address != "" {
+		msg.LocationMessage.Address = proto.String(location.Address)
+	}
+
+	resp, err := clientData.SendMessage(context.Background(), jid, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send location: %w", err)
+	}
+
+	return &SendMessageResponse{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// SendContactMessage sends one or more contact cards to a single recipient.
+// A single contact is sent as a contact message, several as a contacts array.
+func (s *MessageService) SendContactMessage(userID, phone string, contacts []ContactCard) (*SendMessageResponse, error) {
+	if len(contacts) == 0 {
+		return nil, fmt.Errorf("at least one contact is required")
+	}
+
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
+	}
+
+	jid, err := parseRecipient(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]*waProto.ContactMessage, len(contacts))
+	for i, contact := range contacts {
+		if strings.TrimSpace(contact.Name) == "" || strings.TrimSpace(contact.Phone) == "" {
+			return nil, fmt.Errorf("contact %d: name and phone are required", i+1)
+		}
+		cards[i] = &waProto.ContactMessage{
+			DisplayName: proto.String(contact.Name),
+			Vcard:       proto.String(utils.BuildVCard(contact.Name, contact.Phone, contact.Organization)),
+		}
+	}
+
+	var msg *waProto.Message
+	if len(cards) == 1 {
+		msg = &waProto.Message{ContactMessage: cards[0]}
+	} else {
+		msg = &waProto.Message{
+			ContactsArrayMessage: &waProto.ContactsArrayMessage{
+				DisplayName: proto.String(fmt.Sprintf("%d contacts", len(cards))),
+				Contacts:    cards,
+			},
+		}
+	}
+
+	resp, err := clientData.SendMessage(context.Background(), jid, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send contact: %w", err)
+	}
+
+	return &SendMessageResponse{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// SendAudioMessage sends an audio file, as a voice note when ptt is set
+func (s *MessageService) SendAudioMessage(userID, phone, audioURL string, ptt bool) (*SendMessageResponse, error) {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
+	}
+
+	jid, err := parseRecipient(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	data, mimeType, err := downloadMedia(audioURL)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.buildAudioMessage(clientData, data, mimeType, ptt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := clientData.SendMessage(context.Background(), jid, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send audio message: %w", err)
+	}
+
+	return &SendMessageResponse{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// buildAudioMessage converts audio into a format WhatsApp can play, uploads it
+// and returns an audio message carrying duration and waveform metadata.
+// Voice notes must be OGG/Opus, plain audio only when the format isn't playable.
+func (s *MessageService) buildAudioMessage(clientData *whatsmeow_client.ClientData, data []byte, mimeType string, ptt bool) (*waProto.Message, error) {
+	ctx := context.Background()
+
+	if (ptt && !audio.IsOggOpus(data)) || (!ptt && !audio.IsWhatsAppPlayable(mimeType)) {
+		converted, err := s.transcoder.ToOggOpus(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s audio: %w", mimeType, err)
+		}
+		data = converted
+	}
+	if audio.IsOggOpus(data) {
+		mimeType = audio.VoiceNoteMimeType
+	}
+
+	// Metadata is cosmetic, send without it rather than failing
+	info, err := s.transcoder.Analyze(ctx, data)
+	if err != nil {
+		log.Printf("Warning: could not read audio metadata: %v", err)
+		info = &audio.Info{}
+	}
+
+	uploaded, err := clientData.Client.Upload(ctx, data, whatsmeow.MediaAudio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload audio: %w", err)
+	}
+
+	return &waProto.Message{
+		AudioMessage: &waProto.AudioMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			Mimetype:      proto.String(mimeType),
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uint64(len(data))),
+			Seconds:       proto.Uint32(info.Seconds),
+			Waveform:      info.Waveform,
+			PTT:           proto.Bool(ptt),
+		},
+	}, nil
+}
+
+// downloadMedia fetches media from a URL along with its MIME type
+func downloadMedia(mediaURL string) ([]byte, string, error) {
+	httpResp, err := http.Get(mediaURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download media: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download media: status %d", httpResp.StatusCode)
+	}
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read media: %w", err)
+	}
+
+	mimeType := httpResp.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return data, mimeType, nil
+}
+
+// failAllResults marks every recipient of a bulk send as failed with the same error
+func failAllResults(phones []string, errMsg string) []BulkSendResult {
+	results := make([]BulkSendResult, len(phones))
+	for i, phone := range phones {
+		results[i] = BulkSendResult{
+			Phone:   phone,
+			Success: false,
+			Error:   errMsg,
+		}
+	}
+	return results
+}
+
+// sendToMany sends the same prepared message to multiple recipients concurrently
+func sendToMany(clientData *whatsmeow_client.ClientData, phones []string, msg *waProto.Message) []BulkSendResult {
+	results := make([]BulkSendResult, len(phones))
+	var wg sync.WaitGroup
+
+	for i, phone := range phones {
+		wg.Add(1)
+		go func(idx int, ph string) {
+			defer wg.Done()
+
+			jid, err := parseRecipient(ph)
+			if err != nil {
+				results[idx] = BulkSendResult{Phone: ph, Success: false, Error: err.Error()}
+				return
+			}
+
+			// Each send gets its own copy since SendMessage may annotate the message
+			_, err = clientData.SendMessage(context.Background(), jid, proto.Clone(msg).(*waProto.Message))
+			if err != nil {
+				results[idx] = BulkSendResult{Phone: ph, Success: false, Error: err.Error()}
+				return
+			}
+			results[idx] = BulkSendResult{Phone: ph, Success: true}
+		}(i, phone)
+	}
+
 	wg.Wait()
 	return results
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+)
+
+// alertTitles are the notification titles for session alerts
+var alertTitles = map[whatsmeow_client.SessionStatus]string{
+	whatsmeow_client.StatusLoggedOut:         "WhatsApp session logged out",
+	whatsmeow_client.StatusTemporarilyBanned: "WhatsApp account temporarily banned",
+	whatsmeow_client.StatusStreamReplaced:    "WhatsApp session replaced by another connection",
+	whatsmeow_client.StatusClientOutdated:    "WhatsApp rejected the client version",
+}
+
+type NotificationService struct {
+	notificationRepo repository.NotificationRepository
+	sessionRepo      repository.SessionRepository
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, sessionRepo repository.SessionRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		sessionRepo:      sessionRepo,
+	}
+}
+
+type NotificationListResponse struct {
+	Notifications []domain.Notification `json:"notifications"`
+	Unread        int64                 `json:"unread"`
+}
+
+// ListNotifications returns a user's most recent notifications
+func (s *NotificationService) ListNotifications(userID uint, unreadOnly bool, limit int) (*NotificationListResponse, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	notifications, err := s.notificationRepo.FindByUser(userID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	unread, err := s.notificationRepo.CountUnread(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return &NotificationListResponse{
+		Notifications: notifications,
+		Unread:        unread,
+	}, nil
+}
+
+// MarkRead marks a notification as read, or all of them when id is 0
+func (s *NotificationService) MarkRead(userID, id uint) error {
+	return s.notificationRepo.MarkRead(userID, id)
+}
+
+// HandleMessage implements whatsmeow_client.EventHandler and notifies the
+// owner of a session when WhatsApp bans, replaces or logs it out
+func (s *NotificationService) HandleMessage(userID string, evt interface{}) {
+	alert, ok := evt.(*whatsmeow_client.SessionAlert)
+	if !ok {
+		return
+	}
+
+	session, err := s.sessionRepo.FindByID(userID)
+	if err != nil {
+		log.Printf("Warning: no session %s to notify about %s: %v", userID, alert.Status, err)
+		return
+	}
+	if session.OwnerID == 0 {
+		return // Imported session that hasn't been assigned yet
+	}
+
+	title, ok := alertTitles[alert.Status]
+	if !ok {
+		title = fmt.Sprintf("WhatsApp session %s", alert.Status)
+	}
+	message := fmt.Sprintf("Session %q: %s.", session.Name, alert.Reason)
+	if alert.Status == whatsmeow_client.StatusLoggedOut {
+		message += " Initialize the session again to link a device."
+	} else if alert.Permanent {
+		message += " The session won't reconnect by itself."
+	}
+
+	notification := &domain.Notification{
+		UserID:    session.OwnerID,
+		SessionID: session.ID,
+		Type:      string(alert.Status),
+		Title:     title,
+		Message:   message,
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		log.Printf("Failed to store notification for session %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+	"gorm.io/gorm"
+)
+
+type PollService struct {
+	pollRepo  repository.PollRepository
+	waManager *whatsmeow_client.Manager
+	quota     *QuotaService
+}
+
+func NewPollService(pollRepo repository.PollRepository, waManager *whatsmeow_client.Manager, quota *QuotaService) *PollService {
+	return &PollService{
+		pollRepo:  pollRepo,
+		waManager: waManager,
+		quota:     quota,
+	}
+}
+
+type PollOptionResult struct {
+	Option string   `json:"option"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+type PollResults struct {
+	Poll        *domain.Poll       `json:"poll"`
+	TotalVoters int                `json:"total_voters"`
+	Results     []PollOptionResult `json:"results"`
+}
+
+// SendPoll creates a poll in a chat and stores it so that votes can be tallied
+func (s *PollService) SendPoll(userID, phone, question string, options []string, selectableCount int) (*SendMessageResponse, error) {
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("question is required")
+	}
+	if len(options) < 2 {
+		return nil, fmt.Errorf("a poll needs at least 2 options")
+	}
+
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		if strings.TrimSpace(option) == "" {
+			return nil, fmt.Errorf("poll options cannot be empty")
+		}
+		if seen[option] {
+			return nil, fmt.Errorf("duplicate poll option: %s", option)
+		}
+		seen[option] = true
+	}
+
+	if selectableCount < 0 || selectableCount > len(options) {
+		return nil, fmt.Errorf("selectableCount must be between 0 (any number) and %d", len(options))
+	}
+
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quota.UseMessages(userID, 1); err != nil {
+		return nil, err
+	}
+
+	jid, err := parseRecipient(phone)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := clientData.Client.BuildPollCreation(question, options, selectableCount)
+	resp, err := clientData.SendMessage(context.Background(), jid, msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send poll: %w", err)
+	}
+
+	poll := &domain.Poll{
+		ID:                    resp.ID,
+		UserID:                userID,
+		ChatJID:               jid.String(),
+		Question:              question,
+		Options:               options,
+		SelectableOptionCount: selectableCount,
+	}
+	if err := s.pollRepo.Create(poll); err != nil {
+		// The poll is already out, so votes simply won't be tallied
+		log.Printf("Warning: failed to store poll %s for user %s: %v", resp.ID, userID, err)
+	}
+
+	return &SendMessageResponse{
+		MessageID: resp.ID,
+		Timestamp: resp.Timestamp.Unix(),
+	}, nil
+}
+
+// HandleMessage implements the whatsmeow_client.EventHandler interface
+func (s *PollService) HandleMessage(userID string, message interface{}) {
+	evt, ok := message.(*events.Message)
+	if !ok || evt.Message.GetPollUpdateMessage() == nil {
+		return
+	}
+
+	pollID := evt.Message.GetPollUpdateMessage().GetPollCreationMessageKey().GetID()
+	// Message IDs are chosen by the sender, so only this session's polls count
+	poll, err := s.pollRepo.FindBySessionAndID(userID, pollID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Printf("Failed to look up poll %s: %v", pollID, err)
+		}
+		return // Not a poll we sent
+	}
+
+	clientData, exists := s.waManager.GetClient(userID)
+	if !exists {
+		return
+	}
+
+	vote, err := clientData.Client.DecryptPollVote(context.Background(), evt)
+	if err != nil {
+		log.Printf("Failed to decrypt poll vote for poll %s: %v", pollID, err)
+		return
+	}
+
+	// Votes carry SHA-256 hashes of the option names, map them back
+	hashes := whatsmeow.HashPollOptions(poll.Options)
+	optionByHash := make(map[string]string, len(hashes))
+	for i, hash := range hashes {
+		optionByHash[hex.EncodeToString(hash)] = poll.Options[i]
+	}
+
+	selected := make([]string, 0, len(vote.GetSelectedOptions()))
+	for _, hash := range vote.GetSelectedOptions() {
+		if option, ok := optionByHash[hex.EncodeToString(hash)]; ok {
+			selected = append(selected, option)
+		}
+	}
+
+	if err := s.pollRepo.UpsertVote(&domain.PollVote{
+		ID:              utils.GenerateID("vote_"),
+		PollID:          poll.ID,
+		VoterJID:        evt.Info.Sender.ToNonAD().String(),
+		SelectedOptions: selected,
+		VotedAt:         evt.Info.Timestamp,
+	}); err != nil {
+		log.Printf("Failed to store poll vote for poll %s: %v", pollID, err)
+	}
+}
+
+// GetResults tallies the current votes of a poll
+func (s *PollService) GetResults(pollID string) (*PollResults, error) {
+	poll, err := s.pollRepo.FindByID(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := s.pollRepo.FindVotes(pollID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch votes: %w", err)
+	}
+
+	results := make([]PollOptionResult, len(poll.Options))
+	index := make(map[string]int, len(poll.Options))
+	for i, option := range poll.Options {
+		results[i] = PollOptionResult{Option: option, Voters: []string{}}
+		index[option] = i
+	}
+
+	totalVoters := 0
+	for _, vote := range votes {
+		// An empty selection means the voter retracted their vote
+		if len(vote.SelectedOptions) == 0 {
+			continue
+		}
+		totalVoters++
+		for _, option := range vote.SelectedOptions {
+			if i, ok := index[option]; ok {
+				results[i].Votes++
+				results[i].Voters = append(results[i].Voters, vote.VoterJID)
+			}
+		}
+	}
+
+	return &PollResults{
+		Poll:        poll,
+		TotalVoters: totalVoters,
+		Results:     results,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+)
+
+type PresenceService struct {
+	waManager *whatsmeow_client.Manager
+}
+
+func NewPresenceService(waManager *whatsmeow_client.Manager) *PresenceService {
+	return &PresenceService{
+		waManager: waManager,
+	}
+}
+
+// SetAvailability marks a session as online or offline
+func (s *PresenceService) SetAvailability(userID string, available bool) error {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := clientData.SetAvailability(available); err != nil {
+		return fmt.Errorf("failed to set availability: %w", err)
+	}
+	return nil
+}
+
+// SetChatPresence shows composing, recording or paused in a chat
+func (s *PresenceService) SetChatPresence(userID, phone string, state whatsmeow_client.ChatState) error {
+	clientData, err := getReadyClient(s.waManager, userID)
+	if err != nil {
+		return err
+	}
+
+	jid, err := parseRecipient(phone)
+	if err != nil {
+		return err
+	}
+
+	if err := clientData.SetChatPresence(jid, state); err != nil {
+		return fmt.Errorf("failed to set chat presence: %w", err)
+	}
+	return nil
+}
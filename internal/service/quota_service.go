@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+)
+
+// usageRetentionDays is how long daily usage counters are kept
+const usageRetentionDays = 90
+
+// QuotaError is returned when a plan limit is reached. ResetAt is zero for
+// limits that don't reset, like the number of sessions.
+type QuotaError struct {
+	Limit     string    `json:"limit"`
+	Max       int       `json:"max"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s limit of %d reached", e.Limit, e.Max)
+}
+
+// RateLimitStatus describes a request counted against a rate limit
+type RateLimitStatus struct {
+	Max       int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// QuotaService enforces the limits of each account's plan. Agents and
+// read-only users count against the account they belong to. Daily counters
+// are kept in the database; requests per minute are counted in memory, so in
+// a cluster every instance allows the full rate.
+type QuotaService struct {
+	usageRepo   repository.UsageRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	plans       map[string]domain.Plan
+	defaultPlan string
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewQuotaService(usageRepo repository.UsageRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository, plans map[string]domain.Plan, defaultPlan string) *QuotaService {
+	return &QuotaService{
+		usageRepo:   usageRepo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		plans:       plans,
+		defaultPlan: defaultPlan,
+		windows:     make(map[string]*rateWindow),
+	}
+}
+
+// HasPlan reports whether a plan is configured
+func (s *QuotaService) HasPlan(name string) bool {
+	_, ok := s.plans[name]
+	return ok
+}
+
+// PlanFor returns the plan of an account. Unknown plans fall back to the
+// default, so removing a plan from the configuration doesn't lock users out.
+func (s *QuotaService) PlanFor(tenantID uint) domain.Plan {
+	if user, err := s.userRepo.FindByID(tenantID); err == nil && user.Plan != "" {
+		if plan, ok := s.plans[user.Plan]; ok {
+			return plan
+		}
+	}
+	return s.plans[s.defaultPlan]
+}
+
+// PlanForSession returns the plan of the account that owns a session
+func (s *QuotaService) PlanForSession(sessionID string) (uint, domain.Plan, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return 0, domain.Plan{}, fmt.Errorf("failed to load session: %w", err)
+	}
+	return session.OwnerID, s.PlanFor(session.OwnerID), nil
+}
+
+// UseMessages counts n messages sent from a session against its owner's daily
+// limit, all or nothing, and checks the bulk recipient limit for n > 1
+func (s *QuotaService) UseMessages(sessionID string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	tenantID, plan, err := s.PlanForSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if n > 1 && plan.BulkRecipients > 0 && n > plan.BulkRecipients {
+		return &QuotaError{Limit: "bulk recipients", Max: plan.BulkRecipients, Remaining: plan.BulkRecipients}
+	}
+
+	now := time.Now().UTC()
+	day := now.Format("2006-01-02")
+	ok, err := s.usageRepo.Increment(tenantID, domain.UsageMessages, day, n, plan.MessagesPerDay)
+	if err != nil {
+		return fmt.Errorf("failed to count messages: %w", err)
+	}
+	if !ok {
+		used, _ := s.usageRepo.Get(tenantID, domain.UsageMessages, day)
+		return &QuotaError{
+			Limit:     "messages per day",
+			Max:       plan.MessagesPerDay,
+			Remaining: max(plan.MessagesPerDay-used, 0),
+			ResetAt:   nextUTCDay(now),
+		}
+	}
+	return nil
+}
+
+// CheckSessions returns a QuotaError when an account can't add another session
+func (s *QuotaService) CheckSessions(tenantID uint) error {
+	plan := s.PlanFor(tenantID)
+	if plan.MaxSessions <= 0 {
+		return nil
+	}
+	count, err := s.countSessions(tenantID)
+	if err != nil {
+		return err
+	}
+	return s.CheckSessionCount(tenantID, count)
+}
+
+// CheckSessionCount returns a QuotaError when an account with count sessions
+// can't add another one. Creating sessions checks this while holding a lock,
+// CheckSessions only lets requests fail early.
+func (s *QuotaService) CheckSessionCount(tenantID uint, count int) error {
+	plan := s.PlanFor(tenantID)
+	if plan.MaxSessions > 0 && count >= plan.MaxSessions {
+		return &QuotaError{Limit: "sessions", Max: plan.MaxSessions}
+	}
+	return nil
+}
+
+// UsageReport is an account's plan and what it used so far
+type UsageReport struct {
+	Plan               domain.Plan `json:"plan"`
+	Sessions           int         `json:"sessions"`
+	MessagesToday      int         `json:"messagesToday"`
+	MessagesResetAt    time.Time   `json:"messagesResetAt"`
+	RequestsLastMinute int         `json:"requestsLastMinute"`
+}
+
+// Usage reports an account's plan and what it used today
+func (s *QuotaService) Usage(tenantID uint) (*UsageReport, error) {
+	plan := s.PlanFor(tenantID)
+	now := time.Now().UTC()
+	messages, err := s.usageRepo.Get(tenantID, domain.UsageMessages, now.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load usage: %w", err)
+	}
+	sessions, err := s.countSessions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &UsageReport{
+		Plan:               plan,
+		Sessions:           sessions,
+		MessagesToday:      messages,
+		MessagesResetAt:    nextUTCDay(now),
+		RequestsLastMinute: s.requestCount(fmt.Sprintf("tenant:%d", tenantID)),
+	}, nil
+}
+
+// AllowRequest counts an API request of an account against its plan
+func (s *QuotaService) AllowRequest(tenantID uint) (RateLimitStatus, error) {
+	return s.Allow(fmt.Sprintf("tenant:%d", tenantID), s.PlanFor(tenantID).RequestsPerMinute)
+}
+
+// Allow counts a request under key in a one minute window. A limit of 0 or
+// less allows everything.
+func (s *QuotaService) Allow(key string, limit int) (RateLimitStatus, error) {
+	if limit <= 0 {
+		return RateLimitStatus{}, nil
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window, ok := s.windows[key]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		if len(s.windows) > 10000 {
+			s.sweepWindows(now)
+		}
+		window = &rateWindow{start: now}
+		s.windows[key] = window
+	}
+
+	status := RateLimitStatus{Max: limit, ResetAt: window.start.Add(time.Minute)}
+	if window.count >= limit {
+		return status, &QuotaError{Limit: "requests per minute", Max: limit, ResetAt: status.ResetAt}
+	}
+	window.count++
+	status.Remaining = limit - window.count
+	return status, nil
+}
+
+// CleanupUsage deletes daily counters past the retention period
+func (s *QuotaService) CleanupUsage() {
+	before := time.Now().UTC().AddDate(0, 0, -usageRetentionDays).Format("2006-01-02")
+	deleted, err := s.usageRepo.DeleteBefore(before)
+	if err != nil {
+		log.Printf("Warning: failed to delete old usage counters: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🧹 Deleted %d usage counters older than %s", deleted, before)
+	}
+}
+
+func (s *QuotaService) requestCount(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if window, ok := s.windows[key]; ok && time.Since(window.start) < time.Minute {
+		return window.count
+	}
+	return 0
+}
+
+// sweepWindows drops expired windows, callers hold mu
+func (s *QuotaService) sweepWindows(now time.Time) {
+	for key, window := range s.windows {
+		if now.Sub(window.start) >= time.Minute {
+			delete(s.windows, key)
+		}
+	}
+}
+
+// countSessions counts an account's sessions, exported ones live elsewhere
+func (s *QuotaService) countSessions(tenantID uint) (int, error) {
+	sessions, err := s.sessionRepo.FindByOwner(tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	count := 0
+	for _, session := range sessions {
+		if session.Status != domain.SessionStatusExported {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func nextUTCDay(now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"gorm.io/gorm"
+)
+
+// DefaultSessionName is used when a user creates a session without naming it
+const DefaultSessionName = "default"
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrSessionForbidden = errors.New("session belongs to another user")
+	ErrSessionAmbiguous = errors.New("you have several sessions, specify which one with userId")
+)
+
+type SessionService struct {
+	sessionRepo repository.SessionRepository
+}
+
+func NewSessionService(sessionRepo repository.SessionRepository) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+	}
+}
+
+// GetOrCreate returns the owner's session with the given name, creating it on
+// first use. Initializing the same session twice therefore reuses it. allowNew
+// can refuse creating a session, for example when a plan limit is reached.
+func (s *SessionService) GetOrCreate(ownerID uint, name string, allowNew func(ownerID uint, activeSessions int) error) (*domain.Session, bool, error) {
+	if ownerID == 0 {
+		return nil, false, fmt.Errorf("an authenticated user is required")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultSessionName
+	}
+	if len(name) > 100 {
+		return nil, false, fmt.Errorf("session name cannot be longer than 100 characters")
+	}
+
+	session, err := s.sessionRepo.FindByOwnerAndName(ownerID, name)
+	if err == nil {
+		return session, false, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	session = &domain.Session{
+		ID:      utils.GenerateID("session_"),
+		OwnerID: ownerID,
+		Name:    name,
+	}
+	if err := s.create(session, allowNew); err != nil {
+		// Lost a race with a concurrent init of the same name
+		if existing, findErr := s.sessionRepo.FindByOwnerAndName(ownerID, name); findErr == nil {
+			return existing, false, nil
+		}
+		if _, ok := err.(*QuotaError); ok {
+			return nil, false, err
+		}
+		return nil, false, fmt.Errorf("failed to create session: %w", err)
+	}
+	return session, true, nil
+}
+
+// create stores a new session, checking allowNew against its owner's sessions
+// under a lock so concurrent creates can't exceed a limit
+func (s *SessionService) create(session *domain.Session, allowNew func(ownerID uint, activeSessions int) error) error {
+	if allowNew == nil {
+		return s.sessionRepo.Create(session)
+	}
+	return s.sessionRepo.CreateChecked(session, func(activeSessions int) error {
+		return allowNew(session.OwnerID, activeSessions)
+	})
+}
+
+// Resolve returns the session the caller asked for after checking they own it,
+// unless anyOwner is set for admins. Without a session ID it falls back to the
+// caller's default or only session.
+func (s *SessionService) Resolve(ownerID uint, sessionID string, anyOwner bool) (*domain.Session, error) {
+	if sessionID == "" {
+		return s.resolveDefault(ownerID)
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	if session.OwnerID != ownerID && !anyOwner {
+		return nil, ErrSessionForbidden
+	}
+	return session, nil
+}
+
+func (s *SessionService) resolveDefault(ownerID uint) (*domain.Session, error) {
+	sessions, err := s.sessionRepo.FindByOwner(ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up sessions: %w", err)
+	}
+
+	switch len(sessions) {
+	case 0:
+		return nil, ErrSessionNotFound
+	case 1:
+		return &sessions[0], nil
+	}
+
+	for i := range sessions {
+		if sessions[i].Name == DefaultSessionName {
+			return &sessions[i], nil
+		}
+	}
+	return nil, ErrSessionAmbiguous
+}
+
+// Get returns a session by ID
+func (s *SessionService) Get(sessionID string) (*domain.Session, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrSessionNotFound
+	}
+	return session, err
+}
+
+// ListForOwner returns all sessions of a user
+func (s *SessionService) ListForOwner(ownerID uint) ([]domain.Session, error) {
+	return s.sessionRepo.FindByOwner(ownerID)
+}
+
+// GetStatusHistory returns the most recent status changes of a session
+func (s *SessionService) GetStatusHistory(sessionID string, limit int) ([]domain.SessionStatusChange, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return s.sessionRepo.FindStatusHistory(sessionID, limit)
+}
+
+// LoadSessions implements whatsmeow_client.SessionStore
+func (s *SessionService) LoadSessions() ([]whatsmeow_client.SessionRecord, error) {
+	sessions, err := s.sessionRepo.FindWithDevice()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]whatsmeow_client.SessionRecord, 0, len(sessions))
+	for _, session := range sessions {
+		records = append(records, whatsmeow_client.SessionRecord{
+			UserID:    session.ID,
+			DeviceJID: session.DeviceJID,
+		})
+	}
+	return records, nil
+}
+
+// RecordStatus implements whatsmeow_client.SessionStore
+func (s *SessionService) RecordStatus(sessionID string, status whatsmeow_client.SessionStatus, reason, deviceJID string) error {
+	reason = utils.TruncateRunes(reason, 255)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":            string(status),
+		"status_reason":     reason,
+		"status_changed_at": now,
+	}
+
+	switch {
+	case status == whatsmeow_client.StatusLoggedOut:
+		// The device was unlinked and can't be restored anymore
+		updates["device_jid"] = ""
+		deviceJID = ""
+	case deviceJID != "":
+		updates["device_jid"] = deviceJID
+	}
+	if status == whatsmeow_client.StatusReady {
+		updates["last_connected_at"] = now
+	}
+
+	return s.sessionRepo.RecordStatus(sessionID, updates, &domain.SessionStatusChange{
+		SessionID: sessionID,
+		Status:    string(status),
+		Reason:    reason,
+		DeviceJID: deviceJID,
+	})
+}
+
+// legacySessionMetadata is an entry of the metadata.json file sessions used to be tracked in
+type legacySessionMetadata struct {
+	UserID       string    `json:"user_id"`
+	PhoneJID     string    `json:"phone_jid"`
+	LastActivity time.Time `json:"last_activity"`
+	Status       string    `json:"status"`
+}
+
+// legacyMetadataPath is where older versions always wrote metadata.json
+const legacyMetadataPath = "./sessions/metadata.json"
+
+// ImportLegacyMetadata moves sessions from the metadata.json file at path, and
+// at the path older versions always used, into the sessions table. The files
+// are renamed so the import only runs once. They didn't record owners, so
+// imported sessions belong to ownerID (none when 0).
+func (s *SessionService) ImportLegacyMetadata(path string, ownerID uint) (int, error) {
+	imported, err := s.importLegacyMetadata(path, ownerID)
+	if err != nil || filepath.Clean(path) == filepath.Clean(legacyMetadataPath) {
+		return imported, err
+	}
+	more, err := s.importLegacyMetadata(legacyMetadataPath, ownerID)
+	return imported + more, err
+}
+
+// AssignUnownedSessions gives sessions imported without an owner to ownerID
+func (s *SessionService) AssignUnownedSessions(ownerID uint) (int64, error) {
+	return s.sessionRepo.AssignUnowned(ownerID)
+}
+
+func (s *SessionService) importLegacyMetadata(path string, ownerID uint) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []legacySessionMetadata
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	imported := 0
+	for _, entry := range entries {
+		if entry.UserID == "" {
+			continue
+		}
+
+		if _, err := s.sessionRepo.FindByID(entry.UserID); err == nil {
+			continue // Already known
+		} else if err != gorm.ErrRecordNotFound {
+			return imported, fmt.Errorf("failed to look up session %s: %w", entry.UserID, err)
+		}
+
+		name := utils.TruncateRunes(entry.UserID, 100)
+		changedAt := entry.LastActivity
+		session := &domain.Session{
+			ID:              entry.UserID,
+			OwnerID:         ownerID,
+			Name:            name,
+			DeviceJID:       entry.PhoneJID,
+			Status:          entry.Status,
+			StatusChangedAt: &changedAt,
+		}
+		if session.Status == "" {
+			session.Status = string(whatsmeow_client.StatusNotInitialized)
+		}
+
+		if err := s.sessionRepo.Create(session); err != nil {
+			return imported, fmt.Errorf("failed to import session %s: %w", entry.UserID, err)
+		}
+		imported++
+	}
+
+	if err := os.Rename(path, path+".imported"); err != nil {
+		log.Printf("Warning: failed to rename %s after import: %v", path, err)
+	}
+	return imported, nil
+}
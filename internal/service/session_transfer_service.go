@@ -0,0 +1,325 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/domain"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/repository"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/internal/utils"
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/gorm"
+)
+
+// Session bundles are JSON files holding the encrypted export. The key is
+// derived from a passphrase with scrypt.
+const (
+	sessionBundleFormat  = "whatsapp-keepconnect-session"
+	sessionBundleVersion = 1
+	minPassphraseLength  = 12
+)
+
+var (
+	ErrWeakPassphrase      = fmt.Errorf("the passphrase must be at least %d characters long", minPassphraseLength)
+	ErrBadBundle           = errors.New("the bundle can't be decrypted, check the passphrase")
+	ErrSessionNotLinked    = errors.New("the session has no linked device to export")
+	ErrSessionAlreadyHere  = errors.New("this WhatsApp account is already active on this server")
+	ErrSessionNameConflict = errors.New("you already have a session with this name, choose another name")
+)
+
+// SessionBundle is the encrypted file a session is exported to
+type SessionBundle struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// sessionBundlePayload is the decrypted content of a bundle
+type sessionBundlePayload struct {
+	SessionID       string                         `json:"session_id"`
+	Name            string                         `json:"name"`
+	DeviceJID       string                         `json:"device_jid"`
+	LastConnectedAt *time.Time                     `json:"last_connected_at"`
+	ExportedAt      time.Time                      `json:"exported_at"`
+	Device          *whatsmeow_client.DeviceExport `json:"device"`
+}
+
+// SessionTransferService moves sessions between servers. An exported session
+// is disconnected and never restored here again, so the account is only live
+// on the server it was imported into.
+type SessionTransferService struct {
+	sessionRepo    repository.SessionRepository
+	sessionService *SessionService
+	waManager      *whatsmeow_client.Manager
+}
+
+func NewSessionTransferService(sessionRepo repository.SessionRepository, sessionService *SessionService, waManager *whatsmeow_client.Manager) *SessionTransferService {
+	return &SessionTransferService{
+		sessionRepo:    sessionRepo,
+		sessionService: sessionService,
+		waManager:      waManager,
+	}
+}
+
+// Export disconnects a session, marks it as exported and returns its device
+// and metadata as an encrypted bundle
+func (s *SessionTransferService) Export(session *domain.Session, passphrase string) ([]byte, error) {
+	if len(passphrase) < minPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	if session.DeviceJID == "" {
+		return nil, ErrSessionNotLinked
+	}
+
+	// Signal state changes with every message, so the session must be offline
+	// before it is read and stay offline afterwards
+	s.waManager.ReleaseSession(session.ID)
+	if err := s.sessionService.RecordStatus(session.ID, whatsmeow_client.StatusExported, "exported to another server", session.DeviceJID); err != nil {
+		return nil, fmt.Errorf("failed to mark session as exported: %w", err)
+	}
+
+	bundle, err := s.export(session, passphrase)
+	if err != nil {
+		s.reactivate(session, fmt.Sprintf("export failed: %v", err))
+		return nil, err
+	}
+
+	log.Printf("📤 Exported session %s (%s)", session.ID, session.DeviceJID)
+	return bundle, nil
+}
+
+// ExportOffline exports a session from a maintenance command. A running server
+// can't be told to disconnect it, so sessions whose last status isn't final
+// are refused unless force confirms the server is stopped.
+func (s *SessionTransferService) ExportOffline(sessionID, passphrase string, force bool) ([]byte, error) {
+	session, err := s.sessionService.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) < minPassphraseLength {
+		return nil, ErrWeakPassphrase
+	}
+	if session.DeviceJID == "" {
+		return nil, ErrSessionNotLinked
+	}
+	if !force && session.Status != domain.SessionStatusExported && session.Status != string(whatsmeow_client.StatusNotInitialized) {
+		return nil, fmt.Errorf("session %s was last %s, export it through the API or pass -force if the server is stopped", session.ID, session.Status)
+	}
+
+	if err := s.sessionService.RecordStatus(session.ID, whatsmeow_client.StatusExported, "exported to another server", session.DeviceJID); err != nil {
+		return nil, fmt.Errorf("failed to mark session as exported: %w", err)
+	}
+	return s.export(session, passphrase)
+}
+
+func (s *SessionTransferService) export(session *domain.Session, passphrase string) ([]byte, error) {
+	device, err := s.waManager.ExportDevice(session.DeviceJID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export device: %w", err)
+	}
+
+	payload, err := json.Marshal(sessionBundlePayload{
+		SessionID:       session.ID,
+		Name:            session.Name,
+		DeviceJID:       session.DeviceJID,
+		LastConnectedAt: session.LastConnectedAt,
+		ExportedAt:      time.Now(),
+		Device:          device,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sealBundle(payload, passphrase)
+}
+
+// reactivate undoes a failed export
+func (s *SessionTransferService) reactivate(session *domain.Session, reason string) {
+	if err := s.sessionService.RecordStatus(session.ID, whatsmeow_client.StatusDisconnected, reason, session.DeviceJID); err != nil {
+		log.Printf("Warning: failed to record status of session %s: %v", session.ID, err)
+	}
+	record := whatsmeow_client.SessionRecord{UserID: session.ID, DeviceJID: session.DeviceJID}
+	if err := s.waManager.StartSession(record); err != nil {
+		log.Printf("Warning: failed to restart session %s: %v", session.ID, err)
+	}
+}
+
+// Import decrypts a bundle and adds the session to the owner's sessions. The
+// account must not be active on this server already; a session exported from
+// here by the same owner is replaced, which is how an export is undone. When
+// start is set the session connects right away. New sessions must be accepted
+// by allowNew.
+func (s *SessionTransferService) Import(ownerID uint, data []byte, passphrase, name string, start bool, allowNew func(ownerID uint, activeSessions int) error) (*domain.Session, error) {
+	payload, err := openBundle(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Device == nil || payload.DeviceJID == "" || payload.Device.JID != payload.DeviceJID {
+		return nil, fmt.Errorf("the bundle doesn't contain a device")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = payload.Name
+	}
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("session name must be between 1 and 100 characters")
+	}
+
+	// The account may only be live in one place
+	existing, err := s.sessionRepo.FindByDeviceJID(payload.DeviceJID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up sessions: %w", err)
+	}
+	var previous *domain.Session
+	for i := range existing {
+		if existing[i].Status != domain.SessionStatusExported || existing[i].OwnerID != ownerID {
+			return nil, ErrSessionAlreadyHere
+		}
+		previous = &existing[i]
+	}
+
+	if named, err := s.sessionRepo.FindByOwnerAndName(ownerID, name); err == nil {
+		if previous == nil || named.ID != previous.ID {
+			return nil, ErrSessionNameConflict
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	// A new session is created first, within the session limit, and only
+	// linked to the device once that is imported
+	session := previous
+	if session == nil {
+		// Keep the session ID when it is free, so data keyed by it still matches
+		sessionID := payload.SessionID
+		if _, err := s.sessionRepo.FindByID(sessionID); sessionID == "" || err != gorm.ErrRecordNotFound {
+			sessionID = utils.GenerateID("session_")
+		}
+		session = &domain.Session{
+			ID:              sessionID,
+			OwnerID:         ownerID,
+			Name:            name,
+			Status:          string(whatsmeow_client.StatusNotInitialized),
+			LastConnectedAt: payload.LastConnectedAt,
+		}
+		if err := s.sessionService.create(session, allowNew); err != nil {
+			if _, ok := err.(*QuotaError); ok {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to create session: %w", err)
+		}
+	}
+
+	if err := s.waManager.ImportDevice(payload.Device, previous != nil); err != nil {
+		if previous == nil {
+			if delErr := s.sessionRepo.Delete(session.ID); delErr != nil {
+				log.Printf("Warning: failed to remove session %s after a failed import: %v", session.ID, delErr)
+			}
+		}
+		if errors.Is(err, whatsmeow_client.ErrDeviceExists) {
+			return nil, ErrSessionAlreadyHere
+		}
+		return nil, fmt.Errorf("failed to import device: %w", err)
+	}
+
+	if err := s.sessionService.RecordStatus(session.ID, whatsmeow_client.StatusNotInitialized, "imported from another server", payload.DeviceJID); err != nil {
+		return nil, fmt.Errorf("failed to record session status: %w", err)
+	}
+	session.Status = string(whatsmeow_client.StatusNotInitialized)
+
+	if start {
+		record := whatsmeow_client.SessionRecord{UserID: session.ID, DeviceJID: payload.DeviceJID}
+		if err := s.waManager.StartSession(record); err != nil {
+			return nil, fmt.Errorf("session imported but failed to start: %w", err)
+		}
+	}
+
+	log.Printf("📥 Imported session %s (%s) for user %d", session.ID, payload.DeviceJID, ownerID)
+	return session, nil
+}
+
+// sealBundle encrypts a payload with a key derived from the passphrase
+func sealBundle(payload []byte, passphrase string) ([]byte, error) {
+	bundle := SessionBundle{
+		Format:  sessionBundleFormat,
+		Version: sessionBundleVersion,
+		KDF:     "scrypt",
+		N:       1 << 15,
+		R:       8,
+		P:       1,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(bundle.Salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := bundleCipher(&bundle, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	bundle.Ciphertext = aead.Seal(nonce, nonce, payload, []byte(sessionBundleFormat))
+
+	return json.Marshal(bundle)
+}
+
+func openBundle(data []byte, passphrase string) (*sessionBundlePayload, error) {
+	var bundle SessionBundle
+	if err := json.Unmarshal(data, &bundle); err != nil || bundle.Format != sessionBundleFormat {
+		return nil, fmt.Errorf("not a session bundle")
+	}
+	if bundle.Version != sessionBundleVersion || bundle.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported session bundle version %d", bundle.Version)
+	}
+	// Refuse parameters that would make key derivation hang
+	if bundle.N > 1<<20 || bundle.R > 32 || bundle.P > 16 {
+		return nil, fmt.Errorf("unsupported session bundle parameters")
+	}
+
+	aead, err := bundleCipher(&bundle, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundle.Ciphertext) < aead.NonceSize() {
+		return nil, ErrBadBundle
+	}
+	nonce, ciphertext := bundle.Ciphertext[:aead.NonceSize()], bundle.Ciphertext[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, []byte(sessionBundleFormat))
+	if err != nil {
+		return nil, ErrBadBundle
+	}
+
+	var decoded sessionBundlePayload
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid session bundle: %w", err)
+	}
+	return &decoded, nil
+}
+
+func bundleCipher(bundle *SessionBundle, passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), bundle.Salt, bundle.N, bundle.R, bundle.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session bundle parameters: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cm-shreyansh/whatsapp-keepconnect-go/pkg/whatsmeow_client"
+)
+
+func TestSessionBundle(t *testing.T) {
+	passphrase := "correct horse battery staple"
+	noiseKey := bytes.Repeat([]byte{0x42}, 32)
+	payload, err := json.Marshal(sessionBundlePayload{
+		SessionID:  "session_1",
+		Name:       "Support",
+		DeviceJID:  "919876543210.0:1@s.whatsapp.net",
+		ExportedAt: time.Unix(1700000000, 0).UTC(),
+		Device: &whatsmeow_client.DeviceExport{
+			JID: "919876543210.0:1@s.whatsapp.net",
+			Tables: map[string][]map[string]whatsmeow_client.ExportValue{
+				"whatsmeow_device": {{"noise_key": {Kind: "bytes", Bytes: noiseKey}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := sealBundle(payload, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("session_1")) {
+		t.Fatal("the bundle contains the payload in plaintext")
+	}
+
+	// edit changes one field of the sealed bundle
+	edit := func(change func(*SessionBundle)) []byte {
+		var bundle SessionBundle
+		if err := json.Unmarshal(sealed, &bundle); err != nil {
+			t.Fatal(err)
+		}
+		bundle.Ciphertext = append([]byte{}, bundle.Ciphertext...)
+		change(&bundle)
+		data, err := json.Marshal(bundle)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    error
+		wantMsg    string
+	}{
+		{name: "round trip", data: sealed, passphrase: passphrase},
+		{name: "wrong passphrase", data: sealed, passphrase: "incorrect horse battery staple", wantErr: ErrBadBundle},
+		{name: "tampered ciphertext", data: edit(func(b *SessionBundle) { b.Ciphertext[len(b.Ciphertext)-1] ^= 1 }), passphrase: passphrase, wantErr: ErrBadBundle},
+		{name: "other salt", data: edit(func(b *SessionBundle) { b.Salt[0] ^= 1 }), passphrase: passphrase, wantErr: ErrBadBundle},
+		{name: "truncated ciphertext", data: edit(func(b *SessionBundle) { b.Ciphertext = b.Ciphertext[:4] }), passphrase: passphrase, wantErr: ErrBadBundle},
+		{name: "not a bundle", data: []byte(`{"hello":"world"}`), passphrase: passphrase, wantMsg: "not a session bundle"},
+		{name: "not JSON", data: []byte("PK\x03\x04"), passphrase: passphrase, wantMsg: "not a session bundle"},
+		{name: "newer version", data: edit(func(b *SessionBundle) { b.Version = 2 }), passphrase: passphrase, wantMsg: "unsupported session bundle version"},
+		{name: "other KDF", data: edit(func(b *SessionBundle) { b.KDF = "argon2id" }), passphrase: passphrase, wantMsg: "unsupported session bundle version"},
+		{name: "oversized scrypt cost", data: edit(func(b *SessionBundle) { b.N = 1 << 30 }), passphrase: passphrase, wantMsg: "unsupported session bundle parameters"},
+		{name: "invalid scrypt cost", data: edit(func(b *SessionBundle) { b.N = 1000 }), passphrase: passphrase, wantMsg: "invalid session bundle parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := openBundle(tt.data, tt.passphrase)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SessionID != "session_1" || got.Name != "Support" || !got.ExportedAt.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("opened %+v", got)
+			}
+			rows := got.Device.Tables["whatsmeow_device"]
+			if len(rows) != 1 || !bytes.Equal(rows[0]["noise_key"].Bytes, noiseKey) {
+				t.Error("the device keys didn't survive the round trip")
+			}
+		})
+	}
+
+	t.Run("fresh salt and nonce", func(t *testing.T) {
+		again, err := sealBundle(payload, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(again, sealed) {
+			t.Fatal("sealing twice gave the same bundle")
+		}
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // GenerateID generates a unique ID with optional prefix
@@ -81,3 +83,66 @@ func ValidateRequired(fields map[string]string) error {
 
 	return nil
 }
+
+// BuildVCard builds a vCard 3.0 string for sharing a contact on WhatsApp
+func BuildVCard(name, phone, organization string) string {
+	waID := strings.TrimSuffix(FormatPhoneNumber(phone), "@s.whatsapp.net")
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\n")
+	b.WriteString("VERSION:3.0\n")
+	b.WriteString("FN:" + escapeVCardText(name) + "\n")
+	if organization != "" {
+		b.WriteString("ORG:" + escapeVCardText(organization) + ";\n")
+	}
+	b.WriteString("TEL;type=CELL;type=VOICE;waid=" + waID + ":+" + waID + "\n")
+	b.WriteString("END:VCARD")
+	return b.String()
+}
+
+// vCardEscaper escapes text values as RFC 6350 requires, so names can't break
+// out of their property or add new ones
+var vCardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	";", `\;`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeVCardText(value string) string {
+	return vCardEscaper.Replace(value)
+}
+
+// TruncateRunes shortens text to at most max characters without splitting a
+// multi-byte character, for VARCHAR columns
+func TruncateRunes(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max])
+}
+
+const (
+	defaultTypingSpeed = 15 // Characters per second
+	minTypingDelay     = 1 * time.Second
+	maxTypingDelay     = 8 * time.Second
+)
+
+// TypingDelay returns how long a human would take to type a reply,
+// clamped so short replies still show "typing…" and long ones don't stall
+func TypingDelay(text string, charsPerSecond int) time.Duration {
+	if charsPerSecond <= 0 {
+		charsPerSecond = defaultTypingSpeed
+	}
+
+	delay := time.Duration(utf8.RuneCountInString(text)) * time.Second / time.Duration(charsPerSecond)
+	if delay < minTypingDelay {
+		return minTypingDelay
+	}
+	if delay > maxTypingDelay {
+		return maxTypingDelay
+	}
+	return delay
+}
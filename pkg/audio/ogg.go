@@ -0,0 +1,36 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// opusGranuleRate is the fixed granule position clock of Opus streams
+const opusGranuleRate = 48000
+
+// OggDuration reads the duration of an OGG/Opus stream from the granule
+// position of its last page, without decoding any audio
+func OggDuration(data []byte) (uint32, error) {
+	if !IsOggOpus(data) {
+		return 0, errors.New("not an OGG/Opus stream")
+	}
+
+	// The last page header holds the total number of samples
+	idx := bytes.LastIndex(data, []byte("OggS"))
+	if idx < 0 || len(data) < idx+14 {
+		return 0, errors.New("truncated OGG page")
+	}
+
+	granule := binary.LittleEndian.Uint64(data[idx+6 : idx+14])
+
+	// Subtract the encoder pre-skip from the OpusHead header when present
+	if head := bytes.Index(data, []byte("OpusHead")); head >= 0 && len(data) >= head+12 {
+		preSkip := uint64(binary.LittleEndian.Uint16(data[head+10 : head+12]))
+		if granule > preSkip {
+			granule -= preSkip
+		}
+	}
+
+	return uint32((granule + opusGranuleRate - 1) / opusGranuleRate), nil
+}
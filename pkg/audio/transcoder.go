@@ -0,0 +1,172 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// VoiceNoteMimeType is the only format WhatsApp accepts for push-to-talk voice notes
+const VoiceNoteMimeType = "audio/ogg; codecs=opus"
+
+// WaveformSamples is the number of bars WhatsApp draws for a voice note
+const WaveformSamples = 64
+
+// ErrTranscoderUnavailable is returned when audio needs converting but no transcoder is configured
+var ErrTranscoderUnavailable = errors.New("audio transcoding unavailable: install ffmpeg or set FFMPEG_PATH")
+
+// Info holds the metadata WhatsApp shows for an audio message
+type Info struct {
+	Seconds  uint32
+	Waveform []byte
+}
+
+// Transcoder converts audio into formats WhatsApp can play and extracts its metadata
+type Transcoder interface {
+	// ToOggOpus converts any supported audio input (MP3, WAV, AAC, ...) to OGG/Opus
+	ToOggOpus(ctx context.Context, data []byte) ([]byte, error)
+	// Analyze returns the duration and a WaveformSamples-long waveform with values 0-100
+	Analyze(ctx context.Context, data []byte) (*Info, error)
+}
+
+// NewTranscoder returns an ffmpeg-backed transcoder if the binary can be found,
+// otherwise a transcoder that fails every conversion with ErrTranscoderUnavailable
+func NewTranscoder(ffmpegPath string) Transcoder {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	path, err := exec.LookPath(ffmpegPath)
+	if err != nil {
+		return unavailableTranscoder{}
+	}
+	return &FFmpegTranscoder{path: path}
+}
+
+// IsWhatsAppPlayable reports whether WhatsApp can play this audio format as-is
+func IsWhatsAppPlayable(mimeType string) bool {
+	base := strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0])
+	switch base {
+	case "audio/ogg", "audio/mpeg", "audio/mp4", "audio/aac", "audio/amr":
+		return true
+	}
+	return false
+}
+
+// IsOggOpus reports whether the data is an OGG container carrying Opus audio
+func IsOggOpus(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("OggS")) && bytes.Contains(data[:min(len(data), 512)], []byte("OpusHead"))
+}
+
+// FFmpegTranscoder shells out to ffmpeg for conversion and decoding
+type FFmpegTranscoder struct {
+	path string
+}
+
+func (t *FFmpegTranscoder) ToOggOpus(ctx context.Context, data []byte) ([]byte, error) {
+	return t.run(ctx, data,
+		"-i", "pipe:0",
+		"-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", "32k", "-application", "voip",
+		"-f", "ogg", "pipe:1",
+	)
+}
+
+// analyzeSampleRate is low on purpose, the waveform only needs a coarse envelope
+const analyzeSampleRate = 8000
+
+func (t *FFmpegTranscoder) Analyze(ctx context.Context, data []byte) (*Info, error) {
+	pcm, err := t.run(ctx, data,
+		"-i", "pipe:0",
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(analyzeSampleRate),
+		"-f", "s16le", "pipe:1",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]int16, len(pcm)/2)
+	if err := binary.Read(bytes.NewReader(pcm[:len(samples)*2]), binary.LittleEndian, samples); err != nil {
+		return nil, fmt.Errorf("failed to read decoded audio: %w", err)
+	}
+
+	seconds := (len(samples) + analyzeSampleRate - 1) / analyzeSampleRate
+	return &Info{
+		Seconds:  uint32(seconds),
+		Waveform: buildWaveform(samples),
+	}, nil
+}
+
+func (t *FFmpegTranscoder) run(ctx context.Context, input []byte, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, t.path, append([]string{"-hide_banner", "-loglevel", "error"}, args...)...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// buildWaveform averages the absolute amplitude into WaveformSamples buckets scaled to 0-100
+func buildWaveform(samples []int16) []byte {
+	waveform := make([]byte, WaveformSamples)
+	if len(samples) == 0 {
+		return waveform
+	}
+
+	levels := make([]float64, WaveformSamples)
+	peak := 0.0
+	for i := range levels {
+		start := i * len(samples) / WaveformSamples
+		end := (i + 1) * len(samples) / WaveformSamples
+		if end <= start {
+			continue
+		}
+		sum := 0.0
+		for _, s := range samples[start:end] {
+			if s < 0 {
+				sum -= float64(s)
+			} else {
+				sum += float64(s)
+			}
+		}
+		levels[i] = sum / float64(end-start)
+		if levels[i] > peak {
+			peak = levels[i]
+		}
+	}
+
+	if peak == 0 {
+		return waveform
+	}
+	for i, level := range levels {
+		waveform[i] = byte(level / peak * 100)
+	}
+	return waveform
+}
+
+// unavailableTranscoder is used when ffmpeg is not installed
+type unavailableTranscoder struct{}
+
+func (unavailableTranscoder) ToOggOpus(ctx context.Context, data []byte) ([]byte, error) {
+	return nil, ErrTranscoderUnavailable
+}
+
+// Analyze can still read the duration of OGG files without decoding them
+func (unavailableTranscoder) Analyze(ctx context.Context, data []byte) (*Info, error) {
+	seconds, err := OggDuration(data)
+	if err != nil {
+		return nil, ErrTranscoderUnavailable
+	}
+	return &Info{Seconds: seconds}, nil
+}
@@ -0,0 +1,226 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mau.fi/whatsmeow/appstate"
+	waCommon "go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageRef identifies a message well enough to acknowledge it or reference it in app state patches
+type messageRef struct {
+	ID        types.MessageID
+	Sender    types.JID
+	FromMe    bool
+	Timestamp time.Time
+}
+
+// Limits of the per-session chat state kept in memory. Chats with the oldest
+// last message are forgotten first, and of a chat only the newest unread
+// messages are kept; receipts for those still move the read marker forward.
+const (
+	maxTrackedChats  = 1000
+	maxUnreadPerChat = 200
+)
+
+// trackMessage remembers the latest message of each chat and incoming
+// messages that haven't been read yet, so chats can be marked read later
+func (cd *ClientData) trackMessage(evt *events.Message) {
+	chat := evt.Info.Chat.ToNonAD()
+	ref := messageRef{
+		ID:        evt.Info.ID,
+		Sender:    evt.Info.Sender.ToNonAD(),
+		FromMe:    evt.Info.IsFromMe,
+		Timestamp: evt.Info.Timestamp,
+	}
+
+	cd.chatMu.Lock()
+	defer cd.chatMu.Unlock()
+
+	if cd.lastMessages == nil {
+		cd.lastMessages = make(map[types.JID]messageRef)
+		cd.unread = make(map[types.JID][]messageRef)
+	}
+
+	last, ok := cd.lastMessages[chat]
+	if !ok && len(cd.lastMessages) >= maxTrackedChats {
+		cd.forgetOldestChat()
+	}
+	if !ok || !ref.Timestamp.Before(last.Timestamp) {
+		cd.lastMessages[chat] = ref
+	}
+
+	// Our own messages don't read the chat, only read receipts do
+	if !evt.Info.IsFromMe {
+		unread := append(cd.unread[chat], ref)
+		if len(unread) > maxUnreadPerChat {
+			unread = append(unread[:0:0], unread[len(unread)-maxUnreadPerChat:]...)
+		}
+		cd.unread[chat] = unread
+	}
+}
+
+// forgetOldestChat drops the chat with the oldest last message. Callers hold chatMu.
+func (cd *ClientData) forgetOldestChat() {
+	var oldest types.JID
+	var oldestAt time.Time
+	for chat, last := range cd.lastMessages {
+		if oldest.IsEmpty() || last.Timestamp.Before(oldestAt) {
+			oldest, oldestAt = chat, last.Timestamp
+		}
+	}
+	delete(cd.lastMessages, oldest)
+	delete(cd.unread, oldest)
+}
+
+// trackReceipt clears pending unread messages that were read on another device
+func (cd *ClientData) trackReceipt(evt *events.Receipt) {
+	if !evt.IsFromMe || (evt.Type != types.ReceiptTypeRead && evt.Type != types.ReceiptTypeReadSelf) {
+		return
+	}
+	cd.removeUnread(evt.Chat.ToNonAD(), evt.MessageIDs)
+}
+
+func (cd *ClientData) removeUnread(chat types.JID, ids []types.MessageID) {
+	read := make(map[types.MessageID]bool, len(ids))
+	for _, id := range ids {
+		read[id] = true
+	}
+
+	cd.chatMu.Lock()
+	defer cd.chatMu.Unlock()
+
+	remaining := cd.unread[chat][:0]
+	for _, ref := range cd.unread[chat] {
+		if !read[ref.ID] {
+			remaining = append(remaining, ref)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(cd.unread, chat)
+	} else {
+		cd.unread[chat] = remaining
+	}
+}
+
+// UnreadCount returns the number of incoming messages in a chat not yet marked read
+func (cd *ClientData) UnreadCount(chat types.JID) int {
+	cd.chatMu.Lock()
+	defer cd.chatMu.Unlock()
+	return len(cd.unread[chat.ToNonAD()])
+}
+
+func (cd *ClientData) lastMessage(chat types.JID) (time.Time, *waCommon.MessageKey) {
+	cd.chatMu.Lock()
+	defer cd.chatMu.Unlock()
+
+	last, ok := cd.lastMessages[chat.ToNonAD()]
+	if !ok {
+		return time.Time{}, nil
+	}
+
+	key := &waCommon.MessageKey{
+		RemoteJID: proto.String(chat.String()),
+		FromMe:    proto.Bool(last.FromMe),
+		ID:        proto.String(last.ID),
+	}
+	if chat.Server == types.GroupServer && !last.FromMe {
+		key.Participant = proto.String(last.Sender.String())
+	}
+	return last.Timestamp, key
+}
+
+// MarkMessagesRead sends read receipts (blue ticks) for specific messages.
+// In groups the sender must be set to the participant who sent them.
+func (cd *ClientData) MarkMessagesRead(chat, sender types.JID, ids []types.MessageID, timestamp time.Time) error {
+	if err := cd.Client.MarkRead(context.Background(), ids, timestamp, chat, sender); err != nil {
+		return err
+	}
+
+	// WhatsApp doesn't echo our own receipts, so report them like ones from another device
+	receipt := &events.Receipt{
+		MessageSource: types.MessageSource{
+			Chat:     chat,
+			Sender:   sender,
+			IsFromMe: true,
+			IsGroup:  chat.Server == types.GroupServer,
+		},
+		MessageIDs: ids,
+		Timestamp:  time.Now(),
+		Type:       types.ReceiptTypeRead,
+	}
+	if cd.emit != nil {
+		cd.emit(receipt)
+	} else {
+		cd.trackReceipt(receipt)
+	}
+	return nil
+}
+
+// MarkChatRead sends read receipts for every pending incoming message in a chat
+// and syncs the read state to the account's other devices. It returns how many
+// messages were acknowledged.
+func (cd *ClientData) MarkChatRead(chat types.JID) (int, error) {
+	chat = chat.ToNonAD()
+
+	cd.chatMu.Lock()
+	pending := append([]messageRef(nil), cd.unread[chat]...)
+	cd.chatMu.Unlock()
+
+	// Receipts can only batch messages from the same sender
+	bySender := make(map[types.JID][]types.MessageID)
+	latest := make(map[types.JID]time.Time)
+	for _, ref := range pending {
+		bySender[ref.Sender] = append(bySender[ref.Sender], ref.ID)
+		if ref.Timestamp.After(latest[ref.Sender]) {
+			latest[ref.Sender] = ref.Timestamp
+		}
+	}
+
+	marked := 0
+	for sender, ids := range bySender {
+		receiptSender := sender
+		if chat.Server != types.GroupServer {
+			receiptSender = types.EmptyJID
+		}
+		if err := cd.MarkMessagesRead(chat, receiptSender, ids, latest[sender]); err != nil {
+			return marked, fmt.Errorf("failed to mark messages read: %w", err)
+		}
+		marked += len(ids)
+	}
+
+	ts, key := cd.lastMessage(chat)
+	if err := cd.Client.SendAppState(context.Background(), appstate.BuildMarkChatAsRead(chat, true, ts, key)); err != nil {
+		log.Printf("Warning: failed to sync read state for chat %s: %v", chat, err)
+	}
+
+	return marked, nil
+}
+
+// MarkChatUnread flags a chat as unread on all of the account's devices
+func (cd *ClientData) MarkChatUnread(chat types.JID) error {
+	ts, key := cd.lastMessage(chat)
+	return cd.Client.SendAppState(context.Background(), appstate.BuildMarkChatAsRead(chat, false, ts, key))
+}
+
+// ArchiveChat archives or unarchives a chat. Archiving also unpins it.
+func (cd *ClientData) ArchiveChat(chat types.JID, archive bool) error {
+	ts, key := cd.lastMessage(chat)
+	return cd.Client.SendAppState(context.Background(), appstate.BuildArchive(chat, archive, ts, key))
+}
+
+// PinChat pins or unpins a chat
+func (cd *ClientData) PinChat(chat types.JID, pin bool) error {
+	return cd.Client.SendAppState(context.Background(), appstate.BuildPin(chat, pin))
+}
+
+// MuteChat mutes a chat for the given duration (zero mutes forever) or unmutes it
+func (cd *ClientData) MuteChat(chat types.JID, mute bool, duration time.Duration) error {
+	return cd.Client.SendAppState(context.Background(), appstate.BuildMute(chat, mute, duration))
+}
@@ -3,27 +3,21 @@ package whatsmeow_client
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"github.com/skip2/go-qrcode"
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/binary/proto"
-	"go.mau.fi/whatsmeow/store"
-	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
 	"google.golang.org/protobuf/proto"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 type SessionStatus string
@@ -31,22 +25,90 @@ type SessionStatus string
 const (
 	StatusInitializing   SessionStatus = "initializing"
 	StatusQRReady        SessionStatus = "qr_ready"
+	StatusPairCodeReady  SessionStatus = "pair_code_ready"
 	StatusAuthenticated  SessionStatus = "authenticated"
 	StatusReady          SessionStatus = "ready"
 	StatusAuthFailed     SessionStatus = "auth_failed"
 	StatusDisconnected   SessionStatus = "disconnected"
+	StatusReconnecting   SessionStatus = "reconnecting"
 	StatusNotInitialized SessionStatus = "not_initialized"
+	StatusLoggedOut      SessionStatus = "logged_out"
+
+	// Connection problems reported by WhatsApp, the status reason has the details
+	StatusTemporarilyBanned SessionStatus = "temporarily_banned"
+	StatusStreamReplaced    SessionStatus = "stream_replaced"
+	StatusClientOutdated    SessionStatus = "client_outdated"
+	StatusConnectFailed     SessionStatus = "connect_failed"
+	StatusKeepAliveTimeout  SessionStatus = "keepalive_timeout"
+
+	// StatusExported sessions were moved to another server and aren't connected here
+	StatusExported SessionStatus = "exported"
+
+	// StatusHibernating sessions were idle and are disconnected until they are used
+	StatusHibernating SessionStatus = "hibernating"
 )
 
 type ClientData struct {
 	Client    *whatsmeow.Client
 	status    SessionStatus
+	reason    string // Why the session is in its current status, if it isn't obvious
 	qrCode    string
+	pairCode  string                         // Set while linking with a phone number instead of a QR code
 	qrChan    <-chan whatsmeow.QRChannelItem // Add this
 	qrCtx     context.Context                // Add this
 	qrCancel  context.CancelFunc             // Add this
-	Container *sqlstore.Container
+	Container *DeviceStore
 	mu        sync.RWMutex
+
+	// Chat tracking used for read receipts and app state patches
+	lastMessages map[types.JID]messageRef
+	unread       map[types.JID][]messageRef
+	chatMu       sync.Mutex
+
+	historySync HistorySyncProgress
+	reconnect   reconnectState
+	hibernation hibernationState
+	sleepMu     sync.Mutex // Serializes hibernating and waking up
+
+	// onStatusChange is called after the status changes, outside the lock
+	onStatusChange func(status SessionStatus, reason string)
+
+	// emit feeds locally generated events (sent messages, our own read
+	// receipts) through the same handlers as events from WhatsApp
+	emit func(evt interface{})
+}
+
+// Upload encrypts media and uploads it to WhatsApp for sending
+func (cd *ClientData) Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	return cd.Client.Upload(ctx, data, mediaType)
+}
+
+// SendMessage sends a message and feeds it back through the session's event
+// handlers as a from-me message, the same way messages sent from the phone arrive
+func (cd *ClientData) SendMessage(ctx context.Context, to types.JID, msg *waProto.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	cd.touch()
+	resp, err := cd.Client.SendMessage(ctx, to, msg, extra...)
+	if err != nil {
+		return resp, err
+	}
+
+	if cd.emit != nil && cd.Client.Store.ID != nil {
+		cd.emit(&events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{
+					Chat:     to,
+					Sender:   cd.Client.Store.ID.ToNonAD(),
+					IsFromMe: true,
+					IsGroup:  to.Server == types.GroupServer,
+				},
+				ID:        resp.ID,
+				Timestamp: resp.Timestamp,
+			},
+			Message: msg,
+		})
+	}
+
+	return resp, nil
 }
 
 // Add getter for QR channel status
@@ -68,6 +130,22 @@ func (cd *ClientData) CloseQRChannel() {
 	cd.qrChan = nil
 }
 
+// closeQRFlow closes the QR channel only if it is still qrChan. A flow that
+// ends after another one replaced it must not cancel the new one.
+func (cd *ClientData) closeQRFlow(qrChan <-chan whatsmeow.QRChannelItem) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if cd.qrChan != qrChan {
+		return
+	}
+	if cd.qrCancel != nil {
+		cd.qrCancel()
+		cd.qrCancel = nil
+	}
+	cd.qrChan = nil
+}
+
 // GetStatus safely returns the current status
 func (cd *ClientData) GetStatus() SessionStatus {
 	cd.mu.RLock()
@@ -75,11 +153,30 @@ func (cd *ClientData) GetStatus() SessionStatus {
 	return cd.status
 }
 
+// GetStatusReason safely returns why the session is in its current status
+func (cd *ClientData) GetStatusReason() string {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.reason
+}
+
 // SetStatus safely sets the status
 func (cd *ClientData) SetStatus(status SessionStatus) {
+	cd.SetStatusWithReason(status, "")
+}
+
+// SetStatusWithReason safely sets the status along with why it changed
+func (cd *ClientData) SetStatusWithReason(status SessionStatus, reason string) {
 	cd.mu.Lock()
-	defer cd.mu.Unlock()
+	changed := cd.status != status || cd.reason != reason
 	cd.status = status
+	cd.reason = reason
+	onStatusChange := cd.onStatusChange
+	cd.mu.Unlock()
+
+	if changed && onStatusChange != nil {
+		onStatusChange(status, reason)
+	}
 }
 
 // GetQRCode safely returns the QR code
@@ -147,17 +244,69 @@ func (cd *ClientData) SetQRCode(qr string) {
 	cd.qrCode = qr
 }
 
+// GetPairCode safely returns the phone pairing code
+func (cd *ClientData) GetPairCode() string {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.pairCode
+}
+
+// SetPairCode safely sets the phone pairing code
+func (cd *ClientData) SetPairCode(code string) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	cd.pairCode = code
+}
+
 type Manager struct {
-	clients      map[string]*ClientData
-	container    *sqlstore.Container
-	eventHandler EventHandler
-	mu           sync.RWMutex
+	clients       map[string]*ClientData
+	container     *DeviceStore
+	store         SessionStore
+	eventHandlers []EventHandler
+	handlersMu    sync.RWMutex
+	sessionEvents sessionEventBus
+	restore       restoreTracker
+	hibernation   HibernationPolicy
+	mu            sync.RWMutex
 }
 
 type EventHandler interface {
 	HandleMessage(userID string, message interface{})
 }
 
+// AddEventHandler registers an additional handler that receives every
+// non-connection event from all sessions, after the handlers already registered
+func (m *Manager) AddEventHandler(handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	m.handlersMu.Lock()
+	defer m.handlersMu.Unlock()
+	m.eventHandlers = append(m.eventHandlers, handler)
+}
+
+// dispatchEvent passes an event to every registered event handler
+func (m *Manager) dispatchEvent(userID string, evt interface{}) {
+	m.handlersMu.RLock()
+	handlers := m.eventHandlers
+	m.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		m.runEventHandler(handler, userID, evt)
+	}
+}
+
+// runEventHandler calls one handler, so a handler that panics doesn't keep
+// the event from the handlers after it
+func (m *Manager) runEventHandler(handler EventHandler, userID string, evt interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Event handler %T panicked on %T for user %s: %v\n%s", handler, evt, userID, r, debug.Stack())
+		}
+	}()
+	handler.HandleMessage(userID, evt)
+}
+
 // func NewManager(dbPath string, handler EventHandler) (*Manager, error) {
 // 	// Ensure sessions directory exists
 // 	if err := os.MkdirAll("./sessions", 0755); err != nil {
@@ -190,44 +339,19 @@ type EventHandler interface {
 // }
 // Update NewManager in pkg/whatsmeow_client/client.go
 
-func NewManager(dbPath string, handler EventHandler) (*Manager, error) {
-	// Ensure sessions directory exists
-	if err := os.MkdirAll("./sessions", 0755); err != nil {
-		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
-	}
-
-	// Create SQLite database for whatsmeow with foreign keys enabled in DSN
-	dsn := dbPath + "?_foreign_keys=on"
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to open whatsmeow database: %w", err)
-	}
-
-	sqlDB, err := db.DB()
+func NewManager(storeConfig StoreConfig, sessionStore SessionStore, handler EventHandler) (*Manager, error) {
+	container, err := OpenStore(storeConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get database instance: %w", err)
-	}
-
-	fmt.Print("~ Creating Container DUde\n")
-
-	// Create store container
-	container := sqlstore.NewWithDB(sqlDB, "sqlite3", waLog.Noop)
-	if err := container.Upgrade(context.Background()); err != nil {
-		return nil, fmt.Errorf("failed to upgrade store: %w", err)
+		return nil, err
 	}
+	log.Printf("✅ Device store ready (%s)", storeConfig)
 
 	manager := &Manager{
-		clients:      make(map[string]*ClientData),
-		container:    container,
-		eventHandler: handler,
-	}
-
-	log.Printf("~ We are here")
-	// Auto-restore previous sessions
-	if err := manager.RestoreSessions(); err != nil {
-		log.Printf("Warning: failed to restore sessions: %v", err)
-		// Don't fail initialization, just log the warning
+		clients:   make(map[string]*ClientData),
+		container: container,
+		store:     sessionStore,
 	}
+	manager.AddEventHandler(handler)
 
 	return manager, nil
 }
@@ -279,31 +403,146 @@ func (m *Manager) GetOrCreateClient(userID string) (*ClientData, error) {
 
 func (m *Manager) setupEventHandlers(userID string, clientData *ClientData) {
 	client := clientData.Client
+	m.trackStatusChanges(userID, clientData)
+	clientData.touch()
+
+	m.supervise(clientData)
+
+	handle := func(evt interface{}) {
+		if m.handleConnectionEvent(userID, clientData, evt) {
+			m.handleConnectionStatus(userID, clientData, evt)
+			return
+		}
 
-	client.AddEventHandler(func(evt interface{}) {
 		switch v := evt.(type) {
-		case *events.LoggedOut:
-			clientData.SetStatus(StatusDisconnected)
 
-		case *events.Connected:
-			// Save metadata when connection is established
-			if err := m.SaveSessionMetadata(); err != nil {
-				log.Printf("Warning: failed to save metadata on connect: %v", err)
-			}
-			clientData.SetStatus(StatusReady)
+		case *events.Message:
+			// Conversations keep a session awake
+			clientData.touch()
+			clientData.trackMessage(v)
+			m.dispatchEvent(userID, v)
+
+		case *events.Receipt:
+			clientData.trackReceipt(v)
+			m.dispatchEvent(userID, v)
 
-		case *events.Disconnected:
-			clientData.SetStatus(StatusDisconnected)
+		case *events.HistorySync:
+			clientData.trackHistorySync(v)
+			m.dispatchEvent(userID, v)
 
 		default:
-			// Pass message events to the event handler
-			if m.eventHandler != nil {
-				m.eventHandler.HandleMessage(userID, v)
-			}
+			// Pass message events to the event handlers
+			m.dispatchEvent(userID, v)
+		}
+	}
+
+	client.AddEventHandler(handle)
+	clientData.emit = handle
+}
+
+// handleConnectionStatus updates the session status after a connection event,
+// alerts the owner about problems they have to act on and passes Connected on
+// to the event handlers
+func (m *Manager) handleConnectionStatus(userID string, clientData *ClientData, evt interface{}) {
+	clientData.endWake()
+
+	switch v := evt.(type) {
+	case *events.Connected:
+		clientData.SetStatus(StatusReady)
+		m.publishSessionEvent(userID, clientData, SessionEventConnected, nil)
+		m.dispatchEvent(userID, v)
+
+	case *events.Disconnected:
+		clientData.SetStatusWithReason(StatusDisconnected, "connection closed")
+		m.publishSessionEvent(userID, clientData, SessionEventDisconnected, nil)
+
+	case *events.KeepAliveTimeout:
+		if clientData.ReconnectInfo().NextAttemptAt == nil {
+			clientData.SetStatusWithReason(StatusKeepAliveTimeout, fmt.Sprintf("no keep-alive response since %s", v.LastSuccess.Format(time.RFC3339)))
+			return
+		}
+		// Gave up on the connection, a reconnect is scheduled
+		clientData.SetStatusWithReason(StatusDisconnected, clientData.ReconnectInfo().LastError)
+		m.publishSessionEvent(userID, clientData, SessionEventDisconnected, nil)
+
+	case *events.KeepAliveRestored:
+		if clientData.GetStatus() == StatusKeepAliveTimeout {
+			clientData.SetStatus(StatusReady)
+		}
+
+	case *events.ConnectFailure:
+		clientData.SetStatusWithReason(StatusConnectFailed, fmt.Sprintf("connect failure %s: %s", v.Reason, v.Message))
+		m.publishSessionEvent(userID, clientData, SessionEventConnectFailure, nil)
+
+	case *events.TemporaryBan:
+		m.alert(userID, clientData, StatusTemporarilyBanned, v.String(), SessionEventTemporaryBan, v.Expire <= 0)
+
+	case *events.StreamReplaced:
+		m.alert(userID, clientData, StatusStreamReplaced, "another client connected with this session's keys", SessionEventStreamReplaced, true)
+
+	case *events.ClientOutdated:
+		m.alert(userID, clientData, StatusClientOutdated, "WhatsApp rejected the client version, the server needs to be updated", SessionEventClientOutdated, true)
+
+	case *events.LoggedOut:
+		reason := "the device was unlinked from the phone"
+		if v.OnConnect {
+			reason = fmt.Sprintf("logged out on connect: %s", v.Reason)
 		}
+		m.alert(userID, clientData, StatusLoggedOut, reason, SessionEventLoggedOut, true)
+		m.removeLoggedOutClient(userID, clientData)
+	}
+}
+
+// alert records a status that needs the owner's attention, pushes it to the
+// session's event stream and dispatches a SessionAlert to the event handlers
+func (m *Manager) alert(userID string, clientData *ClientData, status SessionStatus, reason, eventType string, permanent bool) {
+	log.Printf("🚨 Session %s is %s: %s", userID, status, reason)
+
+	clientData.SetStatusWithReason(status, reason)
+	m.publishSessionEvent(userID, clientData, eventType, nil)
+	m.dispatchEvent(userID, &SessionAlert{
+		Status:    status,
+		Reason:    reason,
+		Permanent: permanent,
+		Timestamp: time.Now(),
 	})
 }
 
+// removeLoggedOutClient drops a session whose device was unlinked. whatsmeow
+// deletes the device itself as well, but only after dispatching the event, so
+// it is looked up again rather than deleted through the client's store.
+func (m *Manager) removeLoggedOutClient(userID string, clientData *ClientData) {
+	clientData.cancelReconnect()
+	clientData.CloseQRChannel()
+
+	var deviceJID *types.JID
+	if id := clientData.Client.Store.ID; id != nil {
+		jid := *id
+		deviceJID = &jid
+	}
+	clientData.Client.Disconnect()
+
+	if deviceJID != nil {
+		ctx := context.Background()
+		device, err := m.container.GetDevice(ctx, *deviceJID)
+		if err != nil {
+			log.Printf("Warning: failed to look up device %s of user %s: %v", deviceJID, userID, err)
+		} else if device != nil {
+			if err := device.Delete(ctx); err != nil {
+				log.Printf("Warning: failed to delete device %s of user %s: %v", deviceJID, userID, err)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	if m.clients[userID] == clientData {
+		delete(m.clients, userID)
+	}
+	m.mu.Unlock()
+
+	log.Printf("🧹 Removed logged out session %s", userID)
+}
+
 // func (m *Manager) InitializeClient(userID string) (*ClientData, error) {
 // 	clientData, err := m.GetOrCreateClient(userID)
 // 	if err != nil {
@@ -388,11 +627,6 @@ func (m *Manager) InitializeClient(userID string) (*ClientData, error) {
 			return nil, fmt.Errorf("failed to connect: %w", err)
 		}
 		clientData.SetStatus(StatusReady)
-
-		// Save metadata after successful reconnection
-		if err := m.SaveSessionMetadata(); err != nil {
-			log.Printf("Warning: failed to save metadata: %v", err)
-		}
 		return clientData, nil
 	}
 
@@ -432,17 +666,115 @@ func (m *Manager) startQRFlow(userID string, clientData *ClientData) (*ClientDat
 	return clientData, nil
 }
 
+// InitializeClientWithPhone links a session by entering a pairing code on the
+// phone instead of scanning a QR code. It returns the 8-character code, or an
+// empty code if the session is already authenticated.
+func (m *Manager) InitializeClientWithPhone(userID, phone string) (*ClientData, string, error) {
+	m.mu.Lock()
+	clientData, exists := m.clients[userID]
+	m.mu.Unlock()
+
+	if exists && clientData.GetStatus() == StatusReady {
+		return clientData, "", nil
+	}
+
+	// A pending pairing code stays valid until the login websocket closes
+	if exists && clientData.IsQRChannelActive() {
+		if code := clientData.GetPairCode(); code != "" {
+			return clientData, code, nil
+		}
+		// Switch a pending QR login over to a pairing code
+		clientData.CloseQRChannel()
+		clientData.Client.Disconnect()
+	}
+
+	if !exists {
+		var err error
+		clientData, err = m.GetOrCreateClient(userID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if clientData.Client.Store.ID != nil {
+		readyClient, err := m.InitializeClient(userID)
+		return readyClient, "", err
+	}
+
+	return m.startPairCodeFlow(userID, clientData, phone)
+}
+
+// startPairCodeFlow connects like the QR flow does, since the login websocket is
+// shared, then requests a pairing code for the phone number. QR codes that keep
+// arriving on the channel are ignored while a pairing code is pending.
+func (m *Manager) startPairCodeFlow(userID string, clientData *ClientData, phone string) (*ClientData, string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	qrChan, err := clientData.Client.GetQRChannel(ctx)
+	if err != nil {
+		cancel()
+		return nil, "", fmt.Errorf("failed to get QR channel: %w", err)
+	}
+
+	clientData.mu.Lock()
+	clientData.qrChan = qrChan
+	clientData.qrCtx = ctx
+	clientData.qrCancel = cancel
+	clientData.mu.Unlock()
+
+	if err := clientData.Client.Connect(); err != nil {
+		clientData.CloseQRChannel()
+		return nil, "", fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// The first QR code means the websocket is ready for pairing
+	select {
+	case evt, ok := <-qrChan:
+		if !ok || evt.Event != "code" {
+			clientData.CloseQRChannel()
+			clientData.Client.Disconnect()
+			clientData.SetStatus(StatusAuthFailed)
+			return nil, "", fmt.Errorf("login websocket closed before pairing could start")
+		}
+	case <-time.After(20 * time.Second):
+		clientData.CloseQRChannel()
+		clientData.Client.Disconnect()
+		return nil, "", fmt.Errorf("timed out waiting for the login websocket")
+	}
+
+	code, err := clientData.Client.PairPhone(ctx, phone, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		clientData.CloseQRChannel()
+		clientData.Client.Disconnect()
+		clientData.SetStatus(StatusAuthFailed)
+		return nil, "", fmt.Errorf("failed to request pairing code: %w", err)
+	}
+
+	clientData.SetPairCode(code)
+	clientData.SetQRCode("")
+	clientData.SetStatus(StatusPairCodeReady)
+	m.publishSessionEvent(userID, clientData, SessionEventPairCode, nil)
+
+	go m.handleQREvents(userID, clientData, qrChan)
+
+	log.Printf("🔢 Pairing code generated for user %s", userID)
+	return clientData, code, nil
+}
+
 // Dedicated QR event handler
 func (m *Manager) handleQREvents(userID string, clientData *ClientData, qrChan <-chan whatsmeow.QRChannelItem) {
 	defer func() {
 		log.Printf("QR channel closed for user %s", userID)
-		clientData.CloseQRChannel()
+		clientData.closeQRFlow(qrChan)
 	}()
 
 	qrCount := 0
 	for evt := range qrChan {
 		switch evt.Event {
 		case "code":
+			if clientData.GetPairCode() != "" {
+				continue // Linking with a pairing code, QR codes aren't shown
+			}
 			qrCount++
 			log.Printf("📱 QR code #%d generated for user %s (expires in ~60s)", qrCount, userID)
 
@@ -458,27 +790,30 @@ func (m *Manager) handleQREvents(userID string, clientData *ClientData, qrChan <
 
 			clientData.SetQRCode(qrDataURL)
 			clientData.SetStatus(StatusQRReady)
+			m.publishSessionEvent(userID, clientData, SessionEventQRCode, nil)
 
 			log.Printf("✅ QR code updated for user %s", userID)
 
 		case "success":
 			log.Printf("🎉 QR code scanned successfully for user %s", userID)
 			clientData.SetStatus(StatusAuthenticated)
-			// Save metadata immediately after successful authentication
-			if err := m.SaveSessionMetadata(); err != nil {
-				log.Printf("Warning: failed to save metadata after QR scan: %v", err)
-			}
 			clientData.SetQRCode("") // Clear QR code
+			clientData.SetPairCode("")
+			m.publishSessionEvent(userID, clientData, SessionEventPairSuccess, nil)
 
 		case "timeout":
 			log.Printf("⏰ QR code timeout for user %s after %d attempts", userID, qrCount)
 			clientData.SetStatus(StatusAuthFailed)
 			clientData.SetQRCode("") // Clear QR code
+			clientData.SetPairCode("")
+			m.publishSessionEvent(userID, clientData, SessionEventQRTimeout, nil)
 
 		case "error":
 			log.Printf("❌ QR code error for user %s: %v", userID, evt.Error)
 			clientData.SetStatus(StatusAuthFailed)
 			clientData.SetQRCode("") // Clear QR code
+			clientData.SetPairCode("")
+			m.publishSessionEvent(userID, clientData, SessionEventQRError, evt.Error)
 		}
 	}
 }
@@ -553,6 +888,11 @@ func (m *Manager) GetClient(userID string) (*ClientData, bool) {
 // }
 
 func (m *Manager) LogoutClient(userID string) error {
+	// Logging out tells WhatsApp, which needs a connection
+	if err := m.Wake(userID); err != nil {
+		log.Printf("Warning: failed to wake session %s before logout: %v", userID, err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -565,6 +905,7 @@ func (m *Manager) LogoutClient(userID string) error {
 	if clientData.IsQRChannelActive() {
 		clientData.CloseQRChannel()
 	}
+	clientData.cancelReconnect()
 
 	// Logout from WhatsApp (deletes device from WhatsApp servers)
 	if err := clientData.Client.Logout(context.Background()); err != nil {
@@ -574,14 +915,10 @@ func (m *Manager) LogoutClient(userID string) error {
 	// Disconnect the client
 	clientData.Client.Disconnect()
 
-	// Remove from memory
+	// Record the logout, then remove from memory
+	clientData.SetStatus(StatusLoggedOut)
 	delete(m.clients, userID)
 
-	// Update metadata file
-	if err := m.SaveSessionMetadata(); err != nil {
-		log.Printf("Warning: failed to save metadata after logout: %v", err)
-	}
-
 	log.Printf("✅ Session cleaned up for user %s", userID)
 	return nil
 }
@@ -597,6 +934,7 @@ func (m *Manager) GetAllSessions() []map[string]interface{} {
 			"user_id":      userID,
 			"status":       status,
 			"is_logged_in": status == StatusReady,
+			"history_sync": clientData.HistorySync(),
 		})
 	}
 
@@ -667,188 +1005,6 @@ func (cd *ClientData) SendMediaMessage(phone, mediaURL, caption string) (string,
 	return sendResp.ID, nil
 }
 
-type SessionMetadata struct {
-	UserID       string    `json:"user_id"`
-	PhoneJID     string    `json:"phone_jid"`
-	LastActivity time.Time `json:"last_activity"`
-	Status       string    `json:"status"`
-}
-
-// SaveSessionMetadata saves active session info to disk
-func (m *Manager) SaveSessionMetadata() error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	metadata := make([]SessionMetadata, 0, len(m.clients))
-	for userID, clientData := range m.clients {
-		// Get the WhatsApp JID if device is logged in
-		phoneJID := ""
-		if clientData.Client.Store.ID != nil {
-			phoneJID = clientData.Client.Store.ID.String()
-		}
-
-		metadata = append(metadata, SessionMetadata{
-			UserID:       userID,
-			PhoneJID:     phoneJID,
-			LastActivity: time.Now(),
-			Status:       string(clientData.GetStatus()),
-		})
-	}
-
-	// Ensure directory exists
-	dir := filepath.Dir("./sessions/metadata.json")
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	data, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile("./sessions/metadata.json", data, 0644)
-}
-
-// LoadSessionMetadata loads session info from disk
-func loadSessionMetadata() ([]SessionMetadata, error) {
-	data, err := os.ReadFile("./sessions/metadata.json")
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No metadata file yet
-		}
-		return nil, err
-	}
-
-	var metadata []SessionMetadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return nil, err
-	}
-
-	return metadata, nil
-}
-
-// RestoreSessions automatically restores all previously active sessions
-func (m *Manager) RestoreSessions() error {
-	metadata, err := loadSessionMetadata()
-	if err != nil {
-		return fmt.Errorf("failed to load session metadata: %w", err)
-	}
-
-	if len(metadata) == 0 {
-		log.Println("No previous sessions to restore")
-		return nil
-	}
-
-	log.Printf("Found %d sessions to restore...", len(metadata))
-
-	// Get all devices from whatsmeow store
-	devices, err := m.container.GetAllDevices(context.Background())
-	if err != nil {
-		return fmt.Errorf("failed to get devices: %w", err)
-	}
-
-	if len(devices) == 0 {
-		log.Println("No devices found in whatsmeow store")
-		return nil
-	}
-
-	log.Printf("Found %d devices in whatsmeow store", len(devices))
-
-	// Create a map: phoneJID -> device
-	deviceMap := make(map[string]*store.Device)
-	for _, device := range devices {
-		if device.ID != nil {
-			jid := device.ID.String()
-			deviceMap[jid] = device
-			log.Printf("Device in store: %s", jid)
-		}
-	}
-
-	restored := 0
-	skipped := 0
-
-	for _, meta := range metadata {
-		log.Printf("Attempting to restore session for user: %s (JID: %s)", meta.UserID, meta.PhoneJID)
-
-		// Skip if no phone JID recorded
-		if meta.PhoneJID == "" {
-			log.Printf("⚠️  Skipping user %s - no phone JID in metadata", meta.UserID)
-			skipped++
-			continue
-		}
-
-		// Check if device exists in store
-		device, exists := deviceMap[meta.PhoneJID]
-		if !exists {
-			log.Printf("⚠️  Skipping user %s - device %s not found in whatsmeow store", meta.UserID, meta.PhoneJID)
-			skipped++
-			continue
-		}
-
-		// Check if session already exists in memory
-		m.mu.RLock()
-		_, alreadyExists := m.clients[meta.UserID]
-		m.mu.RUnlock()
-
-		if alreadyExists {
-			log.Printf("⚠️  Session for user %s already exists in memory", meta.UserID)
-			continue
-		}
-
-		// Create WhatsApp client with existing device
-		client := whatsmeow.NewClient(device, waLog.Noop)
-
-		clientData := &ClientData{
-			Client:    client,
-			Container: m.container,
-		}
-		clientData.SetStatus(StatusInitializing)
-
-		// Set up event handlers
-		m.setupEventHandlers(meta.UserID, clientData)
-
-		// Add to clients map BEFORE connecting
-		m.mu.Lock()
-		m.clients[meta.UserID] = clientData
-		m.mu.Unlock()
-
-		// Connect to WhatsApp
-		log.Printf("Connecting to WhatsApp for user %s...", meta.UserID)
-		if err := client.Connect(); err != nil {
-			log.Printf("❌ Failed to connect for user %s: %v", meta.UserID, err)
-
-			// Remove from map on failure
-			m.mu.Lock()
-			delete(m.clients, meta.UserID)
-			m.mu.Unlock()
-
-			skipped++
-			continue
-		}
-
-		// Wait a bit for connection to establish
-		time.Sleep(500 * time.Millisecond)
-
-		// Check if connected successfully
-		if client.IsConnected() {
-			clientData.SetStatus(StatusReady)
-			restored++
-			log.Printf("✅ Successfully restored session for user %s (JID: %s)", meta.UserID, meta.PhoneJID)
-		} else {
-			log.Printf("⚠️  Connected but not ready for user %s", meta.UserID)
-			restored++ // Still count as restored, might become ready later
-		}
-	}
-
-	log.Printf("📊 Session restoration complete: %d restored, %d skipped, %d total", restored, skipped, len(metadata))
-
-	if restored == 0 && len(metadata) > 0 {
-		log.Println("⚠️  WARNING: No sessions were restored. Check logs above for details.")
-	}
-
-	return nil
-}
-
 // // RestoreSessions automatically restores all previously active sessions
 // func (m *Manager) RestoreSessions() error {
 // 	log.Printf("~ Restoring Sessions")
@@ -919,15 +1075,3 @@ func (m *Manager) RestoreSessions() error {
 // 	log.Printf("Successfully restored %d/%d sessions", restored, len(metadata))
 // 	return nil
 // }
-
-// StartMetadataSaver starts a background goroutine to periodically save metadata
-func (m *Manager) StartMetadataSaver(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	go func() {
-		for range ticker.C {
-			if err := m.SaveSessionMetadata(); err != nil {
-				log.Printf("Error saving session metadata: %v", err)
-			}
-		}
-	}()
-}
@@ -0,0 +1,62 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// StartSession connects a session with a linked device in the background, for
+// sessions this instance takes over after startup
+func (m *Manager) StartSession(record SessionRecord) error {
+	jid, err := types.ParseJID(record.DeviceJID)
+	if err != nil {
+		return fmt.Errorf("invalid device JID %q: %w", record.DeviceJID, err)
+	}
+
+	device, err := m.container.GetDevice(context.Background(), jid)
+	if err != nil {
+		return fmt.Errorf("failed to look up device %s: %w", record.DeviceJID, err)
+	}
+
+	go m.restoreSession(record, device)
+	return nil
+}
+
+// ReleaseSession disconnects a session and forgets it without logging it out,
+// so another instance can take it over. Its status isn't recorded, the new
+// owner does that.
+func (m *Manager) ReleaseSession(userID string) {
+	m.mu.Lock()
+	clientData, exists := m.clients[userID]
+	delete(m.clients, userID)
+	m.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	clientData.mu.Lock()
+	clientData.onStatusChange = nil
+	clientData.mu.Unlock()
+
+	clientData.cancelReconnect()
+	clientData.CloseQRChannel()
+	clientData.Client.Disconnect()
+
+	log.Printf("👋 Released session %s", userID)
+}
+
+// RunningSessions returns the IDs of the sessions this instance has in memory
+func (m *Manager) RunningSessions() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.clients))
+	for userID := range m.clients {
+		ids = append(ids, userID)
+	}
+	return ids
+}
@@ -0,0 +1,105 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// NormalizeJID strips the device part of a JID and maps hidden LID users to
+// their phone number JID when the mapping is known, so one person always has one ID
+func (cd *ClientData) NormalizeJID(jid types.JID) types.JID {
+	jid = jid.ToNonAD()
+	if jid.Server != types.HiddenUserServer || cd.Client.Store.LIDs == nil {
+		return jid
+	}
+
+	pn, err := cd.Client.Store.LIDs.GetPNForLID(context.Background(), jid)
+	if err != nil || pn.IsEmpty() {
+		return jid
+	}
+	return pn.ToNonAD()
+}
+
+// ContactName returns the best known display name of a user from the device's
+// contact store: the saved name first, then the push name or business name
+func (cd *ClientData) ContactName(jid types.JID) string {
+	if cd.Client.Store.Contacts == nil {
+		return ""
+	}
+
+	contact, err := cd.Client.Store.Contacts.GetContact(context.Background(), jid.ToNonAD())
+	if err != nil || !contact.Found {
+		return ""
+	}
+
+	for _, name := range []string{contact.FullName, contact.FirstName, contact.PushName, contact.BusinessName} {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// DeviceContacts returns every contact cached in the device store: the address
+// book synced from the phone plus push names seen in messages
+func (cd *ClientData) DeviceContacts() (map[types.JID]types.ContactInfo, error) {
+	if cd.Client.Store.Contacts == nil {
+		return nil, fmt.Errorf("device store is not available")
+	}
+	return cd.Client.Store.Contacts.GetAllContacts(context.Background())
+}
+
+// UserProfile is the public profile of a WhatsApp user
+type UserProfile struct {
+	About           string
+	PictureID       string
+	PictureURL      string
+	BusinessName    string // Verified business name, empty for regular accounts
+	BusinessProfile *types.BusinessProfile
+}
+
+// FetchUserProfile looks up a user's about text, profile picture and, for
+// business accounts, their business profile
+func (cd *ClientData) FetchUserProfile(jid types.JID) (*UserProfile, error) {
+	ctx := context.Background()
+	jid = jid.ToNonAD()
+
+	infos, err := cd.Client.GetUserInfo(ctx, []types.JID{jid})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	profile := &UserProfile{}
+	if info, ok := infos[jid]; ok {
+		profile.About = info.Status
+		profile.PictureID = info.PictureID
+		if info.VerifiedName != nil && info.VerifiedName.Details != nil {
+			profile.BusinessName = info.VerifiedName.Details.GetVerifiedName()
+		}
+	}
+
+	picture, err := cd.Client.GetProfilePictureInfo(ctx, jid, &whatsmeow.GetProfilePictureParams{Preview: false})
+	switch {
+	case err == nil && picture != nil:
+		profile.PictureID = picture.ID
+		profile.PictureURL = picture.URL
+	case err != nil && !errors.Is(err, whatsmeow.ErrProfilePictureNotSet) && !errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized):
+		log.Printf("Warning: failed to get profile picture of %s: %v", jid, err)
+	}
+
+	if profile.BusinessName != "" {
+		business, err := cd.Client.GetBusinessProfile(ctx, jid)
+		if err != nil {
+			log.Printf("Warning: failed to get business profile of %s: %v", jid, err)
+		} else {
+			profile.BusinessProfile = business
+		}
+	}
+
+	return profile, nil
+}
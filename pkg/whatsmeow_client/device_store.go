@@ -0,0 +1,736 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/util/keys"
+)
+
+// Value kinds, part of the authenticated data of encrypted values
+const (
+	purposeDeviceKeys    = "device_keys"
+	purposeSession       = "session"
+	purposeSenderKey     = "sender_key"
+	purposeAppStateKey   = "app_state_key"
+	purposeMessageSecret = "message_secret"
+	purposeBufferedEvent = "buffered_event"
+)
+
+// whatsmeow_device requires 32 byte keys, encrypted devices keep zeros there
+// and their real keys in whatsmeow_device_secrets
+var placeholderKey [32]byte
+
+// sealedColumns are the columns with secrets of a device that are encrypted
+// with its data key. Pre-keys are generated and stored inside whatsmeow and
+// stay readable, they are useless without the encrypted identity key.
+var sealedColumns = []struct {
+	table   string
+	column  string
+	owner   string
+	keys    []string
+	binary  []bool // Key columns stored as bytes rather than text
+	purpose string
+}{
+	{"whatsmeow_sessions", "session", "our_jid", []string{"their_id"}, []bool{false}, purposeSession},
+	{"whatsmeow_sender_keys", "sender_key", "our_jid", []string{"chat_id", "sender_id"}, []bool{false, false}, purposeSenderKey},
+	{"whatsmeow_app_state_sync_keys", "key_data", "jid", []string{"key_id"}, []bool{true}, purposeAppStateKey},
+	{"whatsmeow_message_secrets", "key", "our_jid", []string{"chat_jid", "sender_jid", "message_id"}, []bool{false, false, false}, purposeMessageSecret},
+	{"whatsmeow_event_buffer", "plaintext", "our_jid", []string{"ciphertext_hash"}, []bool{true}, purposeBufferedEvent},
+}
+
+// DeviceStore wraps whatsmeow's SQL store and, when a keyring is configured,
+// keeps the keys and signal state of every device encrypted at rest
+type DeviceStore struct {
+	container *sqlstore.Container
+	db        *dbutil.Database
+	keyring   *Keyring
+
+	mu      sync.Mutex
+	ciphers map[string]*valueCipher
+}
+
+func newDeviceStore(container *sqlstore.Container, db *dbutil.Database, keyring *Keyring) *DeviceStore {
+	return &DeviceStore{
+		container: container,
+		db:        db,
+		keyring:   keyring,
+		ciphers:   make(map[string]*valueCipher),
+	}
+}
+
+var _ store.DeviceContainer = (*DeviceStore)(nil)
+
+// Encrypted reports whether new data is written encrypted
+func (ds *DeviceStore) Encrypted() bool {
+	return ds.keyring != nil
+}
+
+// NewDevice creates a device for pairing, it is saved once paired
+func (ds *DeviceStore) NewDevice() *store.Device {
+	device := ds.container.NewDevice()
+	device.Container = ds
+	return device
+}
+
+// GetDevice returns the device with the given JID, nil if there is none
+func (ds *DeviceStore) GetDevice(ctx context.Context, jid types.JID) (*store.Device, error) {
+	device, err := ds.container.GetDevice(ctx, jid)
+	if err != nil || device == nil {
+		return device, err
+	}
+	if err := ds.load(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// GetAllDevices returns every device in the store
+func (ds *DeviceStore) GetAllDevices(ctx context.Context) ([]*store.Device, error) {
+	devices, err := ds.container.GetAllDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if err := ds.load(ctx, device); err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+// PutDevice saves a device. With encryption its keys go to
+// whatsmeow_device_secrets in the same transaction.
+func (ds *DeviceStore) PutDevice(ctx context.Context, device *store.Device) error {
+	if ds.keyring == nil {
+		if err := ds.container.PutDevice(ctx, device); err != nil {
+			return err
+		}
+		ds.initializeOnce(device, nil)
+		return nil
+	}
+	if device.ID == nil {
+		return sqlstore.ErrDeviceIDMustBeSet
+	}
+
+	var vc *valueCipher
+	err := ds.db.DoTxn(ctx, nil, func(ctx context.Context) error {
+		// Only new rows take the keys, so the placeholders never overwrite anything
+		if err := ds.container.PutDevice(ctx, withoutSecrets(device)); err != nil {
+			return err
+		}
+		var err error
+		vc, err = ds.deviceCipher(ctx, device)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	ds.initializeOnce(device, vc)
+	return nil
+}
+
+// DeleteDevice removes a device, its secrets go with it
+func (ds *DeviceStore) DeleteDevice(ctx context.Context, device *store.Device) error {
+	if device.ID != nil {
+		ds.mu.Lock()
+		delete(ds.ciphers, device.ID.String())
+		ds.mu.Unlock()
+	}
+	return ds.container.DeleteDevice(ctx, device)
+}
+
+// Close closes the underlying database
+func (ds *DeviceStore) Close() error {
+	return ds.container.Close()
+}
+
+// load decrypts the keys of a device read from whatsmeow_device, or encrypts
+// them if the device was stored before encryption was enabled
+func (ds *DeviceStore) load(ctx context.Context, device *store.Device) error {
+	jid := device.ID.String()
+
+	row, err := ds.findSecrets(ctx, jid)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		if *device.NoiseKey.Priv == placeholderKey {
+			return fmt.Errorf("keys of device %s are missing from the device store", jid)
+		}
+		if ds.keyring == nil {
+			ds.initialize(device, nil)
+			return nil
+		}
+		vc, err := ds.sealDevice(ctx, device)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt device %s: %w", jid, err)
+		}
+		ds.initialize(device, vc)
+		return nil
+	}
+
+	if ds.keyring == nil {
+		return ErrStoreKeyRequired
+	}
+	vc, err := ds.cipherFromRow(row, jid)
+	if err != nil {
+		return err
+	}
+	if err := restoreSecrets(vc, device, row.secrets); err != nil {
+		return err
+	}
+	ds.initialize(device, vc)
+	return nil
+}
+
+// initialize sets up the device's stores the way sqlstore does, with the
+// stores holding secrets wrapped to encrypt them
+func (ds *DeviceStore) initialize(device *store.Device, vc *valueCipher) {
+	inner := sqlstore.NewSQLStore(ds.container, *device.ID)
+	device.Identities = inner
+	device.Sessions = inner
+	device.PreKeys = inner
+	device.SenderKeys = inner
+	device.AppStateKeys = inner
+	device.AppState = inner
+	device.Contacts = inner
+	device.ChatSettings = inner
+	device.MsgSecrets = inner
+	device.PrivacyTokens = inner
+	device.EventBuffer = inner
+	device.LIDs = ds.container.LIDMap
+	device.Container = ds
+	device.Initialized = true
+
+	if vc != nil {
+		device.Sessions = &sealedSessionStore{SessionStore: inner, cipher: vc}
+		device.SenderKeys = &sealedSenderKeyStore{SenderKeyStore: inner, cipher: vc}
+		device.AppStateKeys = &sealedAppStateKeyStore{AppStateSyncKeyStore: inner, cipher: vc}
+		device.MsgSecrets = &sealedMsgSecretStore{MsgSecretStore: inner, cipher: vc}
+		device.EventBuffer = &sealedEventBuffer{EventBuffer: inner, cipher: vc}
+	}
+}
+
+// initializeOnce initializes a device being saved unless it already is. The
+// client keeps using the device's stores, so they are never swapped out
+// while it is connected.
+func (ds *DeviceStore) initializeOnce(device *store.Device, vc *valueCipher) {
+	if device.Initialized && device.Container == ds {
+		if _, sealed := device.Sessions.(*sealedSessionStore); sealed || vc == nil {
+			return
+		}
+	}
+	ds.initialize(device, vc)
+}
+
+type secretsRow struct {
+	keyID      string
+	wrappedKey []byte
+	secrets    []byte
+}
+
+func (ds *DeviceStore) findSecrets(ctx context.Context, jid string) (*secretsRow, error) {
+	var row secretsRow
+	err := ds.db.QueryRow(ctx, "SELECT key_id, wrapped_key, secrets FROM whatsmeow_device_secrets WHERE jid=$1", jid).
+		Scan(&row.keyID, &row.wrappedKey, &row.secrets)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets of device %s: %w", jid, err)
+	}
+	return &row, nil
+}
+
+func (ds *DeviceStore) cipherFromRow(row *secretsRow, jid string) (*valueCipher, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if vc, ok := ds.ciphers[jid]; ok {
+		return vc, nil
+	}
+	dataKey, err := ds.keyring.unwrap(row.keyID, row.wrappedKey, jid)
+	if err != nil {
+		return nil, err
+	}
+	vc, err := newValueCipher(dataKey, jid)
+	if err != nil {
+		return nil, err
+	}
+	ds.ciphers[jid] = vc
+	return vc, nil
+}
+
+// deviceCipher returns the data key cipher of a device, creating the data key
+// and storing the device's keys on first save
+func (ds *DeviceStore) deviceCipher(ctx context.Context, device *store.Device) (*valueCipher, error) {
+	jid := device.ID.String()
+	row, err := ds.findSecrets(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	if row != nil {
+		return ds.cipherFromRow(row, jid)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	vc, err := newValueCipher(dataKey, jid)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := ds.keyring.wrap(dataKey, jid)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := vc.seal(purposeDeviceKeys, deviceSecrets(device))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ds.db.Exec(ctx,
+		"INSERT INTO whatsmeow_device_secrets (jid, key_id, wrapped_key, secrets, updated_at) VALUES ($1, $2, $3, $4, $5)",
+		jid, keyID, wrapped, secrets, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secrets of device %s: %w", jid, err)
+	}
+
+	ds.mu.Lock()
+	ds.ciphers[jid] = vc
+	ds.mu.Unlock()
+	return vc, nil
+}
+
+// sealDevice encrypts a device stored in plaintext: its keys move to
+// whatsmeow_device_secrets and its signal state is encrypted in place
+func (ds *DeviceStore) sealDevice(ctx context.Context, device *store.Device) (*valueCipher, error) {
+	jid := device.ID.String()
+
+	var vc *valueCipher
+	err := ds.db.DoTxn(ctx, nil, func(ctx context.Context) error {
+		var err error
+		if vc, err = ds.deviceCipher(ctx, device); err != nil {
+			return err
+		}
+
+		_, err = ds.db.Exec(ctx,
+			"UPDATE whatsmeow_device SET noise_key=$1, identity_key=$2, signed_pre_key=$3, adv_key=$4 WHERE jid=$5",
+			placeholderKey[:], placeholderKey[:], placeholderKey[:], placeholderKey[:], jid)
+		if err != nil {
+			return err
+		}
+
+		for _, sc := range sealedColumns {
+			if err := ds.sealColumn(ctx, vc, jid, sc.table, sc.column, sc.owner, sc.keys, sc.binary, sc.purpose); err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", sc.table, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ds.mu.Lock()
+		delete(ds.ciphers, jid)
+		ds.mu.Unlock()
+		return nil, err
+	}
+
+	log.Printf("🔐 Encrypted device %s", jid)
+	return vc, nil
+}
+
+// sealColumn encrypts the plaintext values of one column of a device
+func (ds *DeviceStore) sealColumn(ctx context.Context, vc *valueCipher, jid, table, column, owner string, keyColumns []string, binary []bool, purpose string) error {
+	rows, err := ds.db.Query(ctx,
+		fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s=$1", strings.Join(keyColumns, ", "), column, table, owner), jid)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		keys  []interface{}
+		value []byte
+	}
+	var plaintext []pending
+	for rows.Next() {
+		keyValues := make([]interface{}, len(keyColumns))
+		dest := make([]interface{}, len(keyColumns)+1)
+		for i := range keyColumns {
+			if binary[i] {
+				dest[i] = new([]byte)
+			} else {
+				dest[i] = new(string)
+			}
+		}
+		var value []byte
+		dest[len(keyColumns)] = &value
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(value) == 0 || isSealed(value) {
+			continue
+		}
+		for i := range keyColumns {
+			if binary[i] {
+				keyValues[i] = *dest[i].(*[]byte)
+			} else {
+				keyValues[i] = *dest[i].(*string)
+			}
+		}
+		plaintext = append(plaintext, pending{keys: keyValues, value: value})
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	// Rows are read first, SQLite can't update while a query is open
+	conditions := make([]string, len(keyColumns))
+	for i, key := range keyColumns {
+		conditions[i] = fmt.Sprintf("%s=$%d", key, i+3)
+	}
+	update := fmt.Sprintf("UPDATE %s SET %s=$1 WHERE %s=$2 AND %s", table, column, owner, strings.Join(conditions, " AND "))
+
+	for _, p := range plaintext {
+		sealed, err := vc.seal(purpose, p.value)
+		if err != nil {
+			return err
+		}
+		args := append([]interface{}{sealed, jid}, p.keys...)
+		if _, err := ds.db.Exec(ctx, update, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SealDevices encrypts every device still stored in plaintext. Devices are
+// also encrypted when they are loaded, this does it for all of them up front.
+// Without a keyring it only checks that nothing is encrypted yet.
+func (ds *DeviceStore) SealDevices(ctx context.Context) (int, error) {
+	if ds.keyring == nil {
+		var encrypted int
+		if err := ds.db.QueryRow(ctx, "SELECT COUNT(*) FROM whatsmeow_device_secrets").Scan(&encrypted); err != nil {
+			return 0, err
+		}
+		if encrypted > 0 {
+			return 0, ErrStoreKeyRequired
+		}
+		return 0, nil
+	}
+
+	devices, err := ds.container.GetAllDevices(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sealed := 0
+	for _, device := range devices {
+		row, err := ds.findSecrets(ctx, device.ID.String())
+		if err != nil {
+			return sealed, err
+		}
+		if row != nil {
+			continue
+		}
+		if err := ds.load(ctx, device); err != nil {
+			return sealed, err
+		}
+		sealed++
+	}
+	return sealed, nil
+}
+
+// RotateKey rewraps every data key that isn't wrapped with the current store
+// key. The previous keys have to be in the keyring until this has run.
+func (ds *DeviceStore) RotateKey(ctx context.Context) (int, error) {
+	if ds.keyring == nil {
+		return 0, ErrStoreKeyRequired
+	}
+
+	type wrappedKey struct {
+		jid     string
+		keyID   string
+		wrapped []byte
+	}
+	rows, err := ds.db.Query(ctx, "SELECT jid, key_id, wrapped_key FROM whatsmeow_device_secrets WHERE key_id<>$1", ds.keyring.CurrentID())
+	if err != nil {
+		return 0, err
+	}
+	var stale []wrappedKey
+	for rows.Next() {
+		var wk wrappedKey
+		if err := rows.Scan(&wk.jid, &wk.keyID, &wk.wrapped); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stale = append(stale, wk)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	err = ds.db.DoTxn(ctx, nil, func(ctx context.Context) error {
+		for _, wk := range stale {
+			dataKey, err := ds.keyring.unwrap(wk.keyID, wk.wrapped, wk.jid)
+			if err != nil {
+				return err
+			}
+			keyID, wrapped, err := ds.keyring.wrap(dataKey, wk.jid)
+			if err != nil {
+				return err
+			}
+			_, err = ds.db.Exec(ctx,
+				"UPDATE whatsmeow_device_secrets SET key_id=$1, wrapped_key=$2, updated_at=$3 WHERE jid=$4",
+				keyID, wrapped, time.Now().Unix(), wk.jid)
+			if err != nil {
+				return err
+			}
+			rotated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
+
+// deviceSecrets serializes the private keys of a device: noise, identity and
+// signed pre-key (32 bytes each) followed by the ADV secret
+func deviceSecrets(device *store.Device) []byte {
+	secrets := make([]byte, 0, 96+len(device.AdvSecretKey))
+	secrets = append(secrets, device.NoiseKey.Priv[:]...)
+	secrets = append(secrets, device.IdentityKey.Priv[:]...)
+	secrets = append(secrets, device.SignedPreKey.Priv[:]...)
+	return append(secrets, device.AdvSecretKey...)
+}
+
+// restoreSecrets puts the decrypted private keys back into a device
+func restoreSecrets(vc *valueCipher, device *store.Device, sealed []byte) error {
+	secrets, err := vc.open(purposeDeviceKeys, sealed)
+	if err != nil {
+		return err
+	}
+	if len(secrets) < 96 {
+		return fmt.Errorf("secrets of device %s are truncated", vc.jid)
+	}
+
+	device.NoiseKey = keys.NewKeyPairFromPrivateKey(*(*[32]byte)(secrets[0:32]))
+	device.IdentityKey = keys.NewKeyPairFromPrivateKey(*(*[32]byte)(secrets[32:64]))
+	device.SignedPreKey.KeyPair = *keys.NewKeyPairFromPrivateKey(*(*[32]byte)(secrets[64:96]))
+	device.AdvSecretKey = secrets[96:]
+	return nil
+}
+
+// withoutSecrets returns a copy of the device with placeholders for its private keys
+func withoutSecrets(device *store.Device) *store.Device {
+	shadow := *device
+	placeholder := &keys.KeyPair{Pub: new([32]byte), Priv: new([32]byte)}
+	shadow.NoiseKey = placeholder
+	shadow.IdentityKey = placeholder
+	shadow.SignedPreKey = &keys.PreKey{
+		KeyPair:   *placeholder,
+		KeyID:     device.SignedPreKey.KeyID,
+		Signature: device.SignedPreKey.Signature,
+	}
+	shadow.AdvSecretKey = placeholderKey[:]
+	shadow.Initialized = true
+	return &shadow
+}
+
+// ensureSecretsTable creates the table for encrypted device keys
+func ensureSecretsTable(ctx context.Context, db *dbutil.Database) error {
+	blob := "BLOB"
+	if db.Dialect == dbutil.Postgres {
+		blob = "bytea"
+	}
+	_, err := db.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS whatsmeow_device_secrets (
+		jid         TEXT PRIMARY KEY,
+		key_id      TEXT   NOT NULL,
+		wrapped_key %[1]s  NOT NULL,
+		secrets     %[1]s  NOT NULL,
+		updated_at  BIGINT NOT NULL,
+		FOREIGN KEY (jid) REFERENCES whatsmeow_device(jid) ON DELETE CASCADE ON UPDATE CASCADE
+	)`, blob))
+	return err
+}
+
+// The wrappers below encrypt values on the way into whatsmeow's SQL store and
+// decrypt them on the way out. Everything else passes through.
+
+type sealedSessionStore struct {
+	store.SessionStore
+	cipher *valueCipher
+}
+
+func (s *sealedSessionStore) GetSession(ctx context.Context, address string) ([]byte, error) {
+	session, err := s.SessionStore.GetSession(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.open(purposeSession, session)
+}
+
+func (s *sealedSessionStore) GetManySessions(ctx context.Context, addresses []string) (map[string][]byte, error) {
+	sessions, err := s.SessionStore.GetManySessions(ctx, addresses)
+	if err != nil {
+		return nil, err
+	}
+	for address, session := range sessions {
+		if sessions[address], err = s.cipher.open(purposeSession, session); err != nil {
+			return nil, err
+		}
+	}
+	return sessions, nil
+}
+
+func (s *sealedSessionStore) PutSession(ctx context.Context, address string, session []byte) error {
+	sealed, err := s.cipher.seal(purposeSession, session)
+	if err != nil {
+		return err
+	}
+	return s.SessionStore.PutSession(ctx, address, sealed)
+}
+
+func (s *sealedSessionStore) PutManySessions(ctx context.Context, sessions map[string][]byte) error {
+	sealed := make(map[string][]byte, len(sessions))
+	for address, session := range sessions {
+		var err error
+		if sealed[address], err = s.cipher.seal(purposeSession, session); err != nil {
+			return err
+		}
+	}
+	return s.SessionStore.PutManySessions(ctx, sealed)
+}
+
+type sealedSenderKeyStore struct {
+	store.SenderKeyStore
+	cipher *valueCipher
+}
+
+func (s *sealedSenderKeyStore) PutSenderKey(ctx context.Context, group, user string, session []byte) error {
+	sealed, err := s.cipher.seal(purposeSenderKey, session)
+	if err != nil {
+		return err
+	}
+	return s.SenderKeyStore.PutSenderKey(ctx, group, user, sealed)
+}
+
+func (s *sealedSenderKeyStore) GetSenderKey(ctx context.Context, group, user string) ([]byte, error) {
+	key, err := s.SenderKeyStore.GetSenderKey(ctx, group, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.open(purposeSenderKey, key)
+}
+
+type sealedAppStateKeyStore struct {
+	store.AppStateSyncKeyStore
+	cipher *valueCipher
+}
+
+func (s *sealedAppStateKeyStore) PutAppStateSyncKey(ctx context.Context, id []byte, key store.AppStateSyncKey) error {
+	sealed, err := s.cipher.seal(purposeAppStateKey, key.Data)
+	if err != nil {
+		return err
+	}
+	key.Data = sealed
+	return s.AppStateSyncKeyStore.PutAppStateSyncKey(ctx, id, key)
+}
+
+func (s *sealedAppStateKeyStore) GetAppStateSyncKey(ctx context.Context, id []byte) (*store.AppStateSyncKey, error) {
+	key, err := s.AppStateSyncKeyStore.GetAppStateSyncKey(ctx, id)
+	if err != nil || key == nil {
+		return key, err
+	}
+	if key.Data, err = s.cipher.open(purposeAppStateKey, key.Data); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *sealedAppStateKeyStore) GetAllAppStateSyncKeys(ctx context.Context) ([]*store.AppStateSyncKey, error) {
+	syncKeys, err := s.AppStateSyncKeyStore.GetAllAppStateSyncKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range syncKeys {
+		if key.Data, err = s.cipher.open(purposeAppStateKey, key.Data); err != nil {
+			return nil, err
+		}
+	}
+	return syncKeys, nil
+}
+
+type sealedMsgSecretStore struct {
+	store.MsgSecretStore
+	cipher *valueCipher
+}
+
+func (s *sealedMsgSecretStore) PutMessageSecrets(ctx context.Context, inserts []store.MessageSecretInsert) error {
+	sealed := make([]store.MessageSecretInsert, len(inserts))
+	for i, insert := range inserts {
+		var err error
+		if insert.Secret, err = s.cipher.seal(purposeMessageSecret, insert.Secret); err != nil {
+			return err
+		}
+		sealed[i] = insert
+	}
+	return s.MsgSecretStore.PutMessageSecrets(ctx, sealed)
+}
+
+func (s *sealedMsgSecretStore) PutMessageSecret(ctx context.Context, chat, sender types.JID, id types.MessageID, secret []byte) error {
+	sealed, err := s.cipher.seal(purposeMessageSecret, secret)
+	if err != nil {
+		return err
+	}
+	return s.MsgSecretStore.PutMessageSecret(ctx, chat, sender, id, sealed)
+}
+
+func (s *sealedMsgSecretStore) GetMessageSecret(ctx context.Context, chat, sender types.JID, id types.MessageID) ([]byte, types.JID, error) {
+	secret, realSender, err := s.MsgSecretStore.GetMessageSecret(ctx, chat, sender, id)
+	if err != nil {
+		return nil, realSender, err
+	}
+	secret, err = s.cipher.open(purposeMessageSecret, secret)
+	return secret, realSender, err
+}
+
+type sealedEventBuffer struct {
+	store.EventBuffer
+	cipher *valueCipher
+}
+
+func (s *sealedEventBuffer) GetBufferedEvent(ctx context.Context, ciphertextHash [32]byte) (*store.BufferedEvent, error) {
+	event, err := s.EventBuffer.GetBufferedEvent(ctx, ciphertextHash)
+	if err != nil || event == nil {
+		return event, err
+	}
+	if event.Plaintext, err = s.cipher.open(purposeBufferedEvent, event.Plaintext); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (s *sealedEventBuffer) PutBufferedEvent(ctx context.Context, ciphertextHash [32]byte, plaintext []byte, serverTimestamp time.Time) error {
+	sealed, err := s.cipher.seal(purposeBufferedEvent, plaintext)
+	if err != nil {
+		return err
+	}
+	return s.EventBuffer.PutBufferedEvent(ctx, ciphertextHash, sealed, serverTimestamp)
+}
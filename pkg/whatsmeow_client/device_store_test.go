@@ -0,0 +1,137 @@
+package whatsmeow_client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func openTestStore(t *testing.T, path, key, previousKeys string) *DeviceStore {
+	t.Helper()
+	ds, err := OpenStore(StoreConfig{Driver: StoreDriverSQLite, Path: path, Key: key, PreviousKeys: previousKeys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func rawColumn(t *testing.T, ds *DeviceStore, query string, args ...interface{}) []byte {
+	t.Helper()
+	var value []byte
+	if err := ds.db.QueryRow(context.Background(), query, args...).Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+// TestDeviceStoreEncryption follows a device from a plaintext store through
+// encryption and a store key rotation
+func TestDeviceStoreEncryption(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "whatsmeow.db")
+	oldKey, newKey := newStoreKey(t), newStoreKey(t)
+	jid := types.NewADJID("919876543210", 0, 1)
+	address := "919876543211.0"
+	session := []byte("\x0a\x20signal session state")
+
+	// A device saved before encryption was enabled
+	ds := openTestStore(t, path, "", "")
+	device := ds.NewDevice()
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{
+		Details:             []byte{1},
+		AccountSignature:    make([]byte, 64),
+		AccountSignatureKey: make([]byte, 32),
+		DeviceSignature:     make([]byte, 64),
+	}
+	if err := ds.PutDevice(ctx, device); err != nil {
+		t.Fatal(err)
+	}
+	if err := device.Sessions.PutSession(ctx, address, session); err != nil {
+		t.Fatal(err)
+	}
+	noiseKey := *device.NoiseKey.Priv
+	ds.Close()
+
+	checkDevice := func(t *testing.T, ds *DeviceStore) {
+		t.Helper()
+		loaded, err := ds.GetDevice(ctx, jid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *loaded.NoiseKey.Priv != noiseKey {
+			t.Error("loaded a different noise key")
+		}
+		got, err := loaded.Sessions.GetSession(ctx, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, session) {
+			t.Errorf("session = %q, want %q", got, session)
+		}
+	}
+
+	t.Run("encrypted on open", func(t *testing.T) {
+		ds := openTestStore(t, path, oldKey, "")
+		defer ds.Close()
+
+		if stored := rawColumn(t, ds, "SELECT noise_key FROM whatsmeow_device WHERE jid=$1", jid.String()); !bytes.Equal(stored, placeholderKey[:]) {
+			t.Error("the noise key is still stored in whatsmeow_device")
+		}
+		stored := rawColumn(t, ds, "SELECT session FROM whatsmeow_sessions WHERE our_jid=$1 AND their_id=$2", jid.String(), address)
+		if !isSealed(stored) || bytes.Contains(stored, session) {
+			t.Error("the session is still stored in plaintext")
+		}
+		sealed, err := ds.SealDevices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed != 0 {
+			t.Errorf("encrypted %d devices again", sealed)
+		}
+		checkDevice(t, ds)
+	})
+
+	t.Run("no key", func(t *testing.T) {
+		_, err := OpenStore(StoreConfig{Driver: StoreDriverSQLite, Path: path})
+		if !errors.Is(err, ErrStoreKeyRequired) {
+			t.Fatalf("error = %v, want ErrStoreKeyRequired", err)
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		ds := openTestStore(t, path, newKey, oldKey)
+		defer ds.Close()
+
+		checkDevice(t, ds)
+		rotated, err := ds.RotateKey(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rotated != 1 {
+			t.Fatalf("rotated %d data keys, want 1", rotated)
+		}
+		if rotated, err = ds.RotateKey(ctx); err != nil || rotated != 0 {
+			t.Fatalf("rotating again rotated %d data keys (%v), want 0", rotated, err)
+		}
+	})
+
+	t.Run("old key dropped", func(t *testing.T) {
+		ds := openTestStore(t, path, newKey, "")
+		defer ds.Close()
+		checkDevice(t, ds)
+	})
+
+	t.Run("only the old key", func(t *testing.T) {
+		ds := openTestStore(t, path, oldKey, "")
+		defer ds.Close()
+		if _, err := ds.GetDevice(ctx, jid); err == nil {
+			t.Fatal("loaded a device whose data key was rotated away from this key")
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package whatsmeow_client
+
+import "testing"
+
+type panickingHandler struct{}
+
+func (panickingHandler) HandleMessage(userID string, message interface{}) {
+	panic("broken handler")
+}
+
+type recordingHandler struct{ events []interface{} }
+
+func (h *recordingHandler) HandleMessage(userID string, message interface{}) {
+	h.events = append(h.events, message)
+}
+
+func TestDispatchEventSurvivesPanickingHandler(t *testing.T) {
+	m := &Manager{}
+	after := &recordingHandler{}
+	m.AddEventHandler(panickingHandler{})
+	m.AddEventHandler(after)
+
+	m.dispatchEvent("session_1", "event")
+
+	if len(after.events) != 1 {
+		t.Fatalf("handler after the panicking one got %d events, want 1", len(after.events))
+	}
+}
@@ -3,6 +3,7 @@ package whatsmeow_client
 import (
 	"fmt"
 
+	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
@@ -10,6 +11,7 @@ import (
 type MessageEvent struct {
 	ID        string
 	From      string
+	Sender    string
 	Body      string
 	FromMe    bool
 	Timestamp int64
@@ -31,6 +33,7 @@ func ExtractMessageEvent(evt interface{}) (*MessageEvent, error) {
 		return &MessageEvent{
 			ID:        v.Info.ID,
 			From:      v.Info.Chat.String(),
+			Sender:    v.Info.Sender.ToNonAD().String(),
 			Body:      body,
 			FromMe:    v.Info.IsFromMe,
 			Timestamp: v.Info.Timestamp.Unix(),
@@ -40,4 +43,53 @@ func ExtractMessageEvent(evt interface{}) (*MessageEvent, error) {
 	default:
 		return nil, fmt.Errorf("not a message event")
 	}
-}
\ No newline at end of file
+}
+
+// MessagePreview returns a short human readable summary of a message for chat lists
+func MessagePreview(msg *waProto.Message) string {
+	if msg == nil {
+		return ""
+	}
+
+	withCaption := func(label, caption string) string {
+		if caption == "" {
+			return label
+		}
+		return label + " " + caption
+	}
+
+	switch {
+	case msg.GetConversation() != "":
+		return msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return withCaption("📷 Photo", msg.GetImageMessage().GetCaption())
+	case msg.GetVideoMessage() != nil:
+		return withCaption("🎥 Video", msg.GetVideoMessage().GetCaption())
+	case msg.GetAudioMessage() != nil:
+		if msg.GetAudioMessage().GetPTT() {
+			return "🎤 Voice message"
+		}
+		return "🎵 Audio"
+	case msg.GetDocumentMessage() != nil:
+		return withCaption("📄 "+msg.GetDocumentMessage().GetFileName(), msg.GetDocumentMessage().GetCaption())
+	case msg.GetStickerMessage() != nil:
+		return "Sticker"
+	case msg.GetLocationMessage() != nil:
+		return withCaption("📍 Location", msg.GetLocationMessage().GetName())
+	case msg.GetContactMessage() != nil:
+		return "👤 " + msg.GetContactMessage().GetDisplayName()
+	case msg.GetContactsArrayMessage() != nil:
+		return "👥 " + msg.GetContactsArrayMessage().GetDisplayName()
+	case msg.GetPollCreationMessage() != nil:
+		return "📊 " + msg.GetPollCreationMessage().GetName()
+	case msg.GetPollCreationMessageV3() != nil:
+		return "📊 " + msg.GetPollCreationMessageV3().GetName()
+	case msg.GetEphemeralMessage() != nil:
+		return MessagePreview(msg.GetEphemeralMessage().GetMessage())
+	case msg.GetViewOnceMessage() != nil:
+		return MessagePreview(msg.GetViewOnceMessage().GetMessage())
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package whatsmeow_client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// hibernationCheckInterval is how often idle sessions and due wake-ups are checked
+const hibernationCheckInterval = 30 * time.Second
+
+// HibernationPolicy disconnects sessions that were idle for a while. WhatsApp
+// queues messages for offline devices, so hibernating sessions still receive
+// them on scheduled wake-ups, and sending wakes a session up on demand.
+type HibernationPolicy struct {
+	IdleAfter   time.Duration // No sends or received messages for this long, 0 disables hibernation
+	WakeEvery   time.Duration // Reconnect hibernating sessions this often to receive queued messages, 0 never
+	WakeFor     time.Duration // How long a scheduled wake-up stays connected without activity
+	WakeTimeout time.Duration // How long a send waits for a hibernating session to connect
+}
+
+// Enabled reports whether idle sessions are hibernated
+func (p HibernationPolicy) Enabled() bool {
+	return p.IdleAfter > 0
+}
+
+// HibernationInfo describes a session's activity and hibernation
+type HibernationInfo struct {
+	Hibernating  bool       `json:"hibernating"`
+	LastActivity time.Time  `json:"last_activity"`
+	HibernatedAt *time.Time `json:"hibernated_at,omitempty"`
+	NextWakeAt   *time.Time `json:"next_wake_at,omitempty"`
+}
+
+// hibernationState is kept per session and guarded by ClientData.mu
+type hibernationState struct {
+	info       HibernationInfo
+	waking     bool      // Connecting after hibernation, not connected yet
+	awakeUntil time.Time // Scheduled wake-ups stay connected at least until then
+}
+
+// HibernationInfo returns a copy of the session's hibernation state
+func (cd *ClientData) HibernationInfo() HibernationInfo {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.hibernation.info
+}
+
+// touch records activity, which keeps the session from hibernating
+func (cd *ClientData) touch() {
+	cd.mu.Lock()
+	cd.hibernation.info.LastActivity = time.Now()
+	cd.mu.Unlock()
+}
+
+// endWake clears the waking flag once the connection attempt has an outcome
+func (cd *ClientData) endWake() {
+	cd.mu.Lock()
+	cd.hibernation.waking = false
+	cd.mu.Unlock()
+}
+
+// StartHibernation checks sessions in the background and hibernates idle
+// ones. Call it once, before sessions are restored.
+func (m *Manager) StartHibernation(policy HibernationPolicy) {
+	if !policy.Enabled() {
+		return
+	}
+	if policy.WakeTimeout <= 0 {
+		policy.WakeTimeout = 20 * time.Second
+	}
+	m.hibernation = policy
+
+	log.Printf("💤 Hibernating sessions idle for %s", policy.IdleAfter)
+	go func() {
+		ticker := time.NewTicker(hibernationCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m.checkHibernation()
+		}
+	}()
+}
+
+// HibernationEnabled reports whether idle sessions are hibernated
+func (m *Manager) HibernationEnabled() bool {
+	return m.hibernation.Enabled()
+}
+
+// checkHibernation hibernates idle sessions and wakes up those that are due
+func (m *Manager) checkHibernation() {
+	m.mu.RLock()
+	clients := make(map[string]*ClientData, len(m.clients))
+	for userID, clientData := range m.clients {
+		clients[userID] = clientData
+	}
+	m.mu.RUnlock()
+
+	now := time.Now()
+	for userID, clientData := range clients {
+		clientData.mu.RLock()
+		state := clientData.hibernation
+		status := clientData.status
+		clientData.mu.RUnlock()
+
+		if state.info.Hibernating {
+			if state.info.NextWakeAt != nil && !now.Before(*state.info.NextWakeAt) {
+				go m.wakeScheduled(userID, clientData)
+			}
+			continue
+		}
+
+		// Only connected, linked sessions that aren't busy importing history
+		if status != StatusReady || state.waking || now.Before(state.awakeUntil) ||
+			now.Sub(state.info.LastActivity) < m.hibernation.IdleAfter ||
+			clientData.Client.Store.ID == nil || clientData.IsQRChannelActive() || clientData.importingHistory() {
+			continue
+		}
+		m.hibernate(userID, clientData)
+	}
+}
+
+// importingHistory reports whether the phone is still sending history
+func (cd *ClientData) importingHistory() bool {
+	progress := cd.HistorySync()
+	return progress != nil && progress.InProgress
+}
+
+// hibernate disconnects an idle session. It stays in memory, so it can be
+// woken up without loading the device again.
+func (m *Manager) hibernate(userID string, clientData *ClientData) {
+	clientData.sleepMu.Lock()
+	defer clientData.sleepMu.Unlock()
+
+	clientData.mu.Lock()
+	state := &clientData.hibernation
+	if state.info.Hibernating || state.waking || clientData.status != StatusReady ||
+		time.Since(state.info.LastActivity) < m.hibernation.IdleAfter {
+		clientData.mu.Unlock()
+		return // Used again in the meantime
+	}
+	now := time.Now()
+	state.info.Hibernating = true
+	state.info.HibernatedAt = &now
+	state.info.NextWakeAt = nil
+	if m.hibernation.WakeEvery > 0 {
+		next := now.Add(m.hibernation.WakeEvery)
+		state.info.NextWakeAt = &next
+	}
+	lastActivity := state.info.LastActivity
+	clientData.mu.Unlock()
+
+	clientData.cancelReconnect()
+	clientData.Client.Disconnect()
+	clientData.SetStatusWithReason(StatusHibernating, fmt.Sprintf("idle since %s", lastActivity.Format(time.RFC3339)))
+	m.publishSessionEvent(userID, clientData, SessionEventHibernated, nil)
+
+	log.Printf("💤 Hibernated session %s, idle since %s", userID, lastActivity.Format(time.RFC3339))
+}
+
+// wakeScheduled connects a hibernating session for a while to receive the
+// messages WhatsApp queued for it
+func (m *Manager) wakeScheduled(userID string, clientData *ClientData) {
+	clientData.sleepMu.Lock()
+	defer clientData.sleepMu.Unlock()
+
+	if !clientData.startWake() {
+		return
+	}
+	clientData.mu.Lock()
+	clientData.hibernation.awakeUntil = time.Now().Add(m.hibernation.WakeFor)
+	clientData.mu.Unlock()
+
+	if err := m.wakeSession(userID, clientData, "scheduled wake-up"); err != nil {
+		log.Printf("❌ Scheduled wake-up of session %s failed, retrying in the background: %v", userID, err)
+	}
+}
+
+// Wake makes sure a session is connected before it is used. Hibernating
+// sessions are reconnected and Wake waits for the Connected event; sessions
+// that aren't hibernating are returned to right away, whatever their status.
+func (m *Manager) Wake(userID string) error {
+	clientData, exists := m.GetClient(userID)
+	if !exists {
+		return fmt.Errorf("session %s not found", userID)
+	}
+	clientData.touch()
+
+	if !m.hibernation.Enabled() {
+		return nil
+	}
+
+	events, unsubscribe := m.SubscribeSessionEvents(userID)
+	defer unsubscribe()
+
+	clientData.sleepMu.Lock()
+	started := clientData.startWake()
+	clientData.mu.RLock()
+	waking := clientData.hibernation.waking
+	clientData.mu.RUnlock()
+	if started {
+		if err := m.wakeSession(userID, clientData, "woken up on demand"); err != nil {
+			clientData.sleepMu.Unlock()
+			return fmt.Errorf("failed to wake up session: %w", err)
+		}
+	}
+	clientData.sleepMu.Unlock()
+
+	if !waking || clientData.GetStatus() == StatusReady {
+		return nil
+	}
+
+	err := waitConnected(events, m.hibernation.WakeTimeout,
+		SessionEventLoggedOut, SessionEventStreamReplaced, SessionEventClientOutdated,
+		SessionEventTemporaryBan, SessionEventConnectFailure, SessionEventDisconnected)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errConnectTimeout):
+		return fmt.Errorf("session didn't wake up within %s", m.hibernation.WakeTimeout)
+	case errors.Is(err, errSessionClosed):
+		return errors.New("session was closed while waking up")
+	default:
+		return fmt.Errorf("session didn't wake up: %w", err)
+	}
+}
+
+// startWake leaves hibernation. It returns false when the session isn't
+// hibernating. Callers hold sleepMu.
+func (cd *ClientData) startWake() bool {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if !cd.hibernation.info.Hibernating {
+		return false
+	}
+	cd.hibernation.info.Hibernating = false
+	cd.hibernation.info.HibernatedAt = nil
+	cd.hibernation.info.NextWakeAt = nil
+	cd.hibernation.waking = true
+	return true
+}
+
+// wakeSession reconnects a session that left hibernation. Success is only
+// confirmed by the Connected event; a failed attempt is left to the supervisor.
+func (m *Manager) wakeSession(userID string, clientData *ClientData, reason string) error {
+	clientData.SetStatusWithReason(StatusReconnecting, reason)
+	m.publishSessionEvent(userID, clientData, SessionEventReconnecting, nil)
+
+	err := clientData.Client.Connect()
+	if err == nil || errors.Is(err, whatsmeow.ErrAlreadyConnected) {
+		log.Printf("⏰ Waking session %s (%s)", userID, reason)
+		return nil
+	}
+
+	clientData.endWake()
+	clientData.SetStatusWithReason(StatusDisconnected, err.Error())
+	m.scheduleReconnect(userID, clientData, DisconnectTemporary, err, 0)
+	return err
+}
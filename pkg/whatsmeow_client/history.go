@@ -0,0 +1,82 @@
+package whatsmeow_client
+
+import (
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// HistorySyncProgress describes the history sync a phone sends after pairing
+type HistorySyncProgress struct {
+	InProgress       bool       `json:"in_progress"`
+	SyncType         string     `json:"sync_type,omitempty"`
+	Progress         int        `json:"progress"` // Percent, as reported by the phone
+	Chunks           int        `json:"chunks"`
+	Conversations    int        `json:"conversations"`
+	MessagesReceived int        `json:"messages_received"`
+	MessagesStored   int        `json:"messages_stored"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// SetHistorySyncDays limits how many days of history phones send to newly paired
+// sessions. It only affects pairings made after the call.
+func SetHistorySyncDays(days int) {
+	if days <= 0 {
+		return
+	}
+	store.DeviceProps.HistorySyncConfig.FullSyncDaysLimit = proto.Uint32(uint32(days))
+	store.DeviceProps.HistorySyncConfig.RecentSyncDaysLimit = proto.Uint32(uint32(days))
+}
+
+// trackHistorySync updates the session's history sync progress with a received chunk
+func (cd *ClientData) trackHistorySync(evt *events.HistorySync) {
+	messages := 0
+	for _, conv := range evt.Data.GetConversations() {
+		messages += len(conv.GetMessages())
+	}
+
+	now := time.Now()
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if cd.historySync.StartedAt == nil {
+		cd.historySync.StartedAt = &now
+	}
+	cd.historySync.UpdatedAt = &now
+	cd.historySync.SyncType = strings.ToLower(evt.Data.GetSyncType().String())
+	cd.historySync.Chunks++
+	cd.historySync.Conversations += len(evt.Data.GetConversations())
+	cd.historySync.MessagesReceived += messages
+
+	// Only chunks with conversations carry a meaningful progress value
+	if evt.Data.Progress != nil {
+		cd.historySync.Progress = int(evt.Data.GetProgress())
+	}
+	cd.historySync.InProgress = cd.historySync.Progress < 100
+}
+
+// RecordHistoryMessagesStored adds to the number of history messages that were
+// new to the message store
+func (cd *ClientData) RecordHistoryMessagesStored(count int) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	cd.historySync.MessagesStored += count
+}
+
+// HistorySync returns a snapshot of the session's history sync progress, or nil
+// if no history has been received
+func (cd *ClientData) HistorySync() *HistorySyncProgress {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+
+	if cd.historySync.Chunks == 0 {
+		return nil
+	}
+	progress := cd.historySync
+	return &progress
+}
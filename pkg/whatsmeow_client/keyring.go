@@ -0,0 +1,207 @@
+package whatsmeow_client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Device store encryption uses envelope encryption: every device gets its own
+// random data key, which encrypts the device's keys and signal state, and the
+// data key is stored wrapped (encrypted) with the store key from the config.
+// Rotating the store key only rewraps the data keys.
+
+// sealedPrefix marks encrypted values. whatsmeow stores protobufs and raw
+// keys, and a protobuf never starts with a zero byte.
+var sealedPrefix = []byte{0x00, 'W', 'K', 0x01}
+
+// ErrStoreKeyRequired is returned when an encrypted device store is opened without a key
+var ErrStoreKeyRequired = errors.New("the device store is encrypted, set WHATSMEOW_STORE_KEY or WHATSMEOW_STORE_KEY_FILE")
+
+type storeKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring holds the store key new data keys are wrapped with and the previous
+// keys still needed to unwrap data keys that haven't been rotated yet
+type Keyring struct {
+	current *storeKey
+	keys    map[string]*storeKey
+}
+
+// LoadKeyring builds the keyring from a base64 encoded 32 byte key, or from a
+// file holding the current key on its first line and previous keys on the
+// following lines, plus comma separated previous keys. It returns nil when no
+// key is configured.
+func LoadKeyring(key, keyFile, previousKeys string) (*Keyring, error) {
+	var encoded []string
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read store key file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				encoded = append(encoded, line)
+			}
+		}
+		if len(encoded) == 0 {
+			return nil, fmt.Errorf("store key file %s is empty", keyFile)
+		}
+		if key != "" {
+			return nil, fmt.Errorf("set either a store key or a store key file, not both")
+		}
+	} else if key != "" {
+		encoded = append(encoded, key)
+	}
+
+	for _, previous := range strings.Split(previousKeys, ",") {
+		if previous = strings.TrimSpace(previous); previous != "" {
+			encoded = append(encoded, previous)
+		}
+	}
+
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+	if key == "" && keyFile == "" {
+		return nil, fmt.Errorf("previous store keys are set without a current key")
+	}
+
+	keyring := &Keyring{keys: make(map[string]*storeKey, len(encoded))}
+	for i, value := range encoded {
+		k, err := parseStoreKey(value)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			keyring.current = k
+		}
+		keyring.keys[k.id] = k
+	}
+	return keyring, nil
+}
+
+// GenerateStoreKey returns a new random store key, base64 encoded
+func GenerateStoreKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+func parseStoreKey(value string) (*storeKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("store keys must be 32 random bytes, base64 encoded (openssl rand -base64 32)")
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are referred to by a fingerprint so the key itself is never stored
+	sum := sha256.Sum256(raw)
+	return &storeKey{id: hex.EncodeToString(sum[:8]), aead: aead}, nil
+}
+
+// CurrentID returns the fingerprint of the key new data keys are wrapped with
+func (k *Keyring) CurrentID() string {
+	return k.current.id
+}
+
+// wrap encrypts a device's data key with the current store key
+func (k *Keyring) wrap(dataKey []byte, jid string) (string, []byte, error) {
+	wrapped, err := seal(k.current.aead, dataKey, []byte("data-key|"+jid))
+	return k.current.id, wrapped, err
+}
+
+// unwrap decrypts a device's data key with the store key it was wrapped with
+func (k *Keyring) unwrap(keyID string, wrapped []byte, jid string) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("data key of device %s is wrapped with store key %s, which isn't configured", jid, keyID)
+	}
+	dataKey, err := open(key.aead, wrapped, []byte("data-key|"+jid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of device %s: %w", jid, err)
+	}
+	return dataKey, nil
+}
+
+// valueCipher encrypts the values of one device with its data key. The device
+// and the kind of value are authenticated, so values can't be swapped around.
+type valueCipher struct {
+	aead cipher.AEAD
+	jid  string
+}
+
+func newValueCipher(dataKey []byte, jid string) (*valueCipher, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &valueCipher{aead: aead, jid: jid}, nil
+}
+
+func (v *valueCipher) seal(purpose string, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return plaintext, nil
+	}
+	sealed, err := seal(v.aead, plaintext, []byte(purpose+"|"+v.jid))
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, sealedPrefix...), sealed...), nil
+}
+
+// open decrypts a value. Values written before the store was encrypted are
+// returned as they are.
+func (v *valueCipher) open(purpose string, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	plaintext, err := open(v.aead, data[len(sealedPrefix):], []byte(purpose+"|"+v.jid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s of device %s: %w", purpose, v.jid, err)
+	}
+	return plaintext, nil
+}
+
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedPrefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with AES-GCM and a random nonce, which is prepended
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, data, additionalData []byte) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], additionalData)
+}
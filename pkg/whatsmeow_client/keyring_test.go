@@ -0,0 +1,268 @@
+package whatsmeow_client
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJID = "919876543210.0:1@s.whatsapp.net"
+
+func newStoreKey(t *testing.T) string {
+	t.Helper()
+	key, err := GenerateStoreKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func mustKeyring(t *testing.T, key, previousKeys string) *Keyring {
+	t.Helper()
+	keyring, err := LoadKeyring(key, "", previousKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestLoadKeyring(t *testing.T) {
+	current, previous := newStoreKey(t), newStoreKey(t)
+	keyFile := filepath.Join(t.TempDir(), "store.key")
+	if err := os.WriteFile(keyFile, []byte("# rotated 2026-10-01\n"+current+"\n\n"+previous+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		keyFile  string
+		previous string
+		wantNil  bool
+		wantErr  string
+		wantKeys int
+	}{
+		{name: "nothing configured", wantNil: true},
+		{name: "key", key: current, wantKeys: 1},
+		{name: "key with previous keys", key: current, previous: " " + previous + ", ", wantKeys: 2},
+		{name: "key file with comments", keyFile: keyFile, wantKeys: 2},
+		{name: "short key", key: "c2hvcnQ=", wantErr: "32 random bytes"},
+		{name: "not base64", key: "not a key!", wantErr: "32 random bytes"},
+		{name: "bad previous key", key: current, previous: "c2hvcnQ=", wantErr: "32 random bytes"},
+		{name: "key and key file", key: current, keyFile: keyFile, wantErr: "not both"},
+		{name: "previous keys only", previous: previous, wantErr: "without a current key"},
+		{name: "missing key file", keyFile: filepath.Join(t.TempDir(), "missing"), wantErr: "failed to read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := LoadKeyring(tt.key, tt.keyFile, tt.previous)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if keyring != nil {
+					t.Fatal("keyring without any key, want nil")
+				}
+				return
+			}
+			if len(keyring.keys) != tt.wantKeys {
+				t.Errorf("%d keys, want %d", len(keyring.keys), tt.wantKeys)
+			}
+			if keyring.CurrentID() != mustKeyring(t, current, "").CurrentID() {
+				t.Error("the first key isn't the current one")
+			}
+		})
+	}
+}
+
+func TestKeyringWrap(t *testing.T) {
+	oldKey, newKey := newStoreKey(t), newStoreKey(t)
+	oldKeyring := mustKeyring(t, oldKey, "")
+	dataKey := bytes.Repeat([]byte{7}, 32)
+
+	keyID, wrapped, err := oldKeyring.wrap(dataKey, testJID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID != oldKeyring.CurrentID() {
+		t.Fatalf("wrapped with %s, want the current key %s", keyID, oldKeyring.CurrentID())
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Fatal("the wrapped data key contains the data key")
+	}
+
+	tampered := append([]byte{}, wrapped...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		keyring *Keyring
+		keyID   string
+		wrapped []byte
+		jid     string
+		wantErr string
+	}{
+		{name: "same keyring", keyring: oldKeyring, keyID: keyID, wrapped: wrapped, jid: testJID},
+		{name: "rotated, old key kept as previous", keyring: mustKeyring(t, newKey, oldKey), keyID: keyID, wrapped: wrapped, jid: testJID},
+		{name: "rotated, old key dropped", keyring: mustKeyring(t, newKey, ""), keyID: keyID, wrapped: wrapped, jid: testJID, wantErr: "isn't configured"},
+		{name: "another device", keyring: oldKeyring, keyID: keyID, wrapped: wrapped, jid: "919876543211.0:1@s.whatsapp.net", wantErr: "failed to unwrap"},
+		{name: "tampered", keyring: oldKeyring, keyID: keyID, wrapped: tampered, jid: testJID, wantErr: "failed to unwrap"},
+		{name: "truncated", keyring: oldKeyring, keyID: keyID, wrapped: wrapped[:4], jid: testJID, wantErr: "too short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.unwrap(tt.keyID, tt.wrapped, tt.jid)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, dataKey) {
+				t.Fatal("unwrapped a different data key")
+			}
+		})
+	}
+
+	t.Run("rewrap with the new key", func(t *testing.T) {
+		rotated := mustKeyring(t, newKey, oldKey)
+		if rotated.CurrentID() == oldKeyring.CurrentID() {
+			t.Fatal("the new key has the old key's ID")
+		}
+		newID, rewrapped, err := rotated.wrap(dataKey, testJID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newID != rotated.CurrentID() {
+			t.Fatalf("rewrapped with %s, want %s", newID, rotated.CurrentID())
+		}
+		got, err := mustKeyring(t, newKey, "").unwrap(newID, rewrapped, testJID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, dataKey) {
+			t.Fatal("unwrapped a different data key")
+		}
+	})
+}
+
+func TestValueCipher(t *testing.T) {
+	dataKey := bytes.Repeat([]byte{7}, 32)
+	vc, err := newValueCipher(dataKey, testJID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("\x0a\x20signal session state")
+
+	sealed, err := vc.seal(purposeSession, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSealed(sealed) {
+		t.Fatal("sealed value doesn't start with the sealed prefix")
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("sealed value contains the plaintext")
+	}
+	again, err := vc.seal(purposeSession, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Fatal("sealing twice gave the same ciphertext, the nonce isn't random")
+	}
+
+	otherDevice, err := newValueCipher(dataKey, "919876543211.0:1@s.whatsapp.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := newValueCipher(bytes.Repeat([]byte{8}, 32), testJID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[len(sealedPrefix)+12] ^= 1
+
+	tests := []struct {
+		name    string
+		cipher  *valueCipher
+		purpose string
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "round trip", cipher: vc, purpose: purposeSession, data: sealed, want: plaintext},
+		{name: "plaintext from before encryption", cipher: vc, purpose: purposeSession, data: plaintext, want: plaintext},
+		{name: "empty value", cipher: vc, purpose: purposeSession, data: []byte{}, want: []byte{}},
+		{name: "other purpose", cipher: vc, purpose: purposeSenderKey, data: sealed, wantErr: true},
+		{name: "other device", cipher: otherDevice, purpose: purposeSession, data: sealed, wantErr: true},
+		{name: "other data key", cipher: otherKey, purpose: purposeSession, data: sealed, wantErr: true},
+		{name: "tampered", cipher: vc, purpose: purposeSession, data: tampered, wantErr: true},
+		{name: "prefix only", cipher: vc, purpose: purposeSession, data: sealedPrefix, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.open(tt.purpose, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("opened a value that should be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("opened %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty values stay empty", func(t *testing.T) {
+		got, err := vc.seal(purposeSession, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Fatalf("sealed an empty value to %d bytes", len(got))
+		}
+	})
+}
+
+func TestIsSealed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{name: "sealed prefix", data: append(append([]byte{}, sealedPrefix...), 1, 2, 3), want: true},
+		{name: "prefix only", data: sealedPrefix, want: true},
+		{name: "protobuf", data: []byte{0x0a, 0x02, 'h', 'i'}},
+		{name: "raw key", data: bytes.Repeat([]byte{0x42}, 32)},
+		{name: "partial prefix", data: sealedPrefix[:3]},
+		{name: "other version", data: []byte{0x00, 'W', 'K', 0x02, 1}},
+		{name: "empty", data: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSealed(tt.data); got != tt.want {
+				t.Errorf("isSealed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ChatState is the activity shown to the other side of a chat
+type ChatState string
+
+const (
+	ChatStateComposing ChatState = "composing"
+	ChatStateRecording ChatState = "recording"
+	ChatStatePaused    ChatState = "paused"
+)
+
+// SetAvailability marks the account as online or offline for its contacts
+func (cd *ClientData) SetAvailability(available bool) error {
+	state := types.PresenceUnavailable
+	if available {
+		state = types.PresenceAvailable
+	}
+	return cd.Client.SendPresence(context.Background(), state)
+}
+
+// SetChatPresence shows "typing…" or "recording audio…" in a chat, or clears it
+func (cd *ClientData) SetChatPresence(jid types.JID, state ChatState) error {
+	switch state {
+	case ChatStateComposing:
+		return cd.Client.SendChatPresence(context.Background(), jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+	case ChatStateRecording:
+		return cd.Client.SendChatPresence(context.Background(), jid, types.ChatPresenceComposing, types.ChatPresenceMediaAudio)
+	case ChatStatePaused:
+		return cd.Client.SendChatPresence(context.Background(), jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+	default:
+		return fmt.Errorf("unknown chat state %q", state)
+	}
+}
+
+// SimulateTyping shows "typing…" in a chat for the given duration, blocking until it elapses.
+// Chat presence is only delivered while the account is available, so it is marked online first.
+func (cd *ClientData) SimulateTyping(jid types.JID, duration time.Duration) {
+	if err := cd.SetAvailability(true); err != nil {
+		log.Printf("Warning: failed to set availability before typing: %v", err)
+	}
+	if err := cd.SetChatPresence(jid, ChatStateComposing); err != nil {
+		log.Printf("Warning: failed to send typing indicator to %s: %v", jid, err)
+		return
+	}
+	time.Sleep(duration)
+	if err := cd.SetChatPresence(jid, ChatStatePaused); err != nil {
+		log.Printf("Warning: failed to clear typing indicator for %s: %v", jid, err)
+	}
+}
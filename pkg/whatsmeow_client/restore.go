@@ -0,0 +1,260 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// restoreConnectTimeout is how long a restore worker waits for a session to
+// connect before moving on. The session keeps connecting in the background.
+const restoreConnectTimeout = 30 * time.Second
+
+// Per-session restore states
+const (
+	RestorePending    = "pending"
+	RestoreConnecting = "connecting"
+	RestoreRestored   = "restored"
+	RestoreTimedOut   = "timed_out" // Still connecting or retrying in the background
+	RestoreFailed     = "failed"
+	RestoreSkipped    = "skipped"
+)
+
+// SessionRestore is the restore state of one session
+type SessionRestore struct {
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// RestoreProgress summarizes session restoration after startup
+type RestoreProgress struct {
+	Started     bool       `json:"started"`
+	Completed   bool       `json:"completed"`
+	Total       int        `json:"total"`
+	Restored    int        `json:"restored"`
+	TimedOut    int        `json:"timed_out"`
+	Failed      int        `json:"failed"`
+	Skipped     int        `json:"skipped"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// restoreTracker holds restore progress, guarded by its own lock
+type restoreTracker struct {
+	progress RestoreProgress
+	sessions map[string]*SessionRestore
+	mu       sync.RWMutex
+}
+
+// RestoreProgress returns a copy of the restore progress
+func (m *Manager) RestoreProgress() RestoreProgress {
+	m.restore.mu.RLock()
+	defer m.restore.mu.RUnlock()
+	return m.restore.progress
+}
+
+// IsRestored reports whether session restoration has finished
+func (m *Manager) IsRestored() bool {
+	return m.RestoreProgress().Completed
+}
+
+// SessionRestoreStatus returns the restore state of a session, if it was restored at startup
+func (m *Manager) SessionRestoreStatus(userID string) *SessionRestore {
+	m.restore.mu.RLock()
+	defer m.restore.mu.RUnlock()
+
+	state, exists := m.restore.sessions[userID]
+	if !exists {
+		return nil
+	}
+	copied := *state
+	return &copied
+}
+
+func (m *Manager) setRestoreState(userID, state string, err error) {
+	m.restore.mu.Lock()
+	defer m.restore.mu.Unlock()
+
+	entry := m.restore.sessions[userID]
+	if entry == nil {
+		return
+	}
+	entry.State = state
+	entry.Error = ""
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if m.restore.progress.Completed {
+		return // Sessions taken over later don't change the startup summary
+	}
+	switch state {
+	case RestoreRestored:
+		m.restore.progress.Restored++
+	case RestoreTimedOut:
+		m.restore.progress.TimedOut++
+	case RestoreFailed:
+		m.restore.progress.Failed++
+	case RestoreSkipped:
+		m.restore.progress.Skipped++
+	}
+}
+
+// finishRestore marks restoration as completed, with the error that stopped it if any
+func (m *Manager) finishRestore(err error) {
+	m.restore.mu.Lock()
+	now := time.Now()
+	m.restore.progress.Completed = true
+	m.restore.progress.CompletedAt = &now
+	if err != nil {
+		m.restore.progress.Error = err.Error()
+	}
+	progress := m.restore.progress
+	m.restore.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Warning: failed to restore sessions: %v", err)
+		return
+	}
+	log.Printf("📊 Session restoration complete: %d restored, %d still connecting, %d failed, %d skipped, %d total",
+		progress.Restored, progress.TimedOut, progress.Failed, progress.Skipped, progress.Total)
+}
+
+// RestoreSessions reconnects every session with a linked device that include
+// accepts (all of them when include is nil), up to concurrency at a time. It
+// blocks until all sessions were handled, so run it in a goroutine to keep
+// startup fast and check IsRestored for readiness.
+func (m *Manager) RestoreSessions(concurrency int, include func(SessionRecord) bool) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	now := time.Now()
+	m.restore.mu.Lock()
+	m.restore.progress = RestoreProgress{Started: true, StartedAt: &now}
+	m.restore.sessions = make(map[string]*SessionRestore)
+	m.restore.mu.Unlock()
+
+	if m.store == nil {
+		m.finishRestore(nil)
+		return
+	}
+
+	records, err := m.store.LoadSessions()
+	if err != nil {
+		m.finishRestore(fmt.Errorf("failed to load sessions: %w", err))
+		return
+	}
+	if include != nil {
+		included := records[:0]
+		for _, record := range records {
+			if include(record) {
+				included = append(included, record)
+			}
+		}
+		records = included
+	}
+
+	devices, err := m.container.GetAllDevices(context.Background())
+	if err != nil {
+		m.finishRestore(fmt.Errorf("failed to get devices: %w", err))
+		return
+	}
+
+	deviceMap := make(map[string]*store.Device, len(devices))
+	for _, device := range devices {
+		if device.ID != nil {
+			deviceMap[device.ID.String()] = device
+		}
+	}
+
+	m.restore.mu.Lock()
+	m.restore.progress.Total = len(records)
+	for _, record := range records {
+		m.restore.sessions[record.UserID] = &SessionRestore{State: RestorePending}
+	}
+	m.restore.mu.Unlock()
+
+	log.Printf("Restoring %d sessions (%d devices in store, %d at a time)...", len(records), len(deviceMap), concurrency)
+
+	queue := make(chan SessionRecord)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range queue {
+				m.restoreSession(record, deviceMap[record.DeviceJID])
+			}
+		}()
+	}
+	for _, record := range records {
+		queue <- record
+	}
+	close(queue)
+	wg.Wait()
+
+	m.finishRestore(nil)
+}
+
+// restoreSession connects one session and waits until it is connected, fails
+// for good or restoreConnectTimeout passes
+func (m *Manager) restoreSession(record SessionRecord, device *store.Device) {
+	userID := record.UserID
+
+	if device == nil {
+		log.Printf("⚠️  Skipping user %s - device %s not found in whatsmeow store", userID, record.DeviceJID)
+		m.setRestoreState(userID, RestoreSkipped, fmt.Errorf("device %s not found in whatsmeow store", record.DeviceJID))
+		return
+	}
+
+	m.mu.Lock()
+	if _, exists := m.clients[userID]; exists {
+		m.mu.Unlock()
+		m.setRestoreState(userID, RestoreSkipped, fmt.Errorf("session was already started"))
+		return
+	}
+	clientData := &ClientData{
+		Client:    whatsmeow.NewClient(device, waLog.Noop),
+		Container: m.container,
+	}
+	clientData.SetStatus(StatusInitializing)
+	m.setupEventHandlers(userID, clientData)
+	m.clients[userID] = clientData
+	m.mu.Unlock()
+
+	events, unsubscribe := m.SubscribeSessionEvents(userID)
+	defer unsubscribe()
+
+	m.setRestoreState(userID, RestoreConnecting, nil)
+	if err := clientData.Client.Connect(); err != nil {
+		log.Printf("❌ Failed to connect for user %s, retrying in the background: %v", userID, err)
+		clientData.SetStatusWithReason(StatusDisconnected, err.Error())
+		m.scheduleReconnect(userID, clientData, DisconnectTemporary, err, 0)
+		m.setRestoreState(userID, RestoreFailed, err)
+		return
+	}
+
+	err := waitConnected(events, restoreConnectTimeout,
+		SessionEventLoggedOut, SessionEventStreamReplaced, SessionEventClientOutdated, SessionEventTemporaryBan)
+	switch {
+	case err == nil:
+		log.Printf("✅ Restored session for user %s (JID: %s)", userID, record.DeviceJID)
+		m.setRestoreState(userID, RestoreRestored, nil)
+	case errors.Is(err, errConnectTimeout):
+		log.Printf("⚠️  User %s didn't connect within %s, leaving it to connect in the background", userID, restoreConnectTimeout)
+		m.setRestoreState(userID, RestoreTimedOut, fmt.Errorf("not connected after %s", restoreConnectTimeout))
+	case errors.Is(err, errSessionClosed):
+		m.setRestoreState(userID, RestoreFailed, fmt.Errorf("session was closed while connecting"))
+	default:
+		m.setRestoreState(userID, RestoreFailed, err)
+	}
+}
@@ -0,0 +1,162 @@
+package whatsmeow_client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Session event types pushed to subscribers
+const (
+	SessionEventStatus       = "status" // Snapshot of the current state, sent when subscribing
+	SessionEventQRCode       = "qr"
+	SessionEventPairCode     = "pair_code"
+	SessionEventPairSuccess  = "pair_success"
+	SessionEventQRTimeout    = "qr_timeout"
+	SessionEventQRError      = "qr_error"
+	SessionEventConnected    = "connected"
+	SessionEventDisconnected = "disconnected"
+	SessionEventReconnecting = "reconnecting"
+	SessionEventLoggedOut    = "logged_out"
+	SessionEventHibernated   = "hibernated"
+
+	SessionEventTemporaryBan   = "temporary_ban"
+	SessionEventStreamReplaced = "stream_replaced"
+	SessionEventClientOutdated = "client_outdated"
+	SessionEventConnectFailure = "connect_failure"
+)
+
+// SessionEvent is a login or connection change of a session
+type SessionEvent struct {
+	Type      string        `json:"type"`
+	UserID    string        `json:"user_id"`
+	Status    SessionStatus `json:"status"`
+	Reason    string        `json:"reason,omitempty"`
+	QRCode    string        `json:"qr,omitempty"`
+	PairCode  string        `json:"pairing_code,omitempty"`
+	Reconnect ReconnectInfo `json:"reconnect"`
+	Error     string        `json:"error,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// SessionAlert is dispatched to the event handlers when WhatsApp bans, replaces
+// or logs out a session, or rejects the client, so its owner can be told
+type SessionAlert struct {
+	Status    SessionStatus
+	Reason    string
+	Permanent bool // The session won't come back without the owner's help
+	Timestamp time.Time
+}
+
+// sessionEventBus fans session events out to subscribers of each session
+type sessionEventBus struct {
+	subscribers map[string]map[chan SessionEvent]struct{}
+	mu          sync.Mutex
+}
+
+// SubscribeSessionEvents returns a channel receiving the session's events and a
+// function that must be called to unsubscribe. Slow subscribers miss events
+// rather than blocking the session.
+func (m *Manager) SubscribeSessionEvents(userID string) (<-chan SessionEvent, func()) {
+	ch := make(chan SessionEvent, 16)
+
+	m.sessionEvents.mu.Lock()
+	if m.sessionEvents.subscribers == nil {
+		m.sessionEvents.subscribers = make(map[string]map[chan SessionEvent]struct{})
+	}
+	if m.sessionEvents.subscribers[userID] == nil {
+		m.sessionEvents.subscribers[userID] = make(map[chan SessionEvent]struct{})
+	}
+	m.sessionEvents.subscribers[userID][ch] = struct{}{}
+	m.sessionEvents.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			m.sessionEvents.mu.Lock()
+			defer m.sessionEvents.mu.Unlock()
+			delete(m.sessionEvents.subscribers[userID], ch)
+			if len(m.sessionEvents.subscribers[userID]) == 0 {
+				delete(m.sessionEvents.subscribers, userID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// publishSessionEvent sends an event to every subscriber of the session
+func (m *Manager) publishSessionEvent(userID string, clientData *ClientData, eventType string, err error) {
+	evt := SessionEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Status:    clientData.GetStatus(),
+		Reason:    clientData.GetStatusReason(),
+		QRCode:    clientData.GetQRCode(),
+		PairCode:  clientData.GetPairCode(),
+		Reconnect: clientData.ReconnectInfo(),
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		evt.Error = err.Error()
+	}
+
+	m.sessionEvents.mu.Lock()
+	defer m.sessionEvents.mu.Unlock()
+
+	for ch := range m.sessionEvents.subscribers[userID] {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// SessionSnapshot describes the current state of a session as a status event
+func (cd *ClientData) SessionSnapshot(userID string) SessionEvent {
+	return SessionEvent{
+		Type:      SessionEventStatus,
+		UserID:    userID,
+		Status:    cd.GetStatus(),
+		Reason:    cd.GetStatusReason(),
+		QRCode:    cd.GetQRCode(),
+		PairCode:  cd.GetPairCode(),
+		Reconnect: cd.ReconnectInfo(),
+		Timestamp: time.Now(),
+	}
+}
+
+// Reasons waitConnected gives up
+var (
+	errSessionClosed  = errors.New("session was closed")
+	errConnectTimeout = errors.New("session didn't connect in time")
+)
+
+// waitConnected waits for a session to connect, using events subscribed to
+// before connecting so the Connected event can't be missed. An event of one
+// of the failures types ends the wait with its status and reason.
+func waitConnected(events <-chan SessionEvent, timeout time.Duration, failures ...string) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				return errSessionClosed
+			}
+			if evt.Type == SessionEventConnected {
+				return nil
+			}
+			for _, failure := range failures {
+				if evt.Type == failure {
+					return fmt.Errorf("%s: %s", evt.Status, evt.Reason)
+				}
+			}
+
+		case <-timer.C:
+			return errConnectTimeout
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package whatsmeow_client
+
+import "log"
+
+// SessionRecord is what the manager needs to restore a session after a restart
+type SessionRecord struct {
+	UserID    string
+	DeviceJID string
+}
+
+// SessionStore persists which device belongs to which session and every
+// status change, outside of the whatsmeow device store
+type SessionStore interface {
+	// LoadSessions returns the sessions that have a linked device
+	LoadSessions() ([]SessionRecord, error)
+	// RecordStatus stores a status change and its reason along with the
+	// session's current device JID
+	RecordStatus(userID string, status SessionStatus, reason, deviceJID string) error
+}
+
+// trackStatusChanges persists every status change of a session
+func (m *Manager) trackStatusChanges(userID string, clientData *ClientData) {
+	if m.store == nil {
+		return
+	}
+
+	clientData.mu.Lock()
+	defer clientData.mu.Unlock()
+
+	clientData.onStatusChange = func(status SessionStatus, reason string) {
+		deviceJID := ""
+		if clientData.Client.Store.ID != nil {
+			deviceJID = clientData.Client.Store.ID.String()
+		}
+		if err := m.store.RecordStatus(userID, status, reason, deviceJID); err != nil {
+			log.Printf("Warning: failed to record status %s for user %s: %v", status, userID, err)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"go.mau.fi/util/dbutil"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/store/sqlstore/upgrades"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Device store drivers. whatsmeow's sqlstore only supports SQLite and Postgres.
+const (
+	StoreDriverSQLite   = "sqlite"
+	StoreDriverPostgres = "postgres"
+)
+
+// StoreConfig selects where whatsmeow keeps devices and their keys
+type StoreConfig struct {
+	Driver string
+	Path   string // SQLite database file
+	DSN    string // Postgres connection string
+
+	// Key or KeyFile enable encryption at rest, PreviousKeys are comma
+	// separated keys still accepted for data keys that weren't rotated yet
+	Key          string
+	KeyFile      string
+	PreviousKeys string
+}
+
+// Validate checks that the driver is supported and has what it needs
+func (c StoreConfig) Validate() error {
+	switch c.Driver {
+	case StoreDriverSQLite:
+		if c.Path == "" {
+			return fmt.Errorf("a database path is required for the sqlite device store")
+		}
+	case StoreDriverPostgres:
+		if c.DSN == "" {
+			return fmt.Errorf("a connection string is required for the postgres device store")
+		}
+	case "mysql":
+		return fmt.Errorf("whatsmeow can't keep devices in MySQL, use %q or %q", StoreDriverSQLite, StoreDriverPostgres)
+	default:
+		return fmt.Errorf("unknown device store driver %q, use %q or %q", c.Driver, StoreDriverSQLite, StoreDriverPostgres)
+	}
+	if _, err := c.Keyring(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Keyring loads the store keys, nil when encryption is disabled
+func (c StoreConfig) Keyring() (*Keyring, error) {
+	return LoadKeyring(c.Key, c.KeyFile, c.PreviousKeys)
+}
+
+// String describes the store without credentials, for logs
+func (c StoreConfig) String() string {
+	if c.Driver == StoreDriverSQLite {
+		return "sqlite " + c.Path
+	}
+	return "postgres"
+}
+
+// openStoreDB opens the database behind a device store and returns it with its sqlstore dialect
+func openStoreDB(cfg StoreConfig) (*sql.DB, string, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	if cfg.Driver == StoreDriverPostgres {
+		db, err := sql.Open("postgres", cfg.DSN)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open postgres device store: %w", err)
+		}
+		if err := db.Ping(); err != nil {
+			db.Close()
+			return nil, "", fmt.Errorf("failed to connect to postgres device store: %w", err)
+		}
+		return db, storeDialect(cfg), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	// Foreign keys are enabled in the DSN, sqlstore refuses to work without them
+	db, err := gorm.Open(sqlite.Open(cfg.Path+"?_foreign_keys=on"), &gorm.Config{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open whatsmeow database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get database instance: %w", err)
+	}
+	return sqlDB, storeDialect(cfg), nil
+}
+
+// OpenStore opens a device store and brings its schema up to date. With a
+// store key, devices still stored in plaintext are encrypted right away.
+func OpenStore(cfg StoreConfig) (*DeviceStore, error) {
+	keyring, err := cfg.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
+	container, db, err := openStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceStore := newDeviceStore(container, db, keyring)
+	sealed, err := deviceStore.SealDevices(context.Background())
+	if err != nil {
+		deviceStore.Close()
+		if errors.Is(err, ErrStoreKeyRequired) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to encrypt device store: %w", err)
+	}
+	if sealed > 0 {
+		log.Printf("🔐 Encrypted %d devices stored in plaintext", sealed)
+	}
+	return deviceStore, nil
+}
+
+func openStore(cfg StoreConfig) (*sqlstore.Container, *dbutil.Database, error) {
+	rawDB, dialect, err := openStoreDB(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Wrapped the same way sqlstore.NewWithDB does, so our queries can share its transactions
+	db, err := dbutil.NewWithDB(rawDB, dialect)
+	if err != nil {
+		rawDB.Close()
+		return nil, nil, err
+	}
+	db.UpgradeTable = upgrades.Table
+	db.VersionTable = "whatsmeow_version"
+
+	container := sqlstore.NewWithWrappedDB(db, waLog.Noop)
+	if err := container.Upgrade(context.Background()); err != nil {
+		rawDB.Close()
+		return nil, nil, fmt.Errorf("failed to upgrade store: %w", err)
+	}
+	if err := ensureSecretsTable(context.Background(), db); err != nil {
+		rawDB.Close()
+		return nil, nil, fmt.Errorf("failed to create device secrets table: %w", err)
+	}
+	return container, db, nil
+}
+
+// RotateStoreKey rewraps the data keys of all devices with the current store
+// key, for after the key was replaced and the old one moved to PreviousKeys
+func RotateStoreKey(cfg StoreConfig) (int, error) {
+	deviceStore, err := OpenStore(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer deviceStore.Close()
+
+	return deviceStore.RotateKey(context.Background())
+}
+
+// MigrateStore copies every device with its keys, sessions and app state from
+// one device store to another. Rows that already exist in the destination are
+// left alone, so an interrupted migration can simply be run again. It returns
+// the number of rows copied per table.
+func MigrateStore(from, to StoreConfig) (map[string]int64, error) {
+	ctx := context.Background()
+
+	if from.Driver == to.Driver && from.Path == to.Path && from.DSN == to.DSN {
+		return nil, fmt.Errorf("source and destination are the same store")
+	}
+
+	// Both sides are upgraded so they have the same tables and columns.
+	// Encrypted values are copied as they are and need the same store key.
+	_, srcDB, err := openStore(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", from, err)
+	}
+	defer srcDB.Close()
+	_, dstDB, err := openStore(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", to, err)
+	}
+	defer dstDB.Close()
+	src, dst := srcDB.RawDB, dstDB.RawDB
+
+	srcDialect, dstDialect := storeDialect(from), storeDialect(to)
+
+	tables, err := storeTables(ctx, src, srcDialect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables of %s: %w", from, err)
+	}
+
+	tx, err := dst.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	copied := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		boolColumns, err := booleanColumns(ctx, dst, dstDialect, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+		}
+		count, err := copyTable(ctx, src, tx, table, dstDialect, boolColumns)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		copied[table] = count
+		log.Printf("📦 Copied %d rows of %s", count, table)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return copied, nil
+}
+
+func storeDialect(cfg StoreConfig) string {
+	if cfg.Driver == StoreDriverPostgres {
+		return "postgres"
+	}
+	return "sqlite3"
+}
+
+// storeTables lists whatsmeow's tables, parents before the tables referencing them
+func storeTables(ctx context.Context, db *sql.DB, dialect string) ([]string, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE 'whatsmeow_%'"
+	if dialect == "postgres" {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name LIKE 'whatsmeow_%'"
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if name != "whatsmeow_version" {
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Everything references the device, and mutation MACs reference app state versions
+	sortStoreTables(tables)
+	return tables, nil
+}
+
+// booleanColumns returns the BOOLEAN columns of a Postgres table. SQLite
+// stores booleans as integers, which Postgres doesn't convert by itself.
+func booleanColumns(ctx context.Context, db *sql.DB, dialect, table string) (map[string]bool, error) {
+	columns := make(map[string]bool)
+	if dialect != "postgres" {
+		return columns, nil
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 AND data_type = 'boolean'",
+		table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// copyTable inserts every row of a table into the destination, skipping rows that already exist
+func copyTable(ctx context.Context, src *sql.DB, dst *sql.Tx, table, dstDialect string, boolColumns map[string]bool) (int64, error) {
+	rows, err := src.QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = "?"
+		if dstDialect == "postgres" {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING",
+		table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	var copied int64
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return copied, err
+		}
+		for i, column := range columns {
+			if n, ok := values[i].(int64); ok && boolColumns[column] {
+				values[i] = n != 0
+			}
+		}
+
+		result, err := dst.ExecContext(ctx, insert, values...)
+		if err != nil {
+			return copied, err
+		}
+		affected, _ := result.RowsAffected()
+		copied += affected
+	}
+	return copied, rows.Err()
+}
@@ -0,0 +1,228 @@
+package whatsmeow_client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Reconnect backoff: the delay doubles from reconnectBaseDelay up to
+// reconnectMaxDelay, and a random part of it is taken off so sessions that
+// dropped together don't all come back at the same moment
+const (
+	reconnectBaseDelay = 2 * time.Second
+	reconnectMaxDelay  = 5 * time.Minute
+)
+
+// Disconnect kinds, deciding whether the supervisor reconnects
+const (
+	DisconnectTemporary      = "temporary"
+	DisconnectKeepAlive      = "keepalive_timeout"
+	DisconnectConnectFailure = "connect_failure"
+	DisconnectStreamReplaced = "stream_replaced"
+	DisconnectLoggedOut      = "logged_out"
+	DisconnectBanned         = "temporary_ban"
+	DisconnectClientOutdated = "client_outdated"
+)
+
+// ReconnectInfo describes the supervisor's view of a session's connection
+type ReconnectInfo struct {
+	Attempts         int        `json:"attempts"`
+	LastError        string     `json:"last_error,omitempty"`
+	DisconnectKind   string     `json:"disconnect_kind,omitempty"`
+	LastDisconnectAt *time.Time `json:"last_disconnect_at,omitempty"`
+	NextAttemptAt    *time.Time `json:"next_attempt_at,omitempty"`
+	Stopped          bool       `json:"stopped"` // The disconnect is permanent, nothing will be retried
+}
+
+// reconnectState is kept per session and guarded by ClientData.mu
+type reconnectState struct {
+	info  ReconnectInfo
+	timer *time.Timer
+}
+
+// ReconnectInfo returns a copy of the session's reconnect state
+func (cd *ClientData) ReconnectInfo() ReconnectInfo {
+	cd.mu.RLock()
+	defer cd.mu.RUnlock()
+	return cd.reconnect.info
+}
+
+// supervise takes over reconnecting from whatsmeow, whose built-in retry
+// loop has no jitter and doesn't tell us what it is doing
+func (m *Manager) supervise(clientData *ClientData) {
+	clientData.Client.EnableAutoReconnect = false
+}
+
+// handleConnectionEvent updates the reconnect state for connection events.
+// It returns false for events that aren't about the connection.
+func (m *Manager) handleConnectionEvent(userID string, clientData *ClientData, evt interface{}) bool {
+	switch v := evt.(type) {
+	case *events.Connected:
+		clientData.resetReconnect()
+
+	case *events.Disconnected:
+		m.scheduleReconnect(userID, clientData, DisconnectTemporary, errors.New("connection closed"), 0)
+
+	case *events.KeepAliveTimeout:
+		// Pings fail for a while before the socket notices, give up on it
+		// the same way whatsmeow does when it reconnects by itself
+		if time.Since(v.LastSuccess) < whatsmeow.KeepAliveMaxFailTime {
+			return true
+		}
+		clientData.Client.Disconnect()
+		m.scheduleReconnect(userID, clientData, DisconnectKeepAlive, fmt.Errorf("no keep-alive response since %s", v.LastSuccess.Format(time.RFC3339)), 0)
+
+	case *events.ConnectFailure:
+		m.scheduleReconnect(userID, clientData, DisconnectConnectFailure, fmt.Errorf("connect failure %s: %s", v.Reason, v.Message), 0)
+
+	case *events.TemporaryBan:
+		// Bans without an expiry aren't retried, the rest once they run out
+		if v.Expire <= 0 {
+			clientData.stopReconnect(DisconnectBanned, errors.New(v.String()))
+			return true
+		}
+		m.scheduleReconnect(userID, clientData, DisconnectBanned, errors.New(v.String()), v.Expire)
+
+	case *events.StreamReplaced:
+		// Another client connected with the same keys, reconnecting would kick it out in turn
+		clientData.stopReconnect(DisconnectStreamReplaced, errors.New("stream replaced by another connection"))
+
+	case *events.LoggedOut:
+		clientData.stopReconnect(DisconnectLoggedOut, fmt.Errorf("logged out: %s", v.Reason))
+
+	case *events.ClientOutdated:
+		clientData.stopReconnect(DisconnectClientOutdated, errors.New("client version rejected by WhatsApp, update whatsmeow"))
+
+	case *events.KeepAliveRestored:
+		// Nothing to do, the connection recovered by itself
+
+	default:
+		return false
+	}
+	return true
+}
+
+// scheduleReconnect records the disconnect and arms a timer for the next
+// attempt. A delay of zero uses the backoff for the current attempt count.
+func (m *Manager) scheduleReconnect(userID string, clientData *ClientData, kind string, cause error, delay time.Duration) {
+	clientData.mu.Lock()
+	defer clientData.mu.Unlock()
+
+	state := &clientData.reconnect
+	if state.info.Attempts == 0 && state.timer == nil {
+		now := time.Now()
+		state.info.LastDisconnectAt = &now
+	}
+	state.info.DisconnectKind = kind
+	state.info.LastError = cause.Error()
+	state.info.Stopped = false
+
+	if state.timer != nil {
+		return // An attempt is already pending
+	}
+
+	if delay <= 0 {
+		delay = reconnectDelay(state.info.Attempts)
+	}
+	next := time.Now().Add(delay)
+	state.info.NextAttemptAt = &next
+	state.timer = time.AfterFunc(delay, func() {
+		m.reconnect(userID, clientData)
+	})
+
+	log.Printf("🔄 Reconnecting user %s in %s (%s: %v)", userID, delay.Round(time.Second), kind, cause)
+}
+
+// reconnect makes one attempt and schedules the next one if it fails. A
+// successful attempt is only confirmed by the Connected event.
+func (m *Manager) reconnect(userID string, clientData *ClientData) {
+	clientData.mu.Lock()
+	clientData.reconnect.timer = nil
+	clientData.reconnect.info.NextAttemptAt = nil
+	clientData.reconnect.info.Attempts++
+	attempt := clientData.reconnect.info.Attempts
+	clientData.mu.Unlock()
+
+	// The session may have been logged out or replaced while we waited
+	if current, exists := m.GetClient(userID); !exists || current != clientData || clientData.Client.Store.ID == nil {
+		return
+	}
+
+	clientData.SetStatus(StatusReconnecting)
+	m.publishSessionEvent(userID, clientData, SessionEventReconnecting, nil)
+
+	log.Printf("🔄 Reconnect attempt #%d for user %s", attempt, userID)
+	err := clientData.Client.Connect()
+	if err == nil || errors.Is(err, whatsmeow.ErrAlreadyConnected) {
+		return
+	}
+
+	log.Printf("❌ Reconnect attempt #%d for user %s failed: %v", attempt, userID, err)
+	clientData.SetStatusWithReason(StatusDisconnected, err.Error())
+	m.scheduleReconnect(userID, clientData, DisconnectTemporary, err, 0)
+}
+
+// stopReconnect cancels pending attempts after a permanent disconnect
+func (cd *ClientData) stopReconnect(kind string, cause error) {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	state := &cd.reconnect
+	if state.timer != nil {
+		state.timer.Stop()
+		state.timer = nil
+	}
+	now := time.Now()
+	state.info.LastDisconnectAt = &now
+	state.info.DisconnectKind = kind
+	state.info.LastError = cause.Error()
+	state.info.NextAttemptAt = nil
+	state.info.Stopped = true
+}
+
+// resetReconnect clears the attempt counter once the session is connected.
+// The last error is kept for diagnostics.
+func (cd *ClientData) resetReconnect() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if cd.reconnect.timer != nil {
+		cd.reconnect.timer.Stop()
+		cd.reconnect.timer = nil
+	}
+	cd.reconnect.info.Attempts = 0
+	cd.reconnect.info.NextAttemptAt = nil
+	cd.reconnect.info.Stopped = false
+}
+
+// cancelReconnect stops pending attempts, e.g. when the user logs out
+func (cd *ClientData) cancelReconnect() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	if cd.reconnect.timer != nil {
+		cd.reconnect.timer.Stop()
+		cd.reconnect.timer = nil
+	}
+	cd.reconnect.info.NextAttemptAt = nil
+}
+
+// reconnectDelay returns the backoff before the given attempt with equal
+// jitter: half of the delay is fixed and the other half random
+func reconnectDelay(attempts int) time.Duration {
+	delay := reconnectMaxDelay
+	if attempts < 16 {
+		delay = reconnectBaseDelay << attempts
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,408 @@
+package whatsmeow_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/util/dbutil"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrDeviceExists is returned when importing a device the store already has
+var ErrDeviceExists = errors.New("the device already exists in this store")
+
+// Column kinds of exported values, so they can be written back to SQLite or Postgres
+const (
+	ValueNull  = "null"
+	ValueBytes = "bytes"
+	ValueText  = "text"
+	ValueInt   = "int"
+	ValueBool  = "bool"
+)
+
+// ExportValue is one column value of an exported row
+type ExportValue struct {
+	Kind  string `json:"k"`
+	Bytes []byte `json:"b,omitempty"`
+	Text  string `json:"s,omitempty"`
+	Int   int64  `json:"i,omitempty"`
+	Bool  bool   `json:"v,omitempty"`
+}
+
+// DeviceExport holds every row whatsmeow keeps for one device, with the keys
+// and signal state decrypted so it can be imported into a store with another key
+type DeviceExport struct {
+	JID    string                              `json:"jid"`
+	Tables map[string][]map[string]ExportValue `json:"tables"`
+}
+
+type storeColumn struct {
+	name string
+	kind string
+}
+
+// ExportDevice exports a device with everything needed to connect it elsewhere
+func (m *Manager) ExportDevice(deviceJID string) (*DeviceExport, error) {
+	return m.container.ExportDevice(context.Background(), deviceJID)
+}
+
+// ImportDevice imports an exported device. An existing copy of the device is
+// only replaced when replace is set.
+func (m *Manager) ImportDevice(export *DeviceExport, replace bool) error {
+	return m.container.ImportDevice(context.Background(), export, replace)
+}
+
+// ExportDevice reads every row of the device from whatsmeow's tables
+func (ds *DeviceStore) ExportDevice(ctx context.Context, deviceJID string) (*DeviceExport, error) {
+	jid, err := types.ParseJID(deviceJID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device JID %q: %w", deviceJID, err)
+	}
+
+	// Loading the device decrypts its keys
+	device, err := ds.GetDevice(ctx, jid)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, fmt.Errorf("device %s not found in the device store", deviceJID)
+	}
+	ds.mu.Lock()
+	vc := ds.ciphers[deviceJID]
+	ds.mu.Unlock()
+
+	tables, err := ds.deviceTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &DeviceExport{JID: deviceJID, Tables: make(map[string][]map[string]ExportValue)}
+	for _, table := range tables {
+		columns, err := ds.tableColumns(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", table, err)
+		}
+		owner := ownerColumn(columns)
+		if owner == "" {
+			continue // Not per device, e.g. the LID map
+		}
+
+		rows, err := ds.exportRows(ctx, table, owner, columns, deviceJID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table, err)
+		}
+
+		// Values are exported decrypted, the importing store encrypts them with its own key
+		for _, row := range rows {
+			for _, sc := range sealedColumns {
+				if sc.table == table && vc != nil && row[sc.column].Kind == ValueBytes {
+					plaintext, err := vc.open(sc.purpose, row[sc.column].Bytes)
+					if err != nil {
+						return nil, err
+					}
+					row[sc.column] = ExportValue{Kind: ValueBytes, Bytes: plaintext}
+				}
+			}
+			if table == "whatsmeow_device" {
+				row["noise_key"] = ExportValue{Kind: ValueBytes, Bytes: device.NoiseKey.Priv[:]}
+				row["identity_key"] = ExportValue{Kind: ValueBytes, Bytes: device.IdentityKey.Priv[:]}
+				row["signed_pre_key"] = ExportValue{Kind: ValueBytes, Bytes: device.SignedPreKey.Priv[:]}
+				row["adv_key"] = ExportValue{Kind: ValueBytes, Bytes: device.AdvSecretKey}
+			}
+		}
+		if len(rows) > 0 {
+			export.Tables[table] = rows
+		}
+	}
+	return export, nil
+}
+
+// ImportDevice writes an exported device in one transaction and encrypts it
+// when the store has a key
+func (ds *DeviceStore) ImportDevice(ctx context.Context, export *DeviceExport, replace bool) error {
+	jid, err := types.ParseJID(export.JID)
+	if err != nil {
+		return fmt.Errorf("invalid device JID %q: %w", export.JID, err)
+	}
+	if len(export.Tables["whatsmeow_device"]) != 1 {
+		return fmt.Errorf("the export doesn't contain device %s", export.JID)
+	}
+
+	tables := make([]string, 0, len(export.Tables))
+	for table := range export.Tables {
+		if !strings.HasPrefix(table, "whatsmeow_") || table == "whatsmeow_device_secrets" || table == "whatsmeow_version" {
+			return fmt.Errorf("unexpected table %q in export", table)
+		}
+		tables = append(tables, table)
+	}
+	sortStoreTables(tables)
+
+	err = ds.db.DoTxn(ctx, nil, func(ctx context.Context) error {
+		existing, err := ds.container.GetDevice(ctx, jid)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if !replace {
+				return ErrDeviceExists
+			}
+			// Cascades to every other table, including the secrets
+			if err := ds.container.DeleteDevice(ctx, existing); err != nil {
+				return err
+			}
+		}
+
+		for _, table := range tables {
+			columns, err := ds.tableColumns(ctx, table)
+			if err != nil {
+				return fmt.Errorf("failed to inspect %s: %w", table, err)
+			}
+			if len(columns) == 0 {
+				continue // Table from a newer whatsmeow, nothing to import it into
+			}
+			// Tables without an owner column, like the LID map, are shared by
+			// every device and never exported, so a bundle can't write to them
+			owner := ownerColumn(columns)
+			if owner == "" {
+				return fmt.Errorf("unexpected table %q in export", table)
+			}
+			for _, row := range export.Tables[table] {
+				// Rows always belong to the imported device
+				row[owner] = ExportValue{Kind: ValueText, Text: export.JID}
+				if err := ds.importRow(ctx, table, columns, row); err != nil {
+					return fmt.Errorf("failed to import %s: %w", table, err)
+				}
+			}
+		}
+
+		ds.mu.Lock()
+		delete(ds.ciphers, export.JID)
+		ds.mu.Unlock()
+
+		if ds.keyring == nil {
+			return nil
+		}
+		device, err := ds.container.GetDevice(ctx, jid)
+		if err != nil {
+			return err
+		}
+		_, err = ds.sealDevice(ctx, device)
+		return err
+	})
+	return err
+}
+
+// deviceTables lists whatsmeow's tables in the order rows can be inserted
+func (ds *DeviceStore) deviceTables(ctx context.Context) ([]string, error) {
+	dialect := "sqlite3"
+	if ds.db.Dialect == dbutil.Postgres {
+		dialect = "postgres"
+	}
+	tables, err := storeTables(ctx, ds.db.RawDB, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := tables[:0]
+	for _, table := range tables {
+		if table != "whatsmeow_device_secrets" {
+			filtered = append(filtered, table)
+		}
+	}
+	return filtered, nil
+}
+
+// tableColumns returns the columns of a table with the kind of value they hold
+func (ds *DeviceStore) tableColumns(ctx context.Context, table string) ([]storeColumn, error) {
+	query := "SELECT name, type FROM pragma_table_info($1)"
+	if ds.db.Dialect == dbutil.Postgres {
+		query = "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position"
+	}
+
+	rows, err := ds.db.Query(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []storeColumn
+	for rows.Next() {
+		var name, declared string
+		if err := rows.Scan(&name, &declared); err != nil {
+			return nil, err
+		}
+		columns = append(columns, storeColumn{name: name, kind: columnKind(declared)})
+	}
+	return columns, rows.Err()
+}
+
+func (ds *DeviceStore) exportRows(ctx context.Context, table, owner string, columns []storeColumn, jid string) ([]map[string]ExportValue, error) {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	rows, err := ds.db.Query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s=$1", strings.Join(names, ", "), table, owner), jid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var exported []map[string]ExportValue
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]ExportValue, len(columns))
+		for i, column := range columns {
+			value, err := exportValue(column.kind, values[i])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", column.name, err)
+			}
+			row[column.name] = value
+		}
+		exported = append(exported, row)
+	}
+	return exported, rows.Err()
+}
+
+// importRow inserts a row, leaving out columns this store doesn't have
+func (ds *DeviceStore) importRow(ctx context.Context, table string, columns []storeColumn, row map[string]ExportValue) error {
+	var names, placeholders []string
+	var args []interface{}
+	for _, column := range columns {
+		value, ok := row[column.name]
+		if !ok {
+			continue
+		}
+		arg, err := importValue(column.kind, value)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", column.name, err)
+		}
+		names = append(names, column.name)
+		args = append(args, arg)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	_, err := ds.db.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(names, ", "), strings.Join(placeholders, ", ")), args...)
+	return err
+}
+
+// ownerColumn returns the column linking a table's rows to a device
+func ownerColumn(columns []storeColumn) string {
+	for _, name := range []string{"our_jid", "jid"} {
+		for _, column := range columns {
+			if column.name == name {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// columnKind maps declared SQLite and Postgres column types to value kinds
+func columnKind(declared string) string {
+	declared = strings.ToLower(declared)
+	switch {
+	case strings.Contains(declared, "bytea"), strings.Contains(declared, "blob"):
+		return ValueBytes
+	case strings.Contains(declared, "bool"):
+		return ValueBool
+	case strings.Contains(declared, "int"):
+		return ValueInt
+	default:
+		return ValueText
+	}
+}
+
+func exportValue(kind string, value interface{}) (ExportValue, error) {
+	if value == nil {
+		return ExportValue{Kind: ValueNull}, nil
+	}
+
+	switch kind {
+	case ValueBytes:
+		switch v := value.(type) {
+		case []byte:
+			return ExportValue{Kind: ValueBytes, Bytes: append([]byte{}, v...)}, nil
+		case string:
+			return ExportValue{Kind: ValueBytes, Bytes: []byte(v)}, nil
+		}
+	case ValueBool:
+		switch v := value.(type) {
+		case bool:
+			return ExportValue{Kind: ValueBool, Bool: v}, nil
+		case int64:
+			return ExportValue{Kind: ValueBool, Bool: v != 0}, nil
+		}
+	case ValueInt:
+		switch v := value.(type) {
+		case int64:
+			return ExportValue{Kind: ValueInt, Int: v}, nil
+		case []byte:
+			n, err := strconv.ParseInt(string(v), 10, 64)
+			return ExportValue{Kind: ValueInt, Int: n}, err
+		}
+	default:
+		switch v := value.(type) {
+		case string:
+			return ExportValue{Kind: ValueText, Text: v}, nil
+		case []byte:
+			return ExportValue{Kind: ValueText, Text: string(v)}, nil
+		default:
+			return ExportValue{Kind: ValueText, Text: fmt.Sprint(v)}, nil
+		}
+	}
+	return ExportValue{}, fmt.Errorf("unexpected %T for a %s column", value, kind)
+}
+
+func importValue(kind string, value ExportValue) (interface{}, error) {
+	if value.Kind == ValueNull {
+		return nil, nil
+	}
+	if value.Kind != kind {
+		return nil, fmt.Errorf("expected a %s value, got %s", kind, value.Kind)
+	}
+
+	switch kind {
+	case ValueBytes:
+		if value.Bytes == nil {
+			return []byte{}, nil
+		}
+		return value.Bytes, nil
+	case ValueBool:
+		return value.Bool, nil
+	case ValueInt:
+		return value.Int, nil
+	default:
+		return value.Text, nil
+	}
+}
+
+// sortStoreTables puts the device first and app state versions before the
+// mutation MACs referencing them
+func sortStoreTables(tables []string) {
+	first := map[string]int{"whatsmeow_device": 0, "whatsmeow_app_state_version": 1}
+	sort.Slice(tables, func(i, j int) bool {
+		pi, iFirst := first[tables[i]]
+		pj, jFirst := first[tables[j]]
+		if iFirst != jFirst {
+			return iFirst
+		}
+		if iFirst {
+			return pi < pj
+		}
+		return tables[i] < tables[j]
+	})
+}